@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/amaury95/GetGround-Party/auth"
+	"github.com/gin-gonic/gin"
+)
+
+const tokenKey = "auth:token"
+
+// authenticate resolves the bearer token of the request. Requests without token go through, the routes
+// requiring a permission reject them, so public routes such as the rsvp answers keep working.
+func (h *Handler) authenticate(g *gin.Context) {
+	header := g.GetHeader("Authorization")
+	if header == "" {
+		return
+	}
+
+	value := strings.TrimPrefix(header, "Bearer ")
+	if value == header {
+		fail(g, http.StatusUnauthorized, CodeUnauthorized, "expected a bearer token")
+		g.Abort()
+		return
+	}
+
+	token, ok := h.tokens.Lookup(value)
+	if !ok {
+		fail(g, http.StatusUnauthorized, CodeUnauthorized, "invalid token")
+		g.Abort()
+		return
+	}
+
+	g.Set(tokenKey, token)
+}
+
+// require returns the middleware rejecting the requests whose token does not grant the permission.
+// Every request is allowed when authentication is disabled.
+func (h *Handler) require(permission auth.Permission) gin.HandlerFunc {
+	return func(g *gin.Context) {
+		if h.tokens == nil {
+			return
+		}
+
+		value, ok := g.Get(tokenKey)
+		if !ok {
+			fail(g, http.StatusUnauthorized, CodeUnauthorized, "authentication required")
+			g.Abort()
+			return
+		}
+
+		if token := value.(auth.Token); !token.Role.Allows(permission) {
+			fail(g, http.StatusForbidden, CodeForbidden, `role "%s" is not allowed to %s`, token.Role, permission)
+			g.Abort()
+			return
+		}
+	}
+}
+
+// principal returns the name of the token of the request, empty for anonymous requests
+func principal(g *gin.Context) string {
+	if value, ok := g.Get(tokenKey); ok {
+		return value.(auth.Token).Name
+	}
+	return ""
+}
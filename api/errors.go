@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ErrorCodeHeader is the response header holding the code of a failed request, the body holds the error message
+const ErrorCodeHeader = "X-Error-Code"
+
+// Error codes of the failed requests
+const (
+	CodeInvalidBody           = "invalid_body"
+	CodeInvalidRequest        = "invalid_request"
+	CodeNotFound              = "not_found"
+	CodeAmbiguousName         = "ambiguous_name"
+	CodeNotAccepted           = "reservation_not_accepted"
+	CodeCapacityExceeded      = "capacity_exceeded"
+	CodeUnsuitableTable       = "unsuitable_table"
+	CodeConstraintViolated    = "constraint_violated"
+	CodeOversizeArrival       = "oversize_arrival"
+	CodeArrivalConflict       = "arrival_conflict"
+	CodeOutsideSlot           = "outside_slot"
+	CodeInvitationExpired     = "invitation_expired"
+	CodeNotificationsDisabled = "notifications_disabled"
+	CodeWalkInsDisabled       = "walk_ins_disabled"
+	CodeTicketsDisabled       = "tickets_disabled"
+	CodeIdempotencyConflict   = "idempotency_conflict"
+	CodeUnauthorized          = "unauthorized"
+	CodeForbidden             = "forbidden"
+	CodeInternal              = "internal"
+)
+
+// fail logs the error of the request and writes it as response along with its code.
+// The guest names mentioned by the service errors are redacted from the log.
+func fail(g *gin.Context, status int, code string, format string, values ...interface{}) {
+	logger := logging.FromContext(g.Request.Context())
+	msg := fmt.Sprintf(format, values...)
+
+	logged := logging.Redact(msg, names(values)...)
+	if status >= 500 {
+		logger.Error(logged, zap.Int("status", status), zap.String("code", code))
+	} else {
+		logger.Warn(logged, zap.Int("status", status), zap.String("code", code))
+	}
+
+	g.Header(ErrorCodeHeader, code)
+	g.String(status, "%s", msg)
+}
+
+// names returns the guest names mentioned by the service errors among the values
+func names(values []interface{}) []string {
+	var names []string
+	for _, value := range values {
+		if err, ok := value.(error); ok {
+			names = append(names, party.Names(err)...)
+		}
+	}
+	return names
+}
+
+// Candidate is a reservation holding a name shared by several reservations
+type Candidate struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Table     int    `json:"table"`
+	PartySize int    `json:"party_size"`
+	Status    string `json:"status"`
+	Email     string `json:"email,omitempty"`
+}
+
+// AmbiguousNameResponse is the body of the requests referencing by name a reservation whose name is shared,
+// they should be retried with the id of one of the candidates
+type AmbiguousNameResponse struct {
+	Message    string      `json:"message"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// failAmbiguous logs the ambiguous reference of the request and responds with the candidates it could reference
+func failAmbiguous(g *gin.Context, err *party.AmbiguousError) {
+	logging.FromContext(g.Request.Context()).Warn(logging.Redact(err.Error(), err.Name), zap.Int("status", http.StatusConflict), zap.String("code", CodeAmbiguousName))
+
+	resp := AmbiguousNameResponse{Message: err.Error(), Candidates: make([]Candidate, len(err.Candidates))}
+	for i, c := range err.Candidates {
+		resp.Candidates[i] = Candidate{
+			ID:        c.ID,
+			Name:      c.Name,
+			Table:     c.TableID,
+			PartySize: c.Guests(),
+			Status:    c.Status,
+			Email:     c.Email,
+		}
+	}
+
+	g.Header(ErrorCodeHeader, CodeAmbiguousName)
+	g.JSON(http.StatusConflict, resp)
+}
+
+// failService writes the error of a service operation with the status and code of its kind,
+// the errors of no kind are reported as internal errors
+func failService(g *gin.Context, err error) {
+	var ambiguous *party.AmbiguousError
+
+	switch {
+	case errors.As(err, &ambiguous):
+		failAmbiguous(g, ambiguous)
+	case errors.Is(err, party.ErrInvalid):
+		fail(g, http.StatusBadRequest, CodeInvalidRequest, "%v", err)
+	case errors.Is(err, party.ErrNotFound):
+		fail(g, http.StatusNotFound, CodeNotFound, "%v", err)
+	case errors.Is(err, party.ErrNotAccepted):
+		fail(g, http.StatusBadRequest, CodeNotAccepted, "%v", err)
+	case errors.Is(err, party.ErrCapacityExceeded):
+		fail(g, http.StatusConflict, CodeCapacityExceeded, "%v", err)
+	case errors.Is(err, party.ErrUnsuitableTable):
+		fail(g, http.StatusConflict, CodeUnsuitableTable, "%v", err)
+	case errors.Is(err, party.ErrConstraintViolated):
+		fail(g, http.StatusConflict, CodeConstraintViolated, "%v", err)
+	case errors.Is(err, party.ErrOversizeArrival):
+		fail(g, http.StatusConflict, CodeOversizeArrival, "%v", err)
+	case errors.Is(err, party.ErrArrivalConflict):
+		fail(g, http.StatusConflict, CodeArrivalConflict, "%v", err)
+	case errors.Is(err, party.ErrOutsideSlot):
+		fail(g, http.StatusConflict, CodeOutsideSlot, "%v", err)
+	case errors.Is(err, party.ErrInvitationExpired):
+		fail(g, http.StatusGone, CodeInvitationExpired, "%v", err)
+	case errors.Is(err, party.ErrNotificationsDisabled):
+		fail(g, http.StatusServiceUnavailable, CodeNotificationsDisabled, "%v", err)
+	case errors.Is(err, party.ErrWalkInsDisabled):
+		fail(g, http.StatusForbidden, CodeWalkInsDisabled, "%v", err)
+	case errors.Is(err, party.ErrTicketsDisabled):
+		fail(g, http.StatusNotImplemented, CodeTicketsDisabled, "%v", err)
+	default:
+		fail(g, http.StatusInternalServerError, CodeInternal, "%v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/gin-gonic/gin"
+)
+
+// EventSettings holds the settings of the event changed while it runs
+type EventSettings struct {
+	WalkIns bool `json:"walk_ins"`
+}
+
+func eventSettings(settings *party.EventSettings) EventSettings {
+	return EventSettings{WalkIns: settings.WalkIns}
+}
+
+/*
+	Get Event Settings
+*/
+
+// GetEventSettings returns the settings of the event
+func (h *Handler) GetEventSettings(g *gin.Context) {
+	settings, err := h.service.EventSettings(g.Request.Context())
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.JSON(http.StatusOK, eventSettings(settings))
+}
+
+/*
+	Update Event Settings
+*/
+
+// UpdateEventSettingsRequest holds the settings of the event to change, the ones not set are kept
+type UpdateEventSettingsRequest struct {
+	WalkIns *bool `json:"walk_ins,omitempty"`
+}
+
+// UpdateEventSettings changes the settings of the event while it runs, such as seating the walk-ins
+func (h *Handler) UpdateEventSettings(g *gin.Context) {
+	var body UpdateEventSettingsRequest
+
+	// decode body from request
+	if err := json.NewDecoder(g.Request.Body).Decode(&body); err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidBody, "error decoding body: %v", err)
+		return
+	}
+
+	var (
+		settings *party.EventSettings
+		err      error
+	)
+	if body.WalkIns != nil {
+		settings, err = h.service.SetWalkIns(g.Request.Context(), *body.WalkIns)
+	} else {
+		settings, err = h.service.EventSettings(g.Request.Context())
+	}
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.JSON(http.StatusOK, eventSettings(settings))
+}
@@ -3,8 +3,10 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/party"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,52 +14,98 @@ import (
 	Create Guest
 */
 
+// CreateGuestRequest holds the persons arriving, the guest and its accompanying guests when Arriving is not set
 type CreateGuestRequest struct {
 	AccompanyingGuests int `json:"accompanying_guests"`
+	Arriving           int `json:"arriving,omitempty"`
 }
 
 type CreateGuestResponse struct {
-	Name string `json:"name"`
+	ID          string `json:"id"`
+	Reservation string `json:"reservation"`
+	Name        string `json:"name"`
+	Table       int    `json:"table"`
+	Arrived     int    `json:"arrived"`
+	Expected    int    `json:"expected"`
 }
 
+// CreateGuest registers the arrival of persons of the reservation, added to the ones already present
 func (h *Handler) CreateGuest(g *gin.Context) {
 	var body CreateGuestRequest
 
 	// decode body from request
 	if err := json.NewDecoder(g.Request.Body).Decode(&body); err != nil {
-		g.String(http.StatusInternalServerError, "error decoding body: %v", err)
+		fail(g, http.StatusBadRequest, CodeInvalidBody, "error decoding body: %v", err)
 		return
 	}
 
-	// decode name from params
-	name := g.Param("name")
+	persons := body.Arriving
+	if persons == 0 {
+		persons = 1 + body.AccompanyingGuests
+	}
 
-	// get guest reservation
-	var reservation models.Reservation
-	if err := h.db.First(&reservation, "name = ?", name).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error retrieving guest reservation: %v", err)
+	record, err := h.service.CheckIn(g.Request.Context(), reservationRef(g), persons)
+	if err != nil {
+		failService(g, err)
 		return
 	}
 
-	record := models.Guest{
-		Name:               name,
-		AccompanyingGuests: body.AccompanyingGuests,
-		TableID:            reservation.TableID,
-	}
+	g.JSON(http.StatusCreated, CreateGuestResponse{
+		ID:          record.ID,
+		Reservation: record.ReservationID,
+		Name:        record.Name,
+		Table:       record.TableID,
+		Arrived:     record.TotalGuests(),
+		Expected:    record.Reservation.Guests(),
+	})
+}
+
+/*
+	Create Walk In
+*/
+
+// CreateWalkInRequest holds the party arriving without reservation, the features its table must have and the
+// seating rules binding it to booked reservations, the name is optional
+type CreateWalkInRequest struct {
+	Name         string   `json:"name,omitempty"`
+	Persons      int      `json:"persons"`
+	Requirements []string `json:"requirements,omitempty"`
+	Constraints  []Rule   `json:"constraints,omitempty"`
+	Email        string   `json:"email,omitempty"`
+	Phone        string   `json:"phone,omitempty"`
+}
 
-	// validate model
-	if err := record.Validate(h.db); err != nil {
-		g.String(http.StatusBadRequest, "error validating reservation: %v", err)
+// CreateWalkIn seats the party arriving without reservation at a table with enough free seats
+func (h *Handler) CreateWalkIn(g *gin.Context) {
+	var body CreateWalkInRequest
+
+	// decode body from request
+	if err := json.NewDecoder(g.Request.Body).Decode(&body); err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidBody, "error decoding body: %v", err)
 		return
 	}
 
-	// create model in the database
-	if err := h.db.Create(&record).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error creating reservation: %v", err)
+	record, err := h.service.SeatWalkIn(g.Request.Context(), party.WalkIn{
+		Name:         body.Name,
+		Persons:      body.Persons,
+		Requirements: body.Requirements,
+		Constraints:  constraints(body.Constraints),
+		Email:        body.Email,
+		Phone:        body.Phone,
+	})
+	if err != nil {
+		failService(g, err)
 		return
 	}
 
-	g.JSON(http.StatusCreated, CreateGuestResponse{Name: record.Name})
+	g.JSON(http.StatusCreated, CreateGuestResponse{
+		ID:          record.ID,
+		Reservation: record.ReservationID,
+		Name:        record.Name,
+		Table:       record.TableID,
+		Arrived:     record.TotalGuests(),
+		Expected:    record.Reservation.Guests(),
+	})
 }
 
 /*
@@ -68,27 +116,73 @@ type GetGuestsResponse struct {
 	Guests []models.Guest `json:"guests"`
 }
 
+// GetGuests returns the arrived guests paginated by name
 func (h *Handler) GetGuests(g *gin.Context) {
-	var elements []models.Guest
+	p, err := parsePage(g)
+	if err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidRequest, "%v", err)
+		return
+	}
 
-	if err := h.db.Find(&elements).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error retrieving the guests: %v", err)
+	elements, next, err := h.service.Guests(g.Request.Context(), p)
+	if err != nil {
+		failService(g, err)
 		return
 	}
 
+	setNext(g, next)
 	g.JSON(http.StatusOK, GetGuestsResponse{Guests: elements})
 }
 
+/*
+	Check In Member
+*/
+
+// CheckInMember registers the arrival of a named member of a reservation
+func (h *Handler) CheckInMember(g *gin.Context) {
+	record, err := h.service.CheckInMember(g.Request.Context(), g.Param("id"))
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.JSON(http.StatusOK, record)
+}
+
+/*
+	Check Out Member
+*/
+
+// CheckOutMember registers the departure of a named member of a reservation
+func (h *Handler) CheckOutMember(g *gin.Context) {
+	record, err := h.service.CheckOutMember(g.Request.Context(), g.Param("id"))
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.JSON(http.StatusOK, record)
+}
+
 /*
 	Delete Guest
 */
 
+// DeleteGuest registers the departure of the number of persons of the leaving query parameter, all the persons
+// of the reservation when it is not set
 func (h *Handler) DeleteGuest(g *gin.Context) {
-	// decode name from params
-	name := g.Param("name")
+	var persons int
+	if raw := g.Query("leaving"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			fail(g, http.StatusBadRequest, CodeInvalidRequest, `invalid "%s" leaving persons`, raw)
+			return
+		}
+		persons = n
+	}
 
-	if err := h.db.Delete(new(models.Guest), "name = ?", name).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error deleting guest: %v", err)
+	if err := h.service.CheckOut(g.Request.Context(), reservationRef(g), persons); err != nil {
+		failService(g, err)
 		return
 	}
 
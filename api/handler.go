@@ -4,13 +4,29 @@ Package api holds the structure for the api handler that is going to be used to
 package api
 
 import (
+	"github.com/amaury95/GetGround-Party/auth"
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/metrics"
+	"github.com/amaury95/GetGround-Party/notify"
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/amaury95/GetGround-Party/tickets"
+	"github.com/amaury95/GetGround-Party/tracing"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 // Handler is the structure that holds the connection context for the application
 type Handler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	issuer   *tickets.Issuer
+	notifier notify.Notifier
+	metrics  *metrics.Metrics
+	tracer   trace.TracerProvider
+	tokens   *auth.Tokens
+	service  *party.Service
 }
 
 // Connection is the connection context getter
@@ -22,38 +38,151 @@ func (h *Handler) WithConnection(conn *gorm.DB) *Handler {
 	return h
 }
 
+// WithTicketIssuer sets the issuer used to sign the guest tickets and return the handler
+func (h *Handler) WithTicketIssuer(issuer *tickets.Issuer) *Handler {
+	h.issuer = issuer
+	return h
+}
+
+// WithNotifier sets the notifier used to message the reservation holders and return the handler
+func (h *Handler) WithNotifier(notifier notify.Notifier) *Handler {
+	h.notifier = notifier
+	return h
+}
+
+// WithService sets the service running the operations of the routes and return the handler, its commit hooks
+// refresh the metrics. Without it the service is built from the connection, issuer, notifier and metrics of the handler.
+func (h *Handler) WithService(service *party.Service) *Handler {
+	h.service = service
+	return h
+}
+
+// WithMetrics sets the collectors exposed on the metrics endpoint and return the handler
+func (h *Handler) WithMetrics(m *metrics.Metrics) *Handler {
+	h.metrics = m
+	return h
+}
+
+// WithTracing sets the provider tracing the handled requests and return the handler
+func (h *Handler) WithTracing(provider trace.TracerProvider) *Handler {
+	h.tracer = provider
+	return h
+}
+
+// WithAuth sets the tokens required to call the api and return the handler.
+// Without tokens the api is open, as it is meant to run behind a trusted network.
+func (h *Handler) WithAuth(tokens *auth.Tokens) *Handler {
+	h.tokens = tokens
+	return h
+}
+
 type RouterConfig struct {
-	ShowLogs    bool
-	ReleaseMode bool
+	Logger       *zap.Logger
+	ReleaseMode  bool
+	HealthChecks bool
 }
 
 // Router returns the router engine for the api handler
 func (h *Handler) Router(config *RouterConfig) *gin.Engine {
+	if h.service == nil {
+		h.service = party.New(h.db).WithNotifier(h.notifier).WithTicketIssuer(h.issuer)
+		if h.metrics != nil {
+			h.service.WithCommitHook(h.metrics.Refresh)
+		}
+	}
+
 	r := gin.New()
 	r.Use(gin.Recovery())
 
+	// trace the requests, continuing the trace of the W3C traceparent header
+	if h.tracer != nil {
+		r.Use(otelgin.Middleware(tracing.ServiceName, otelgin.WithTracerProvider(h.tracer), otelgin.WithPropagators(tracing.Propagator())))
+	}
+
 	// setup configurations
-	if config.ShowLogs {
-		r.Use(gin.Logger())
+	if config.Logger != nil {
+		r.Use(logging.Middleware(config.Logger))
 	}
 
 	if config.ReleaseMode {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// health probes
+	if config.HealthChecks {
+		r.GET(`/healthz`, h.GetLiveness)
+		r.GET(`/readyz`, h.GetReadiness)
+	}
+
+	// metrics
+	if h.metrics != nil {
+		r.Use(h.metrics.Middleware())
+		r.GET(`/metrics`, gin.WrapH(h.metrics.Handler()))
+	}
+
+	// authenticate the bearer tokens
+	if h.tokens != nil {
+		r.Use(h.authenticate)
+	}
+
+	// replay the writes retried with an idempotency key
+	r.Use(newIdempotency().middleware())
+
+	read, checkIn, manage := h.require(auth.PermRead), h.require(auth.PermCheckIn), h.require(auth.PermManage)
+	walkIn := h.require(auth.PermWalkIn)
+
 	// tables
-	r.GET(`/tables`, h.GetTables)
-	r.POST(`/tables`, h.CreateTable)
-	r.GET(`/seats_empty`, h.GetSeatsEmpty)
+	r.GET(`/tables`, read, h.GetTables)
+	r.POST(`/tables`, manage, h.CreateTable)
+	r.GET(`/seats_empty`, read, h.GetSeatsEmpty)
 
 	// reservations
-	r.POST(`/guest_list/:name`, h.CreateReservation)
-	r.GET(`/guest_list`, h.GetReservations)
+	r.POST(`/guest_list/:name`, manage, h.CreateReservation)
+	r.GET(`/guest_list`, read, h.GetReservations)
+	r.GET(`/guest_list/summary`, read, h.GetReservationsSummary)
+	r.GET(`/guest_list/search`, read, h.SearchReservations)
+	r.GET(`/guest_list/no_shows`, read, h.GetNoShows)
+	r.PUT(`/guest_list/:name`, manage, h.UpdateReservation)
+	r.DELETE(`/guest_list/:name`, manage, h.CancelReservation)
+	r.POST(`/guest_list/:name/reminder`, manage, h.RemindReservation)
+	r.POST(`/rsvp/:token`, h.RespondInvitation)
+
+	// reservations by id, the routes by name fail when the name is shared
+	r.GET(`/reservations/:id`, read, h.GetReservation)
+	r.PUT(`/reservations/:id`, manage, h.UpdateReservation)
+	r.DELETE(`/reservations/:id`, manage, h.CancelReservation)
+	r.POST(`/reservations/:id/reminder`, manage, h.RemindReservation)
+	r.PUT(`/reservations/:id/guest`, checkIn, h.CreateGuest)
+	r.DELETE(`/reservations/:id/guest`, checkIn, h.DeleteGuest)
 
 	// guests
-	r.PUT(`/guests/:name`, h.CreateGuest)
-	r.GET(`/guests`, h.GetGuests)
-	r.DELETE(`/guests/:name`, h.DeleteGuest)
+	r.PUT(`/guests/:name`, checkIn, h.CreateGuest)
+	r.GET(`/guests`, read, h.GetGuests)
+	r.DELETE(`/guests/:name`, checkIn, h.DeleteGuest)
+	r.POST(`/walk_ins`, walkIn, h.CreateWalkIn)
+
+	// seating constraints between reservations
+	r.GET(`/seating/constraints`, read, h.GetConstraints)
+	r.POST(`/seating/constraints`, manage, h.CreateConstraint)
+	r.DELETE(`/seating/constraints/:id`, manage, h.DeleteConstraint)
+	r.GET(`/seating/violations`, read, h.GetViolations)
+
+	// event settings and reports
+	r.GET(`/event`, read, h.GetEventSettings)
+	r.PUT(`/event`, manage, h.UpdateEventSettings)
+	r.GET(`/stats`, read, h.GetStats)
+
+	// party members
+	r.PUT(`/members/:id`, checkIn, h.CheckInMember)
+	r.DELETE(`/members/:id`, checkIn, h.CheckOutMember)
+
+	// tickets
+	if h.issuer != nil {
+		r.GET(`/tickets/public_key`, read, h.GetPublicKey)
+		r.GET(`/guest_list/:name/ticket`, read, h.GetTicket)
+		r.GET(`/reservations/:id/ticket`, read, h.GetTicket)
+		r.POST(`/checkins/sync`, checkIn, h.SyncCheckIns)
+	}
 
 	return r
 }
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Health statuses reported by the probes
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+/*
+	Liveness
+*/
+
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+type HealthCheck struct {
+	Status  string   `json:"status"`
+	Latency string   `json:"latency,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Pending []string `json:"pending,omitempty"`
+}
+
+// GetLiveness reports the process is up and serving requests
+func (h *Handler) GetLiveness(g *gin.Context) {
+	g.JSON(http.StatusOK, HealthResponse{Status: HealthOK})
+}
+
+/*
+	Readiness
+*/
+
+// GetReadiness reports whether the dependencies of the application are ready to serve requests
+func (h *Handler) GetReadiness(g *gin.Context) {
+	resp := HealthResponse{
+		Status: HealthOK,
+		Checks: map[string]HealthCheck{
+			"database":   h.checkDatabase(g.Request.Context()),
+			"migrations": h.checkMigrations(g.Request.Context()),
+		},
+	}
+
+	status := http.StatusOK
+	for _, check := range resp.Checks {
+		if check.Status != HealthOK {
+			resp.Status = HealthUnavailable
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	g.JSON(status, resp)
+}
+
+// checkDatabase pings the database connection
+func (h *Handler) checkDatabase(ctx context.Context) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	conn, err := h.db.DB()
+	if err != nil {
+		return HealthCheck{Status: HealthUnavailable, Error: err.Error()}
+	}
+
+	start := time.Now()
+	if err := conn.PingContext(ctx); err != nil {
+		return HealthCheck{Status: HealthUnavailable, Error: err.Error()}
+	}
+
+	return HealthCheck{Status: HealthOK, Latency: time.Since(start).String()}
+}
+
+// checkMigrations verifies the database schema holds every table and column of the models
+func (h *Handler) checkMigrations(ctx context.Context) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	pending, err := models.PendingMigrations(h.db.WithContext(ctx))
+	if err != nil {
+		return HealthCheck{Status: HealthUnavailable, Error: err.Error()}
+	}
+
+	if len(pending) > 0 {
+		return HealthCheck{Status: HealthUnavailable, Error: "database schema is not up to date", Pending: pending}
+	}
+
+	return HealthCheck{Status: HealthOK}
+}
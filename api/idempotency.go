@@ -0,0 +1,194 @@
+package api
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header identifying a write so that it can be retried safely
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayHeader is set on the responses replayed from a previous request with the same key
+const IdempotentReplayHeader = "Idempotent-Replayed"
+
+// IdempotencyTTL is how long the response of a write is replayed for its idempotency key
+const IdempotencyTTL = 24 * time.Hour
+
+// IdempotencyMaxBody is the largest body of the writes sent with an idempotency key, read whole to fingerprint them
+const IdempotencyMaxBody = 1 << 20
+
+// IdempotencyMaxKeys is the most idempotency keys kept, the oldest responses are forgotten first once reached
+const IdempotencyMaxKeys = 10000
+
+// idempotency replays the response of the writes retried with the same idempotency key.
+// Responses are kept in memory, so keys are only honoured by the instance that handled the first request.
+type idempotency struct {
+	mu      sync.Mutex
+	entries map[string]*idempotent
+	// stored holds the keys of the stored responses in the order they expire, all of them living for the same TTL
+	stored *list.List
+}
+
+type idempotent struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	expires     time.Time
+
+	status      int
+	contentType string
+	code        string
+	body        []byte
+}
+
+// responseRecorder keeps a copy of the response body written by the handlers
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+func newIdempotency() *idempotency {
+	return &idempotency{entries: make(map[string]*idempotent), stored: list.New()}
+}
+
+// middleware replays the stored response of a key, rejects keys reused for a different request and stores the
+// responses of the completed writes. Server errors are not stored so the writes that failed can be retried.
+func (i *idempotency) middleware() gin.HandlerFunc {
+	return func(g *gin.Context) {
+		key := g.GetHeader(IdempotencyKeyHeader)
+		if key == "" || g.Request.Method == http.MethodGet || g.Request.Method == http.MethodHead {
+			g.Next()
+			return
+		}
+
+		// keys are scoped to the token, a response is only replayed to whom was authorized to get it
+		key = principal(g) + "\x00" + key
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(g.Writer, g.Request.Body, IdempotencyMaxBody))
+		if err != nil {
+			status := http.StatusBadRequest
+			if len(body) == IdempotencyMaxBody {
+				status = http.StatusRequestEntityTooLarge
+			}
+			fail(g, status, CodeInvalidBody, "error reading body: %v", err)
+			g.Abort()
+			return
+		}
+		g.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		fingerprint := sha256.Sum256(append([]byte(g.Request.Method+" "+g.Request.URL.Path+"?"+g.Request.URL.RawQuery+"\n"), body...))
+
+		entry, replay := i.begin(key, fingerprint)
+		switch {
+		case entry == nil:
+			fail(g, http.StatusUnprocessableEntity, CodeIdempotencyConflict, "idempotency key was used for a different request")
+			g.Abort()
+			return
+		case replay && !entry.done:
+			fail(g, http.StatusConflict, CodeIdempotencyConflict, "a request with the same idempotency key is in progress")
+			g.Abort()
+			return
+		case replay:
+			g.Header(IdempotentReplayHeader, "true")
+			if entry.code != "" {
+				g.Header(ErrorCodeHeader, entry.code)
+			}
+			g.Data(entry.status, entry.contentType, entry.body)
+			g.Abort()
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: g.Writer}
+		g.Writer = rec
+
+		// a handler panicking releases the key before the recovery answers, so the write can be retried
+		finished := false
+		defer func() {
+			if !finished {
+				i.release(key)
+			}
+		}()
+
+		g.Next()
+
+		i.finish(key, entry, rec)
+		finished = true
+	}
+}
+
+// begin returns the entry of the key and whether it belongs to a previous request, a nil entry means the key
+// was used for a different request
+func (i *idempotency) begin(key string, fingerprint [sha256.Size]byte) (*idempotent, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.prune(time.Now())
+
+	if entry, ok := i.entries[key]; ok {
+		if entry.fingerprint != fingerprint {
+			return nil, true
+		}
+		return entry, true
+	}
+
+	entry := &idempotent{fingerprint: fingerprint}
+	i.entries[key] = entry
+	return entry, false
+}
+
+// finish stores the response of the request, or forgets the key when the request failed on the server
+func (i *idempotency) finish(key string, entry *idempotent, rec *responseRecorder) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if rec.Status() >= http.StatusInternalServerError {
+		delete(i.entries, key)
+		return
+	}
+
+	entry.done = true
+	entry.expires = time.Now().Add(IdempotencyTTL)
+	i.stored.PushBack(key)
+	entry.status = rec.Status()
+	entry.contentType = rec.Header().Get("Content-Type")
+	entry.code = rec.Header().Get(ErrorCodeHeader)
+	entry.body = rec.body.Bytes()
+}
+
+// release forgets the key of a request that did not complete
+func (i *idempotency) release(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.entries, key)
+}
+
+// prune forgets the expired responses, and the oldest ones while the keys are at their limit. The responses expire
+// in the order they were stored, so only the expired ones at the front are walked.
+func (i *idempotency) prune(now time.Time) {
+	for front := i.stored.Front(); front != nil; front = i.stored.Front() {
+		key := front.Value.(string)
+		if now.Before(i.entries[key].expires) && len(i.entries) < IdempotencyMaxKeys {
+			return
+		}
+
+		i.stored.Remove(front)
+		delete(i.entries, key)
+	}
+}
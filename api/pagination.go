@@ -0,0 +1,38 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/gin-gonic/gin"
+)
+
+// NextCursorHeader is the response header holding the cursor of the next page, it is absent on the last page
+const NextCursorHeader = "X-Next-Cursor"
+
+// MaxPageSize is the largest page the list endpoints return
+const MaxPageSize = party.MaxPageSize
+
+// parsePage reads the keyset pagination of a list endpoint, requested with the limit and after query parameters.
+// Lists are returned whole when no limit is given.
+func parsePage(g *gin.Context) (party.Page, error) {
+	raw := g.Query("limit")
+	if raw == "" {
+		return party.Page{}, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 || limit > MaxPageSize {
+		return party.Page{}, fmt.Errorf(`invalid "%s" limit, expected a value between 1 and %d`, raw, MaxPageSize)
+	}
+
+	return party.Page{Limit: limit, After: g.Query("after")}, nil
+}
+
+// setNext sets the cursor of the next page, unless the list is on its last page
+func setNext(g *gin.Context, next string) {
+	if next != "" {
+		g.Header(NextCursorHeader, next)
+	}
+}
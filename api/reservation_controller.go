@@ -3,22 +3,53 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/party"
 	"github.com/gin-gonic/gin"
 )
 
+// reservationRef reads the reservation referenced by the route, by its id on the /reservations routes and
+// by the name of its holder on the others
+func reservationRef(g *gin.Context) party.Ref {
+	if id := g.Param("id"); id != "" {
+		return party.ByID(id)
+	}
+	return party.ByName(g.Param("name"))
+}
+
 /*
 	Create Reservation
 */
 
 type CreateReservationRequest struct {
-	Table  int `json:"table"`
-	AccompanyingGuests int `json:"accompanying_guests"`
+	Table              int        `json:"table"`
+	AccompanyingGuests int        `json:"accompanying_guests"`
+	Requirements       []string   `json:"requirements,omitempty"`
+	StartsAt           *time.Time `json:"starts_at,omitempty"`
+	EndsAt             *time.Time `json:"ends_at,omitempty"`
+	RespondBy          *time.Time `json:"respond_by,omitempty"`
+	Email              string     `json:"email,omitempty"`
+	Phone              string     `json:"phone,omitempty"`
+	Members            []Member   `json:"members,omitempty"`
+	Constraints        []Rule     `json:"constraints,omitempty"`
+}
+
+// Member is a named person of the party, the holder included
+type Member struct {
+	Name     string `json:"name"`
+	Email    string `json:"email,omitempty"`
+	Dietary  string `json:"dietary,omitempty"`
+	AgeGroup string `json:"age_group,omitempty"`
 }
 
 type CreateReservationResponse struct {
-	Name string `json:"name"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	RSVPToken string `json:"rsvp_token"`
 }
 
 func (h *Handler) CreateReservation(g *gin.Context) {
@@ -26,32 +57,133 @@ func (h *Handler) CreateReservation(g *gin.Context) {
 
 	// decode body from request
 	if err := json.NewDecoder(g.Request.Body).Decode(&body); err != nil {
-		g.String(http.StatusInternalServerError, "error decoding body: %v", err)
+		fail(g, http.StatusBadRequest, CodeInvalidBody, "error decoding body: %v", err)
 		return
 	}
 
-	// decode name from params
-	name := g.Param("name")
+	members := make([]models.Member, len(body.Members))
+	for i, m := range body.Members {
+		members[i] = models.Member{Name: m.Name, Email: m.Email, Dietary: m.Dietary, AgeGroup: m.AgeGroup}
+	}
 
-	record := models.Reservation{
-		Name:               name,
+	record, err := h.service.BookReservation(g.Request.Context(), party.Booking{
+		Name:               g.Param("name"),
+		Table:              body.Table,
 		AccompanyingGuests: body.AccompanyingGuests,
-		TableID:            body.Table,
+		Requirements:       body.Requirements,
+		StartsAt:           body.StartsAt,
+		EndsAt:             body.EndsAt,
+		RespondBy:          body.RespondBy,
+		Email:              body.Email,
+		Phone:              body.Phone,
+		Members:            members,
+		Constraints:        constraints(body.Constraints),
+	})
+	if err != nil {
+		failService(g, err)
+		return
 	}
 
-	// validate model
-	if err := record.Validate(h.db); err != nil {
-		g.String(http.StatusBadRequest, "error validating reservation: %v", err)
+	g.JSON(http.StatusCreated, CreateReservationResponse{
+		ID:        record.ID,
+		Name:      record.Name,
+		Status:    record.Status,
+		RSVPToken: record.Token,
+	})
+}
+
+/*
+	Update Reservation
+*/
+
+type UpdateReservationRequest struct {
+	Name               *string    `json:"name,omitempty"`
+	Table              *int       `json:"table,omitempty"`
+	AccompanyingGuests *int       `json:"accompanying_guests,omitempty"`
+	Requirements       *[]string  `json:"requirements,omitempty"`
+	StartsAt           *time.Time `json:"starts_at,omitempty"`
+	EndsAt             *time.Time `json:"ends_at,omitempty"`
+	RespondBy          *time.Time `json:"respond_by,omitempty"`
+	Email              *string    `json:"email,omitempty"`
+	Phone              *string    `json:"phone,omitempty"`
+}
+
+// UpdateReservation changes the given fields of the reservation, its name included, and notifies the holder
+func (h *Handler) UpdateReservation(g *gin.Context) {
+	var body UpdateReservationRequest
+
+	// decode body from request
+	if err := json.NewDecoder(g.Request.Body).Decode(&body); err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidBody, "error decoding body: %v", err)
 		return
 	}
 
-	// create model in the database
-	if err := h.db.Create(&record).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error creating reservation: %v", err)
+	var requirements *models.Features
+	if body.Requirements != nil {
+		features := models.Features(*body.Requirements)
+		requirements = &features
+	}
+
+	record, err := h.service.UpdateReservation(g.Request.Context(), reservationRef(g), party.ReservationChanges{
+		Name:               body.Name,
+		Table:              body.Table,
+		AccompanyingGuests: body.AccompanyingGuests,
+		Requirements:       requirements,
+		StartsAt:           body.StartsAt,
+		EndsAt:             body.EndsAt,
+		RespondBy:          body.RespondBy,
+		Email:              body.Email,
+		Phone:              body.Phone,
+	})
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.JSON(http.StatusOK, record)
+}
+
+/*
+	Cancel Reservation
+*/
+
+// CancelReservation removes the reservation from the guest list and notifies the holder
+func (h *Handler) CancelReservation(g *gin.Context) {
+	if _, err := h.service.CancelReservation(g.Request.Context(), reservationRef(g)); err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.Status(http.StatusAccepted)
+}
+
+/*
+	Remind Reservation
+*/
+
+// RemindReservation sends a reminder to the reservation holder
+func (h *Handler) RemindReservation(g *gin.Context) {
+	if err := h.service.RemindReservation(g.Request.Context(), reservationRef(g)); err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.Status(http.StatusAccepted)
+}
+
+/*
+	Get Reservation
+*/
+
+// GetReservation returns the reservation with the given id and its members
+func (h *Handler) GetReservation(g *gin.Context) {
+	record, err := h.service.Reservation(g.Request.Context(), reservationRef(g))
+	if err != nil {
+		failService(g, err)
 		return
 	}
 
-	g.JSON(http.StatusCreated, CreateReservationResponse{Name: record.Name})
+	g.JSON(http.StatusOK, record)
 }
 
 /*
@@ -62,13 +194,126 @@ type GetReservationsResponse struct {
 	Guests []models.Reservation `json:"guests"`
 }
 
+// GetReservations returns the guest list paginated by name, optionally filtered by the invitation status
 func (h *Handler) GetReservations(g *gin.Context) {
-	var elements []models.Reservation
+	p, err := parsePage(g)
+	if err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidRequest, "%v", err)
+		return
+	}
+
+	elements, next, err := h.service.Reservations(g.Request.Context(), g.Query("status"), p)
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	setNext(g, next)
+	g.JSON(http.StatusOK, GetReservationsResponse{Guests: elements})
+}
+
+/*
+	Get No Shows
+*/
+
+// GetNoShows returns the accepted reservations whose guest has not arrived paginated by name
+func (h *Handler) GetNoShows(g *gin.Context) {
+	p, err := parsePage(g)
+	if err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidRequest, "%v", err)
+		return
+	}
 
-	if err := h.db.Find(&elements).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error retrieving reservations: %v", err)
+	elements, next, err := h.service.NoShows(g.Request.Context(), p)
+	if err != nil {
+		failService(g, err)
 		return
 	}
 
+	setNext(g, next)
 	g.JSON(http.StatusOK, GetReservationsResponse{Guests: elements})
 }
+
+/*
+	Get Reservations Summary
+*/
+
+type GetReservationsSummaryResponse struct {
+	Total          int     `json:"total"`
+	Invited        int     `json:"invited"`
+	Accepted       int     `json:"accepted"`
+	Declined       int     `json:"declined"`
+	Expired        int     `json:"expired"`
+	ResponseRate   float64 `json:"response_rate"`
+	AcceptanceRate float64 `json:"acceptance_rate"`
+}
+
+// GetReservationsSummary returns the invitation counts per status and the response rates
+func (h *Handler) GetReservationsSummary(g *gin.Context) {
+	summary, err := h.service.ReservationsSummary(g.Request.Context())
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	resp := GetReservationsSummaryResponse{
+		Total:          summary.Total,
+		Invited:        summary.Invited,
+		Accepted:       summary.Accepted,
+		Declined:       summary.Declined,
+		Expired:        summary.Expired,
+		ResponseRate:   summary.ResponseRate,
+		AcceptanceRate: summary.AcceptanceRate,
+	}
+
+	g.JSON(http.StatusOK, resp)
+}
+
+/*
+	Search Reservations
+*/
+
+type SearchResult struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Table     int     `json:"table"`
+	PartySize int     `json:"party_size"`
+	Status    string  `json:"status"`
+	Score     float64 `json:"score"`
+}
+
+type SearchReservationsResponse struct {
+	Results []SearchResult `json:"results"`
+}
+
+// SearchReservations finds the reservations whose holder name resembles the q query parameter, the best matches first
+func (h *Handler) SearchReservations(g *gin.Context) {
+	var limit int
+	if raw := g.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			fail(g, http.StatusBadRequest, CodeInvalidRequest, `invalid "%s" limit`, raw)
+			return
+		}
+	}
+
+	matches, err := h.service.SearchReservations(g.Request.Context(), g.Query("q"), limit)
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	resp := SearchReservationsResponse{Results: make([]SearchResult, len(matches))}
+	for i, m := range matches {
+		resp.Results[i] = SearchResult{
+			ID:        m.Reservation.ID,
+			Name:      m.Reservation.Name,
+			Table:     m.Reservation.TableID,
+			PartySize: m.Reservation.Guests(),
+			Status:    m.Reservation.Status,
+			Score:     m.Score,
+		}
+	}
+
+	g.JSON(http.StatusOK, resp)
+}
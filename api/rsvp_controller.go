@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+	Respond Invitation
+*/
+
+// RSVP answers accepted by the invitation endpoint
+const (
+	ResponseAccept  = "accept"
+	ResponseDecline = "decline"
+)
+
+type RespondInvitationRequest struct {
+	Response string `json:"response"`
+}
+
+type RespondInvitationResponse struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// RespondInvitation accepts or declines the invitation identified by the rsvp token.
+// Accepting an invitation takes the seats of the reservation, so it fails when the table is full.
+func (h *Handler) RespondInvitation(g *gin.Context) {
+	var body RespondInvitationRequest
+
+	// decode body from request
+	if err := json.NewDecoder(g.Request.Body).Decode(&body); err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidBody, "error decoding body: %v", err)
+		return
+	}
+
+	var accept bool
+	switch body.Response {
+	case ResponseAccept:
+		accept = true
+	case ResponseDecline:
+	default:
+		fail(g, http.StatusBadRequest, CodeInvalidRequest, `invalid "%s" response, expected "%s" or "%s"`, body.Response, ResponseAccept, ResponseDecline)
+		return
+	}
+
+	reservation, err := h.service.RespondInvitation(g.Request.Context(), g.Param("token"), accept)
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.JSON(http.StatusOK, RespondInvitationResponse{ID: reservation.ID, Name: reservation.Name, Status: reservation.Status})
+}
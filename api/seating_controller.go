@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/gin-gonic/gin"
+)
+
+// Rule is a seating constraint binding a reservation, or a walk-in, to the booked reservation With
+type Rule struct {
+	Kind string `json:"kind"`
+	With string `json:"with"`
+	Note string `json:"note,omitempty"`
+}
+
+// constraints returns the service constraints of the rules
+func constraints(rules []Rule) []party.Constraint {
+	var constraints []party.Constraint
+	for _, r := range rules {
+		constraints = append(constraints, party.Constraint{Kind: r.Kind, Other: r.With, Note: r.Note})
+	}
+	return constraints
+}
+
+/*
+	Create Constraint
+*/
+
+// CreateConstraintRequest holds the seating constraint binding two reservations, the note is optional
+type CreateConstraintRequest struct {
+	Kind        string `json:"kind"`
+	Reservation string `json:"reservation"`
+	Other       string `json:"other"`
+	Note        string `json:"note,omitempty"`
+}
+
+// CreateConstraint records a seating constraint between two reservations
+func (h *Handler) CreateConstraint(g *gin.Context) {
+	var body CreateConstraintRequest
+
+	// decode body from request
+	if err := json.NewDecoder(g.Request.Body).Decode(&body); err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidBody, "error decoding body: %v", err)
+		return
+	}
+
+	record, err := h.service.AddConstraint(g.Request.Context(), body.Reservation, party.Constraint{
+		Kind:  body.Kind,
+		Other: body.Other,
+		Note:  body.Note,
+	})
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.JSON(http.StatusCreated, record)
+}
+
+/*
+	Get Constraints
+*/
+
+// GetConstraints returns the seating constraints, only the ones of the reservation query parameter when it is set
+func (h *Handler) GetConstraints(g *gin.Context) {
+	elements, err := h.service.Constraints(g.Request.Context(), g.Query("reservation"))
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.JSON(http.StatusOK, elements)
+}
+
+/*
+	Delete Constraint
+*/
+
+// DeleteConstraint removes a seating constraint by id
+func (h *Handler) DeleteConstraint(g *gin.Context) {
+	id, err := strconv.Atoi(g.Param("id"))
+	if err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidRequest, `invalid "%s" constraint id`, g.Param("id"))
+		return
+	}
+
+	if err := h.service.RemoveConstraint(g.Request.Context(), id); err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.Status(http.StatusAccepted)
+}
+
+/*
+	Get Violations
+*/
+
+type GetViolationsResponse struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Violation is a seating constraint broken by the current plan, along the tables and zones of its reservations
+type Violation struct {
+	Constraint  int    `json:"constraint"`
+	Kind        string `json:"kind"`
+	Reservation string `json:"reservation"`
+	Table       int    `json:"table"`
+	Zone        string `json:"zone"`
+	Other       string `json:"other"`
+	OtherTable  int    `json:"other_table"`
+	OtherZone   string `json:"other_zone"`
+	Note        string `json:"note,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// GetViolations reports the seating constraints broken by the tables of the reservations
+func (h *Handler) GetViolations(g *gin.Context) {
+	violations, err := h.service.Violations(g.Request.Context())
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	resp := GetViolationsResponse{Violations: make([]Violation, len(violations))}
+	for i, v := range violations {
+		resp.Violations[i] = Violation{
+			Constraint:  v.Constraint.ID,
+			Kind:        v.Constraint.Kind,
+			Reservation: v.Constraint.ReservationID,
+			Table:       v.Table,
+			Zone:        v.Zone,
+			Other:       v.Constraint.OtherID,
+			OtherTable:  v.OtherTable,
+			OtherZone:   v.OtherZone,
+			Note:        v.Constraint.Note,
+			Reason:      v.Reason,
+		}
+	}
+
+	g.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+	Get Stats
+*/
+
+// DefaultStatsBucket is the length of the arrival buckets when the request does not give one
+const DefaultStatsBucket = 15 * time.Minute
+
+// GetStatsResponse holds the figures of the event for its reports
+type GetStatsResponse struct {
+	Reservations     int             `json:"reservations"`
+	Expected         int             `json:"expected"`
+	NoShows          int             `json:"no_shows"`
+	NoShowRate       float64         `json:"no_show_rate"`
+	AveragePartySize float64         `json:"average_party_size"`
+	Bucket           string          `json:"bucket"`
+	Arrivals         []ArrivalBucket `json:"arrivals"`
+	Peak             Peak            `json:"peak"`
+	Tables           []TableUse      `json:"tables"`
+}
+
+// ArrivalBucket holds the parties and persons arrived in the bucket starting at Start
+type ArrivalBucket struct {
+	Start   time.Time `json:"start"`
+	Parties int       `json:"parties"`
+	Persons int       `json:"persons"`
+}
+
+// Peak is the most persons present at once and the moment they were reached, none before the first arrival
+type Peak struct {
+	Persons int        `json:"persons"`
+	At      *time.Time `json:"at,omitempty"`
+}
+
+// TableUse holds the seats of a table booked and taken, Utilization being the share of its capacity taken
+type TableUse struct {
+	Table       int     `json:"table"`
+	Label       string  `json:"label,omitempty"`
+	Zone        string  `json:"zone,omitempty"`
+	Capacity    int     `json:"capacity"`
+	Booked      int     `json:"booked"`
+	Present     int     `json:"present"`
+	Utilization float64 `json:"utilization"`
+}
+
+// GetStats reports the arrivals, no-shows and use of the tables of the event, as JSON or as CSV with ?format=csv
+func (h *Handler) GetStats(g *gin.Context) {
+	bucket := DefaultStatsBucket
+	if raw := g.Query("bucket"); raw != "" {
+		var err error
+		if bucket, err = time.ParseDuration(raw); err != nil {
+			fail(g, http.StatusBadRequest, CodeInvalidRequest, `invalid "%s" bucket, expected a duration as 15m`, raw)
+			return
+		}
+	}
+
+	format := g.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		fail(g, http.StatusBadRequest, CodeInvalidRequest, `invalid "%s" format, expected json or csv`, format)
+		return
+	}
+
+	stats, err := h.service.Stats(g.Request.Context(), bucket)
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	resp := GetStatsResponse{
+		Reservations:     stats.Reservations,
+		Expected:         stats.Expected,
+		NoShows:          stats.NoShows,
+		NoShowRate:       stats.NoShowRate,
+		AveragePartySize: stats.AveragePartySize,
+		Bucket:           bucket.String(),
+		Arrivals:         make([]ArrivalBucket, len(stats.Arrivals)),
+		Peak:             Peak{Persons: stats.Peak.Persons, At: stats.Peak.At},
+		Tables:           make([]TableUse, len(stats.Tables)),
+	}
+	for i, a := range stats.Arrivals {
+		resp.Arrivals[i] = ArrivalBucket{Start: a.Start, Parties: a.Parties, Persons: a.Persons}
+	}
+	for i, t := range stats.Tables {
+		resp.Tables[i] = TableUse{
+			Table:       t.ID,
+			Label:       t.Label,
+			Zone:        t.Zone,
+			Capacity:    t.Capacity,
+			Booked:      t.Booked,
+			Present:     t.Present,
+			Utilization: t.Utilization,
+		}
+	}
+
+	if format == "csv" {
+		writeStatsCSV(g, &resp)
+		return
+	}
+
+	g.JSON(http.StatusOK, resp)
+}
+
+// writeStatsCSV writes the stats as three CSV sections separated by an empty line, each one under its header:
+// the figures of the event, the arrivals and the tables
+func writeStatsCSV(g *gin.Context, stats *GetStatsResponse) {
+	formatFloat := func(f float64) string { return strconv.FormatFloat(f, 'f', 4, 64) }
+
+	var peakAt string
+	if stats.Peak.At != nil {
+		peakAt = stats.Peak.At.Format(time.RFC3339)
+	}
+
+	records := [][]string{
+		{"metric", "value"},
+		{"reservations", strconv.Itoa(stats.Reservations)},
+		{"expected", strconv.Itoa(stats.Expected)},
+		{"no_shows", strconv.Itoa(stats.NoShows)},
+		{"no_show_rate", formatFloat(stats.NoShowRate)},
+		{"average_party_size", formatFloat(stats.AveragePartySize)},
+		{"peak_persons", strconv.Itoa(stats.Peak.Persons)},
+		{"peak_at", peakAt},
+		{},
+		{"bucket_start", "parties", "persons"},
+	}
+	for _, a := range stats.Arrivals {
+		records = append(records, []string{a.Start.Format(time.RFC3339), strconv.Itoa(a.Parties), strconv.Itoa(a.Persons)})
+	}
+	records = append(records, []string{}, []string{"table", "label", "zone", "capacity", "booked", "present", "utilization"})
+	for _, t := range stats.Tables {
+		records = append(records, []string{
+			strconv.Itoa(t.Table), t.Label, t.Zone, strconv.Itoa(t.Capacity), strconv.Itoa(t.Booked), strconv.Itoa(t.Present), formatFloat(t.Utilization),
+		})
+	}
+
+	g.Header("Content-Type", "text/csv; charset=utf-8")
+	g.Status(http.StatusOK)
+
+	w := csv.NewWriter(g.Writer)
+	if err := w.WriteAll(records); err != nil {
+		g.Error(err)
+	}
+}
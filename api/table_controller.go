@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/party"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,8 +15,12 @@ import (
 	Create Table
 */
 
+// CreateTableRequest holds the table to create, its label, zone and features are optional
 type CreateTableRequest struct {
-	Capacity int `json:"capacity"`
+	Label    string   `json:"label,omitempty"`
+	Zone     string   `json:"zone,omitempty"`
+	Capacity int      `json:"capacity"`
+	Features []string `json:"features,omitempty"`
 }
 
 // CreateTable creates a table with the given capacity
@@ -22,23 +29,18 @@ func (h *Handler) CreateTable(g *gin.Context) {
 
 	// decode input from request
 	if err := json.NewDecoder(g.Request.Body).Decode(&body); err != nil {
-		g.String(http.StatusInternalServerError, "error decoding body: %v", err)
+		fail(g, http.StatusBadRequest, CodeInvalidBody, "error decoding body: %v", err)
 		return
 	}
 
-	record := models.Table{
+	record, err := h.service.AddTable(g.Request.Context(), party.TableSpec{
+		Label:    body.Label,
+		Zone:     body.Zone,
 		Capacity: body.Capacity,
-	}
-
-	// validate model
-	if err := record.Validate(h.db); err != nil {
-		g.String(http.StatusBadRequest, "error validating table: %v", err)
-		return
-	}
-
-	// create model in the database
-	if err := h.db.Create(&record).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error creating table: %v", err)
+		Features: body.Features,
+	})
+	if err != nil {
+		failService(g, err)
 		return
 	}
 
@@ -49,16 +51,30 @@ func (h *Handler) CreateTable(g *gin.Context) {
 	Get Tables
 */
 
-// GetTables returns a list of the existing tables on the database.
-// TODO: pagination
+// GetTables returns a list of the existing tables on the database, paginated by id. The tables are filtered by the
+// zone, feature (repeated, all required) and min_capacity query parameters.
 func (h *Handler) GetTables(g *gin.Context) {
-	var tables []models.Table
+	p, err := parsePage(g)
+	if err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidRequest, "%v", err)
+		return
+	}
 
-	if err := h.db.Find(&tables).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error retrieving tables: %v", err)
+	filter := party.TableFilter{Zone: g.Query("zone"), Features: g.QueryArray("feature")}
+	if raw := g.Query("min_capacity"); raw != "" {
+		if filter.MinCapacity, err = strconv.Atoi(raw); err != nil {
+			fail(g, http.StatusBadRequest, CodeInvalidRequest, `invalid "%s" min capacity`, raw)
+			return
+		}
+	}
+
+	tables, next, err := h.service.Tables(g.Request.Context(), filter, p)
+	if err != nil {
+		failService(g, err)
 		return
 	}
 
+	setNext(g, next)
 	g.JSON(http.StatusOK, tables)
 }
 
@@ -66,25 +82,40 @@ func (h *Handler) GetTables(g *gin.Context) {
 	Get Seats Empty
 */
 type GetSeatsEmptyRespose struct {
-	SeatsEmpty int `json:"seats_empty"`
+	SeatsEmpty int         `json:"seats_empty"`
+	Zones      []ZoneSeats `json:"zones"`
 }
 
-// GetSeatsEmpty calculate the total availability of the party
-func (h *Handler) GetSeatsEmpty(g *gin.Context) {
-	// SET @availability := (SELECT SUM(capacity) FROM tables);
-	// SELECT @availability - COUNT(*) - SUM(accompanying_guests) FROM guests;
+// ZoneSeats holds the seats of the tables of a zone, the tables without zone are grouped under the empty one
+type ZoneSeats struct {
+	Zone       string `json:"zone"`
+	Capacity   int    `json:"capacity"`
+	SeatsEmpty int    `json:"seats_empty"`
+}
 
-	var capacity, occupied int
+// GetSeatsEmpty calculate the total availability of the party and the one of each zone. Given the at query parameter,
+// an RFC 3339 time, the seats not booked at that moment are returned instead of the ones not taken by the arrived guests.
+func (h *Handler) GetSeatsEmpty(g *gin.Context) {
+	seatsEmpty := h.service.SeatsEmpty
+	if raw := g.Query("at"); raw != "" {
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			fail(g, http.StatusBadRequest, CodeInvalidRequest, `invalid "%s" time, expected an RFC 3339 time`, raw)
+			return
+		}
+		seatsEmpty = func(ctx context.Context) (int, []party.ZoneSeats, error) { return h.service.SeatsEmptyAt(ctx, at) }
+	}
 
-	if err := h.db.Select("SUM(capacity)").Table("tables").Scan(&capacity).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error calculating capacity: %v", err)
+	seats, zones, err := seatsEmpty(g.Request.Context())
+	if err != nil {
+		failService(g, err)
 		return
 	}
 
-	if err := h.db.Select("COUNT(*) + SUM(accompanying_guests)").Table("guests").Scan(&occupied).Error; err != nil {
-		g.String(http.StatusInternalServerError, "error getting occupancy: %v", err)
-		return
+	resp := GetSeatsEmptyRespose{SeatsEmpty: seats, Zones: make([]ZoneSeats, len(zones))}
+	for i, z := range zones {
+		resp.Zones[i] = ZoneSeats{Zone: z.Zone, Capacity: z.Capacity, SeatsEmpty: z.SeatsEmpty}
 	}
 
-	g.JSON(http.StatusOK, GetSeatsEmptyRespose{SeatsEmpty: capacity - occupied})
+	g.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/amaury95/GetGround-Party/tickets"
+	"github.com/gin-gonic/gin"
+)
+
+/*
+	Get Public Key
+*/
+
+type GetPublicKeyResponse struct {
+	Algorithm string `json:"algorithm"`
+	Key       string `json:"key"`
+	PEM       string `json:"pem"`
+}
+
+// GetPublicKey exports the public key used by the door devices to verify tickets offline
+func (h *Handler) GetPublicKey(g *gin.Context) {
+	key, err := h.service.PublicKey()
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	encoded, err := tickets.EncodePublicKey(key)
+	if err != nil {
+		fail(g, http.StatusInternalServerError, CodeInternal, "error encoding public key: %v", err)
+		return
+	}
+
+	g.JSON(http.StatusOK, GetPublicKeyResponse{
+		Algorithm: "Ed25519",
+		Key:       base64.StdEncoding.EncodeToString(key),
+		PEM:       string(encoded),
+	})
+}
+
+/*
+	Get Ticket
+*/
+
+type GetTicketResponse struct {
+	Ticket string `json:"ticket"`
+}
+
+// GetTicket issues a signed ticket for the reservation
+func (h *Handler) GetTicket(g *gin.Context) {
+	ticket, err := h.service.Ticket(g.Request.Context(), reservationRef(g))
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	g.JSON(http.StatusOK, GetTicketResponse{Ticket: ticket})
+}
+
+/*
+	Sync Check-ins
+*/
+
+// SyncCheckIn holds the persons arriving, the guest and its accompanying guests when Arriving is not set
+type SyncCheckIn struct {
+	Ticket             string    `json:"ticket"`
+	AccompanyingGuests int       `json:"accompanying_guests"`
+	Arriving           int       `json:"arriving,omitempty"`
+	CheckedInAt        time.Time `json:"checked_in_at"`
+}
+
+type SyncCheckInsRequest struct {
+	Device   string        `json:"device"`
+	CheckIns []SyncCheckIn `json:"checkins"`
+}
+
+type SyncConflict struct {
+	Ticket string `json:"ticket"`
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type SyncCheckInsResponse struct {
+	Accepted  []string       `json:"accepted"`
+	Conflicts []SyncConflict `json:"conflicts"`
+}
+
+// SyncCheckIns reconciles a batch of check-ins recorded offline by a door device.
+// Check-ins are applied in the order of the device timestamps and the ones that can not be applied are reported as conflicts.
+func (h *Handler) SyncCheckIns(g *gin.Context) {
+	var body SyncCheckInsRequest
+
+	// decode body from request
+	if err := json.NewDecoder(g.Request.Body).Decode(&body); err != nil {
+		fail(g, http.StatusBadRequest, CodeInvalidBody, "error decoding body: %v", err)
+		return
+	}
+
+	checkIns := make([]party.OfflineCheckIn, len(body.CheckIns))
+	for i, checkIn := range body.CheckIns {
+		persons := checkIn.Arriving
+		if persons == 0 {
+			persons = 1 + checkIn.AccompanyingGuests
+		}
+
+		checkIns[i] = party.OfflineCheckIn{
+			Device:      body.Device,
+			Ticket:      checkIn.Ticket,
+			Persons:     persons,
+			CheckedInAt: checkIn.CheckedInAt,
+		}
+	}
+
+	result, err := h.service.SyncCheckIns(g.Request.Context(), checkIns)
+	if err != nil {
+		failService(g, err)
+		return
+	}
+
+	resp := SyncCheckInsResponse{
+		Accepted:  result.Accepted,
+		Conflicts: make([]SyncConflict, len(result.Conflicts)),
+	}
+	for i, conflict := range result.Conflicts {
+		resp.Conflicts[i] = SyncConflict{Ticket: conflict.Ticket, Name: conflict.Name, Reason: conflict.Reason}
+	}
+
+	g.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,142 @@
+/*
+Package auth holds the bearer token authentication of the api and the permissions of the roles.
+
+Tokens are listed in a YAML file by the SHA-256 of their value, so the file does not hold usable credentials:
+
+	tokens:
+	  - name: door-laptop-1
+	    sha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+	    role: staff
+*/
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Role is the set of permissions granted to a token
+type Role string
+
+// Roles of the tokens
+const (
+	RoleAdmin  Role = "admin"
+	RoleStaff  Role = "staff"
+	RoleViewer Role = "viewer"
+)
+
+// Permission is an operation of the api
+type Permission string
+
+// Permissions required by the routes of the api
+const (
+	PermRead    Permission = "read"
+	PermCheckIn Permission = "checkin"
+	PermWalkIn  Permission = "walkin"
+	PermManage  Permission = "manage"
+)
+
+// grants lists the permissions of each role
+var grants = map[Role][]Permission{
+	RoleAdmin:  {PermRead, PermCheckIn, PermWalkIn, PermManage},
+	RoleStaff:  {PermRead, PermCheckIn, PermWalkIn},
+	RoleViewer: {PermRead},
+}
+
+// Valid reports whether the role is known
+func (r Role) Valid() bool {
+	_, ok := grants[r]
+	return ok
+}
+
+// Allows reports whether the role grants the permission
+func (r Role) Allows(p Permission) bool {
+	for _, granted := range grants[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Token is an entry of the tokens file
+type Token struct {
+	Name   string `yaml:"name"`
+	SHA256 string `yaml:"sha256"`
+	Role   Role   `yaml:"role"`
+}
+
+// Tokens are the tokens accepted by the api, indexed by their hash
+type Tokens struct {
+	byHash map[string]Token
+}
+
+// NewTokens returns the set of the given tokens
+func NewTokens(tokens ...Token) (*Tokens, error) {
+	t := &Tokens{byHash: make(map[string]Token, len(tokens))}
+
+	for _, token := range tokens {
+		if token.Name == "" {
+			return nil, fmt.Errorf("token without name")
+		}
+		if !token.Role.Valid() {
+			return nil, fmt.Errorf(`invalid "%s" role of token "%s", expected admin, staff or viewer`, token.Role, token.Name)
+		}
+
+		hash := strings.ToLower(token.SHA256)
+		if len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf(`invalid sha256 of token "%s"`, token.Name)
+		}
+		if _, ok := t.byHash[hash]; ok {
+			return nil, fmt.Errorf(`duplicated sha256 of token "%s"`, token.Name)
+		}
+
+		t.byHash[hash] = token
+	}
+
+	return t, nil
+}
+
+// LoadTokens reads the tokens file
+func LoadTokens(path string) (*Tokens, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tokens file: %v", err)
+	}
+
+	var file struct {
+		Tokens []Token `yaml:"tokens"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error decoding tokens file: %v", err)
+	}
+
+	return NewTokens(file.Tokens...)
+}
+
+// Lookup returns the entry of the token value
+func (t *Tokens) Lookup(value string) (Token, bool) {
+	token, ok := t.byHash[Hash(value)]
+	return token, ok
+}
+
+// Hash returns the hex encoded SHA-256 of the token value
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Generate returns a new random token value
+func Generate() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating token: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,138 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files generated by GenerateDev
+const (
+	CAFile         = "ca.pem"
+	CAKeyFile      = "ca-key.pem"
+	ServerFile     = "server.pem"
+	ServerKeyFile  = "server-key.pem"
+	ClientFile     = "client.pem"
+	ClientKeyFile  = "client-key.pem"
+	devValidPeriod = 365 * 24 * time.Hour
+)
+
+// GenerateDev creates into dir a self-signed certificate authority, a server certificate for the given hosts
+// and a client certificate for the door devices, both signed by the authority. They are meant for local testing only.
+func GenerateDev(dir string, hosts []string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating certificates directory: %v", err)
+	}
+
+	now := time.Now()
+
+	// certificate authority
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error generating ca key: %v", err)
+	}
+
+	ca := &x509.Certificate{
+		SerialNumber:          serial(),
+		Subject:               pkix.Name{Organization: []string{"Party"}, CommonName: "Party Development CA"},
+		NotBefore:             now,
+		NotAfter:              now.Add(devValidPeriod),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("error creating ca certificate: %v", err)
+	}
+
+	if err := write(dir, CAFile, CAKeyFile, caDER, caKey); err != nil {
+		return err
+	}
+
+	// server certificate
+	server := &x509.Certificate{
+		SerialNumber: serial(),
+		Subject:      pkix.Name{Organization: []string{"Party"}, CommonName: "party server"},
+		NotBefore:    now,
+		NotAfter:     now.Add(devValidPeriod),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			server.IPAddresses = append(server.IPAddresses, ip)
+		} else {
+			server.DNSNames = append(server.DNSNames, host)
+		}
+	}
+
+	if err := sign(dir, ServerFile, ServerKeyFile, server, ca, caKey); err != nil {
+		return err
+	}
+
+	// client certificate
+	client := &x509.Certificate{
+		SerialNumber: serial(),
+		Subject:      pkix.Name{Organization: []string{"Party"}, CommonName: "party door device"},
+		NotBefore:    now,
+		NotAfter:     now.Add(devValidPeriod),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	return sign(dir, ClientFile, ClientKeyFile, client, ca, caKey)
+}
+
+func sign(dir, certFile, keyFile string, template, ca *x509.Certificate, caKey *ecdsa.PrivateKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error generating %s key: %v", template.Subject.CommonName, err)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("error creating %s certificate: %v", template.Subject.CommonName, err)
+	}
+
+	return write(dir, certFile, keyFile, der, key)
+}
+
+func write(dir, certFile, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("error encoding key: %v", err)
+	}
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(filepath.Join(dir, certFile), cert, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %v", certFile, err)
+	}
+
+	private := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(filepath.Join(dir, keyFile), private, 0600); err != nil {
+		return fmt.Errorf("error writing %s: %v", keyFile, err)
+	}
+
+	return nil
+}
+
+func serial() *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return n
+}
@@ -0,0 +1,98 @@
+/*
+Package certs holds the TLS setup of the server: the certificates reloading and the generation of development certificates.
+*/
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// Reloader serves the server certificate and the client certificate authorities from files that can be reloaded at runtime.
+// New handshakes use the reloaded files while the established connections are kept.
+type Reloader struct {
+	certFile, keyFile, clientCAFile string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// NewReloader loads the certificate key pair and, when clientCAFile is not empty,
+// the bundle of authorities the client certificates are verified against.
+func NewReloader(certFile, keyFile, clientCAFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload reads the files again, the current certificates are kept if any of them fails to load
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %v", err)
+	}
+
+	var pool *x509.CertPool
+	if r.clientCAFile != "" {
+		data, err := ioutil.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("error reading client ca bundle: %v", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf(`no certificates found in "%s"`, r.clientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = pool
+	r.mu.Unlock()
+
+	return nil
+}
+
+// MutualTLS reports whether the client certificates are verified
+func (r *Reloader) MutualTLS() bool { return r.clientCAFile != "" }
+
+// TLSConfig returns the server configuration resolving the certificates on every handshake. The configuration of a
+// handshake replaces the returned one, so it carries over its protocols: h2 is negotiated for HTTP and gRPC.
+func (r *Reloader) TLSConfig() *tls.Config {
+	outer := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	outer.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		config := &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			NextProtos:   outer.NextProtos,
+			Certificates: []tls.Certificate{*r.cert},
+		}
+
+		if r.clientCAs != nil {
+			config.ClientCAs = r.clientCAs
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+		return config, nil
+	}
+
+	return outer
+}
@@ -0,0 +1,235 @@
+/*
+Package client holds the typed Go client of the party api.
+
+Every route of the api router has a method taking a context, and the request and response types are the ones of
+the api package. Writes are sent with an idempotency key and retried on network and server errors, and failed
+requests are returned as *Error values comparable with errors.Is against the sentinel errors of the package.
+*/
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+)
+
+// Client is the typed client of the party api
+type Client struct {
+	base    *url.URL
+	http    *http.Client
+	header  http.Header
+	retries int
+	backoff time.Duration
+}
+
+// Option configures the client
+type Option func(*Client)
+
+// WithHTTPClient sets the http client used to send the requests
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) { cl.http = c }
+}
+
+// WithRetries sets how many times a failed request is retried and the delay before the first retry,
+// which doubles on every attempt
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(cl *Client) {
+		cl.retries = retries
+		cl.backoff = backoff
+	}
+}
+
+// WithHeader sets a header sent on every request
+func WithHeader(key, value string) Option {
+	return func(cl *Client) { cl.header.Set(key, value) }
+}
+
+// WithToken sets the bearer token authenticating the requests
+func WithToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// New returns a client of the api served on the base url
+func New(baseURL string, opts ...Option) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing base url: %v", err)
+	}
+
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf(`invalid "%s" base url, expected an http or https url`, baseURL)
+	}
+
+	c := &Client{
+		base:    base,
+		http:    &http.Client{Timeout: 30 * time.Second},
+		header:  make(http.Header),
+		retries: 3,
+		backoff: 200 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// request is a call to the api
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+}
+
+// response is the reply of the api to a request
+type response struct {
+	header http.Header
+	body   []byte
+}
+
+// do sends the request and decodes the response into out when given. Writes carry an idempotency key kept across
+// the retries, so a write that reached the server before a network error is not applied twice.
+func (c *Client) do(ctx context.Context, req request, out interface{}) (*response, error) {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return nil, fmt.Errorf("error encoding body: %v", err)
+		}
+	}
+
+	// the paths hold escaped segments, e.g. the names of the guests
+	path, err := url.PathUnescape(req.path)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing path: %v", err)
+	}
+
+	u := *c.base
+	u.Path += path
+	u.RawPath = c.base.EscapedPath() + req.path
+	u.RawQuery = req.query.Encode()
+
+	var key string
+	if req.method != http.MethodGet {
+		key = newKey()
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req.method, u.String(), payload, key)
+		if err == nil {
+			if out != nil {
+				if err := json.Unmarshal(resp.body, out); err != nil {
+					return nil, fmt.Errorf("error decoding response: %v", err)
+				}
+			}
+			return resp, nil
+		}
+
+		if attempt >= c.retries || !retryable(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// send makes a single attempt of the request
+func (c *Client) send(ctx context.Context, method, url string, payload []byte, key string) (*response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set(api.IdempotencyKeyHeader, key)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &netError{err: err}
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &netError{err: err}
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &Error{
+			Status:  resp.StatusCode,
+			Code:    resp.Header.Get(api.ErrorCodeHeader),
+			Message: strings.TrimSpace(string(data)),
+		}
+
+		// the ambiguous names are reported along with the reservations sharing them
+		var ambiguous api.AmbiguousNameResponse
+		if apiErr.Code == api.CodeAmbiguousName && json.Unmarshal(data, &ambiguous) == nil {
+			apiErr.Message, apiErr.Candidates = ambiguous.Message, ambiguous.Candidates
+		}
+
+		return nil, apiErr
+	}
+
+	return &response{header: resp.Header, body: data}, nil
+}
+
+// netError is a failure to reach the server or read its response
+type netError struct{ err error }
+
+func (e *netError) Error() string { return e.err.Error() }
+func (e *netError) Unwrap() error { return e.err }
+
+// retryable reports whether the request may succeed when sent again. The features disabled on the server fail
+// whatever the attempt.
+func retryable(err error) bool {
+	switch e := err.(type) {
+	case *netError:
+		return true
+	case *Error:
+		switch e.Code {
+		case api.CodeNotificationsDisabled, api.CodeWalkInsDisabled, api.CodeTicketsDisabled:
+			return false
+		}
+		return e.Status >= http.StatusInternalServerError || e.Status == http.StatusTooManyRequests
+	}
+	return false
+}
+
+func newKey() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
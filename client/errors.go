@@ -0,0 +1,52 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/amaury95/GetGround-Party/api"
+)
+
+// Error is a request rejected by the api, Code is one of the error codes of the api package.
+// Candidates are the reservations sharing the name of the requests failing with ErrAmbiguousName.
+type Error struct {
+	Status     int
+	Code       string
+	Message    string
+	Candidates []api.Candidate
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("party api: %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("party api: %s: %s", e.Code, e.Message)
+}
+
+// Is reports whether the target is an error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// Errors matching the codes of the api, to be compared with errors.Is
+var (
+	ErrInvalidBody           = &Error{Code: api.CodeInvalidBody}
+	ErrInvalidRequest        = &Error{Code: api.CodeInvalidRequest}
+	ErrNotFound              = &Error{Code: api.CodeNotFound}
+	ErrAmbiguousName         = &Error{Code: api.CodeAmbiguousName}
+	ErrNotAccepted           = &Error{Code: api.CodeNotAccepted}
+	ErrCapacityExceeded      = &Error{Code: api.CodeCapacityExceeded}
+	ErrUnsuitableTable       = &Error{Code: api.CodeUnsuitableTable}
+	ErrConstraintViolated    = &Error{Code: api.CodeConstraintViolated}
+	ErrOversizeArrival       = &Error{Code: api.CodeOversizeArrival}
+	ErrArrivalConflict       = &Error{Code: api.CodeArrivalConflict}
+	ErrOutsideSlot           = &Error{Code: api.CodeOutsideSlot}
+	ErrInvitationExpired     = &Error{Code: api.CodeInvitationExpired}
+	ErrNotificationsDisabled = &Error{Code: api.CodeNotificationsDisabled}
+	ErrWalkInsDisabled       = &Error{Code: api.CodeWalkInsDisabled}
+	ErrTicketsDisabled       = &Error{Code: api.CodeTicketsDisabled}
+	ErrIdempotencyConflict   = &Error{Code: api.CodeIdempotencyConflict}
+	ErrUnauthorized          = &Error{Code: api.CodeUnauthorized}
+	ErrForbidden             = &Error{Code: api.CodeForbidden}
+	ErrInternal              = &Error{Code: api.CodeInternal}
+)
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/amaury95/GetGround-Party/api"
+)
+
+// EventSettings returns the settings of the event
+func (c *Client) EventSettings(ctx context.Context) (*api.EventSettings, error) {
+	var resp api.EventSettings
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/event"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateEventSettings changes the settings of the event set in the request, such as seating the walk-ins
+func (c *Client) UpdateEventSettings(ctx context.Context, req api.UpdateEventSettingsRequest) (*api.EventSettings, error) {
+	var resp api.EventSettings
+	if _, err := c.do(ctx, request{method: http.MethodPut, path: "/event", body: req}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/models"
+)
+
+// Guests returns an iterator over the arrived guests ordered by name
+func (c *Client) Guests(opts ListOptions) *GuestIterator {
+	return &GuestIterator{pager: newPager(c, "/guests", nil, opts.PageSize)}
+}
+
+// CheckIn registers the arrival of the guest with the given name and accompanying guests
+func (c *Client) CheckIn(ctx context.Context, name string, accompanyingGuests int) (*api.CreateGuestResponse, error) {
+	var resp api.CreateGuestResponse
+	if _, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/guests/" + url.PathEscape(name),
+		body:   api.CreateGuestRequest{AccompanyingGuests: accompanyingGuests},
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CheckOut registers the departure of the guest with the given name
+func (c *Client) CheckOut(ctx context.Context, name string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/guests/" + url.PathEscape(name)}, nil)
+	return err
+}
+
+// CheckInReservation registers the arrival of the guest of the reservation with the given id and accompanying guests
+func (c *Client) CheckInReservation(ctx context.Context, id string, accompanyingGuests int) (*api.CreateGuestResponse, error) {
+	var resp api.CreateGuestResponse
+	if _, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/reservations/" + url.PathEscape(id) + "/guest",
+		body:   api.CreateGuestRequest{AccompanyingGuests: accompanyingGuests},
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CheckOutReservation registers the departure of the guest of the reservation with the given id
+func (c *Client) CheckOutReservation(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/reservations/" + url.PathEscape(id) + "/guest"}, nil)
+	return err
+}
+
+// CheckInMember registers the arrival of the party member with the given id
+func (c *Client) CheckInMember(ctx context.Context, id string) (*models.Member, error) {
+	var resp models.Member
+	if _, err := c.do(ctx, request{method: http.MethodPut, path: "/members/" + url.PathEscape(id)}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CheckOutMember registers the departure of the party member with the given id
+func (c *Client) CheckOutMember(ctx context.Context, id string) (*models.Member, error) {
+	var resp models.Member
+	if _, err := c.do(ctx, request{method: http.MethodDelete, path: "/members/" + url.PathEscape(id)}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// WalkIn seats a party arriving without reservation at a table with enough free seats
+func (c *Client) WalkIn(ctx context.Context, walkIn api.CreateWalkInRequest) (*api.CreateGuestResponse, error) {
+	var resp api.CreateGuestResponse
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/walk_ins", body: walkIn}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Arrive registers the arrival of persons of the reservation with the given id, added to the ones present
+func (c *Client) Arrive(ctx context.Context, id string, persons int) (*api.CreateGuestResponse, error) {
+	var resp api.CreateGuestResponse
+	if _, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/reservations/" + url.PathEscape(id) + "/guest",
+		body:   api.CreateGuestRequest{Arriving: persons},
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Leave registers the departure of persons of the reservation with the given id
+func (c *Client) Leave(ctx context.Context, id string, persons int) error {
+	query := url.Values{"leaving": {strconv.Itoa(persons)}}
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/reservations/" + url.PathEscape(id) + "/guest", query: query}, nil)
+	return err
+}
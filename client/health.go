@@ -0,0 +1,17 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/amaury95/GetGround-Party/api"
+)
+
+// Ready returns the readiness of the server and its dependencies, a not ready server is returned as an error
+func (c *Client) Ready(ctx context.Context) (*api.HealthResponse, error) {
+	var resp api.HealthResponse
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/readyz"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/models"
+)
+
+// DefaultPageSize is the amount of elements the iterators fetch per request
+const DefaultPageSize = 100
+
+// ListOptions configures the listing of the elements
+type ListOptions struct {
+	// PageSize is the amount of elements fetched per request, DefaultPageSize when zero
+	PageSize int
+}
+
+// pager fetches the pages of a list endpoint following the next cursor of the responses
+type pager struct {
+	client *Client
+	path   string
+	query  url.Values
+	size   int
+	cursor string
+	done   bool
+	err    error
+}
+
+func newPager(c *Client, path string, query url.Values, size int) pager {
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	if query == nil {
+		query = make(url.Values)
+	}
+	return pager{client: c, path: path, query: query, size: size}
+}
+
+// fetch decodes the next page into out, it returns false when the last page was fetched or the request failed
+func (p *pager) fetch(ctx context.Context, out interface{}) bool {
+	if p.done || p.err != nil {
+		return false
+	}
+
+	query := make(url.Values, len(p.query)+2)
+	for k, v := range p.query {
+		query[k] = v
+	}
+	query.Set("limit", strconv.Itoa(p.size))
+	if p.cursor != "" {
+		query.Set("after", p.cursor)
+	}
+
+	resp, err := p.client.do(ctx, request{method: http.MethodGet, path: p.path, query: query}, out)
+	if err != nil {
+		p.err = err
+		return false
+	}
+
+	p.cursor = resp.header.Get(api.NextCursorHeader)
+	p.done = p.cursor == ""
+	return true
+}
+
+// Err returns the error that stopped the iteration, if any
+func (p *pager) Err() error { return p.err }
+
+// TableIterator iterates over the tables
+type TableIterator struct {
+	pager
+	buf     []models.Table
+	current models.Table
+}
+
+// Next advances to the next table, fetching the next page when needed. It returns false at the end or on error.
+func (it *TableIterator) Next(ctx context.Context) bool {
+	for len(it.buf) == 0 {
+		var page []models.Table
+		if !it.fetch(ctx, &page) {
+			return false
+		}
+		it.buf = page
+	}
+
+	it.current, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Table returns the current table
+func (it *TableIterator) Table() models.Table { return it.current }
+
+// ReservationIterator iterates over the guest list
+type ReservationIterator struct {
+	pager
+	buf     []models.Reservation
+	current models.Reservation
+}
+
+// Next advances to the next reservation, fetching the next page when needed. It returns false at the end or on error.
+func (it *ReservationIterator) Next(ctx context.Context) bool {
+	for len(it.buf) == 0 {
+		var page api.GetReservationsResponse
+		if !it.fetch(ctx, &page) {
+			return false
+		}
+		it.buf = page.Guests
+	}
+
+	it.current, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Reservation returns the current reservation
+func (it *ReservationIterator) Reservation() models.Reservation { return it.current }
+
+// GuestIterator iterates over the arrived guests
+type GuestIterator struct {
+	pager
+	buf     []models.Guest
+	current models.Guest
+}
+
+// Next advances to the next guest, fetching the next page when needed. It returns false at the end or on error.
+func (it *GuestIterator) Next(ctx context.Context) bool {
+	for len(it.buf) == 0 {
+		var page api.GetGuestsResponse
+		if !it.fetch(ctx, &page) {
+			return false
+		}
+		it.buf = page.Guests
+	}
+
+	it.current, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Guest returns the current guest
+func (it *GuestIterator) Guest() models.Guest { return it.current }
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileEnv is the environment variable selecting the profile used by the command line tools
+const ProfileEnv = "PARTY_PROFILE"
+
+// Profile holds the server a command line tool talks to and the credentials it uses
+type Profile struct {
+	Name   string `yaml:"-"`
+	Server string `yaml:"server"`
+	Token  string `yaml:"token"`
+
+	// CA verifies the server certificate, Cert and Key are the client certificate of mutual TLS
+	CA   string `yaml:"ca"`
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+// DefaultProfilesPath returns the path of the profiles file in the user configuration directory
+func DefaultProfilesPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "party.yaml"
+	}
+	return filepath.Join(dir, "party", "profiles.yaml")
+}
+
+// LoadProfile reads the named profile from the profiles file, the default profile of the file when name is empty.
+//
+//	default: venue
+//	profiles:
+//	  venue:
+//	    server: https://party.internal:3033
+//	    token: 4f1c...
+func LoadProfile(path, name string) (*Profile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading profiles file: %v", err)
+	}
+
+	var file struct {
+		Default  string              `yaml:"default"`
+		Profiles map[string]*Profile `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error decoding profiles file: %v", err)
+	}
+
+	if name == "" {
+		name = file.Default
+	}
+	if name == "" && len(file.Profiles) == 1 {
+		for n := range file.Profiles {
+			name = n
+		}
+	}
+
+	profile, ok := file.Profiles[name]
+	if !ok || profile == nil {
+		return nil, fmt.Errorf(`profile "%s" not found in %s`, name, path)
+	}
+	if profile.Server == "" {
+		return nil, fmt.Errorf(`profile "%s" has no server`, name)
+	}
+
+	profile.Name = name
+	return profile, nil
+}
+
+// Client returns the client of the profile server, authenticated with its token and certificates
+func (p *Profile) Client(opts ...Option) (*Client, error) {
+	if p.CA != "" || p.Cert != "" {
+		config := new(tls.Config)
+
+		if p.CA != "" {
+			pem, err := ioutil.ReadFile(p.CA)
+			if err != nil {
+				return nil, fmt.Errorf("error reading ca: %v", err)
+			}
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", p.CA)
+			}
+		}
+
+		if p.Cert != "" {
+			cert, err := tls.LoadX509KeyPair(p.Cert, p.Key)
+			if err != nil {
+				return nil, fmt.Errorf("error loading client certificate: %v", err)
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		opts = append([]Option{WithHTTPClient(&http.Client{Transport: transport, Timeout: 30 * time.Second})}, opts...)
+	}
+
+	if p.Token != "" {
+		opts = append([]Option{WithToken(p.Token)}, opts...)
+	}
+
+	return New(p.Server, opts...)
+}
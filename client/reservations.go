@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/models"
+)
+
+// ReservationListOptions configures the listing of the guest list
+type ReservationListOptions struct {
+	ListOptions
+
+	// Status filters the reservations by invitation status when set
+	Status string
+}
+
+// Reservations returns an iterator over the guest list ordered by name
+func (c *Client) Reservations(opts ReservationListOptions) *ReservationIterator {
+	query := make(url.Values)
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+	return &ReservationIterator{pager: newPager(c, "/guest_list", query, opts.PageSize)}
+}
+
+// NoShows returns an iterator over the accepted reservations whose guest has not arrived ordered by name
+func (c *Client) NoShows(opts ListOptions) *ReservationIterator {
+	return &ReservationIterator{pager: newPager(c, "/guest_list/no_shows", nil, opts.PageSize)}
+}
+
+// Reservation returns the reservation with the given id
+func (c *Client) Reservation(ctx context.Context, id string) (*models.Reservation, error) {
+	var resp models.Reservation
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/reservations/" + url.PathEscape(id)}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateReservation invites the guest with the given name
+func (c *Client) CreateReservation(ctx context.Context, name string, req api.CreateReservationRequest) (*api.CreateReservationResponse, error) {
+	var resp api.CreateReservationResponse
+	if _, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/guest_list/" + url.PathEscape(name),
+		body:   req,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateReservation changes the given fields of the reservation held by the given name
+func (c *Client) UpdateReservation(ctx context.Context, name string, req api.UpdateReservationRequest) (*models.Reservation, error) {
+	var resp models.Reservation
+	if _, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/guest_list/" + url.PathEscape(name),
+		body:   req,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CancelReservation removes the reservation held by the given name from the guest list
+func (c *Client) CancelReservation(ctx context.Context, name string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/guest_list/" + url.PathEscape(name)}, nil)
+	return err
+}
+
+// RemindReservation sends a reminder to the reservation holder
+func (c *Client) RemindReservation(ctx context.Context, name string) error {
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/guest_list/" + url.PathEscape(name) + "/reminder"}, nil)
+	return err
+}
+
+// ReservationsSummary returns the invitation counts per status and the response rates
+func (c *Client) ReservationsSummary(ctx context.Context) (*api.GetReservationsSummaryResponse, error) {
+	var resp api.GetReservationsSummaryResponse
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/guest_list/summary"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SearchReservations finds the reservations whose holder name resembles the query, the best matches first.
+// A zero limit returns the default amount of results of the server.
+func (c *Client) SearchReservations(ctx context.Context, query string, limit int) ([]api.SearchResult, error) {
+	params := url.Values{"q": {query}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp api.SearchReservationsResponse
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/guest_list/search", query: params}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// RespondInvitation accepts or declines the invitation of the rsvp token, response is api.ResponseAccept or api.ResponseDecline
+func (c *Client) RespondInvitation(ctx context.Context, token, response string) (*api.RespondInvitationResponse, error) {
+	var resp api.RespondInvitationResponse
+	if _, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/rsvp/" + url.PathEscape(token),
+		body:   api.RespondInvitationRequest{Response: response},
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
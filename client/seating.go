@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/models"
+)
+
+// Constraints returns the seating constraints ordered by id, only the ones binding the reservation with the given id
+// when it is not empty
+func (c *Client) Constraints(ctx context.Context, reservation string) ([]models.SeatingConstraint, error) {
+	query := make(url.Values)
+	if reservation != "" {
+		query.Set("reservation", reservation)
+	}
+
+	var resp []models.SeatingConstraint
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/seating/constraints", query: query}, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AddConstraint records a seating constraint between two reservations
+func (c *Client) AddConstraint(ctx context.Context, req api.CreateConstraintRequest) (*models.SeatingConstraint, error) {
+	var resp models.SeatingConstraint
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/seating/constraints", body: req}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RemoveConstraint deletes the seating constraint with the given id
+func (c *Client) RemoveConstraint(ctx context.Context, id int) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/seating/constraints/" + strconv.Itoa(id)}, nil)
+	return err
+}
+
+// Violations returns the seating constraints broken by the tables of the reservations
+func (c *Client) Violations(ctx context.Context) ([]api.Violation, error) {
+	var resp api.GetViolationsResponse
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/seating/violations"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Violations, nil
+}
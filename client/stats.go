@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+)
+
+// Stats returns the arrivals, no-shows and use of the tables of the event, the arrivals counted per bucket of the
+// given length, or of the default one of the server when zero
+func (c *Client) Stats(ctx context.Context, bucket time.Duration) (*api.GetStatsResponse, error) {
+	query := make(url.Values)
+	if bucket != 0 {
+		query.Set("bucket", bucket.String())
+	}
+
+	var resp api.GetStatsResponse
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/stats", query: query}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/models"
+)
+
+// TableListOptions configures the listing of the tables
+type TableListOptions struct {
+	ListOptions
+
+	// Zone filters the tables by zone when set
+	Zone string
+	// Features filters the tables having all the features
+	Features []string
+	// MinCapacity filters the tables seating at least the given persons when set
+	MinCapacity int
+}
+
+// Tables returns an iterator over the tables ordered by id
+func (c *Client) Tables(opts TableListOptions) *TableIterator {
+	query := make(url.Values)
+	if opts.Zone != "" {
+		query.Set("zone", opts.Zone)
+	}
+	for _, feature := range opts.Features {
+		query.Add("feature", feature)
+	}
+	if opts.MinCapacity > 0 {
+		query.Set("min_capacity", strconv.Itoa(opts.MinCapacity))
+	}
+	return &TableIterator{pager: newPager(c, "/tables", query, opts.PageSize)}
+}
+
+// CreateTable creates a table with the given capacity
+func (c *Client) CreateTable(ctx context.Context, capacity int) (*models.Table, error) {
+	return c.AddTable(ctx, api.CreateTableRequest{Capacity: capacity})
+}
+
+// AddTable creates a table with the given capacity, label, zone and features
+func (c *Client) AddTable(ctx context.Context, table api.CreateTableRequest) (*models.Table, error) {
+	var record models.Table
+	if _, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/tables",
+		body:   table,
+	}, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// SeatsEmpty returns the amount of seats not taken by the arrived guests
+func (c *Client) SeatsEmpty(ctx context.Context) (int, error) {
+	seats, err := c.Seats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return seats.SeatsEmpty, nil
+}
+
+// Seats returns the seats not taken by the arrived guests, in total and per zone
+func (c *Client) Seats(ctx context.Context) (*api.GetSeatsEmptyRespose, error) {
+	var resp api.GetSeatsEmptyRespose
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/seats_empty"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SeatsAt returns the seats not booked by the accepted reservations at the given moment, in total and per zone
+func (c *Client) SeatsAt(ctx context.Context, at time.Time) (*api.GetSeatsEmptyRespose, error) {
+	var resp api.GetSeatsEmptyRespose
+	if _, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/seats_empty",
+		query:  url.Values{"at": {at.Format(time.RFC3339)}},
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
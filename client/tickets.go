@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/amaury95/GetGround-Party/api"
+)
+
+// PublicKey returns the key the door devices verify the tickets with
+func (c *Client) PublicKey(ctx context.Context) (*api.GetPublicKeyResponse, error) {
+	var resp api.GetPublicKeyResponse
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/tickets/public_key"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Ticket returns the signed ticket of the reservation with the given name
+func (c *Client) Ticket(ctx context.Context, name string) (string, error) {
+	var resp api.GetTicketResponse
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/guest_list/" + url.PathEscape(name) + "/ticket"}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Ticket, nil
+}
+
+// SyncCheckIns uploads a batch of check-ins recorded offline by a door device
+func (c *Client) SyncCheckIns(ctx context.Context, req api.SyncCheckInsRequest) (*api.SyncCheckInsResponse, error) {
+	var resp api.SyncCheckInsResponse
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/checkins/sync", body: req}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,664 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akamensky/argparse"
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/client"
+	"github.com/amaury95/GetGround-Party/door"
+	"github.com/amaury95/GetGround-Party/models"
+)
+
+// adminCommand is a command of the admin client, run against the server of the selected profile
+type adminCommand struct {
+	cmd    *argparse.Command
+	flags  *clientFlags
+	output *string
+	run    func(ctx context.Context, c *client.Client, out *output) error
+}
+
+// clientFlags are the arguments of the commands talking to the api
+type clientFlags struct {
+	profiles *string
+	profile  *string
+	server   *string
+}
+
+func registerClientFlags(cmd *argparse.Command) *clientFlags {
+	return &clientFlags{
+		profiles: cmd.String("", "profiles", &argparse.Options{Default: client.DefaultProfilesPath(), Help: "profiles file holding the servers and credentials"}),
+		profile:  cmd.String("P", "profile", &argparse.Options{Help: "profile to use, also read from " + client.ProfileEnv + ". Default: the default profile of the file"}),
+		server:   cmd.String("", "server", &argparse.Options{Help: "server url overriding the one of the profile"}),
+	}
+}
+
+// client returns the client of the selected profile
+func (f *clientFlags) client(opts ...client.Option) (*client.Client, error) {
+	name := *f.profile
+	if name == "" {
+		name = os.Getenv(client.ProfileEnv)
+	}
+
+	// a server given without profiles file reaches an open api
+	profile := &client.Profile{Server: *f.server}
+	if _, err := os.Stat(*f.profiles); err == nil || *f.server == "" || name != "" {
+		if profile, err = client.LoadProfile(*f.profiles, name); err != nil {
+			return nil, err
+		}
+		if *f.server != "" {
+			profile.Server = *f.server
+		}
+	}
+
+	return profile.Client(opts...)
+}
+
+// registerAdminCommands adds the commands of the admin client to the parser
+func registerAdminCommands(parser *argparse.Parser) []adminCommand {
+	var commands []adminCommand
+	add := func(cmd *argparse.Command, run func(ctx context.Context, c *client.Client, out *output) error) {
+		commands = append(commands, adminCommand{
+			cmd:    cmd,
+			flags:  registerClientFlags(cmd),
+			output: cmd.Selector("o", "output", []string{FormatTable, FormatJSON, FormatCSV}, &argparse.Options{Default: FormatTable, Help: "output format"}),
+			run:    run,
+		})
+	}
+
+	// tables
+	tablesCmd := parser.NewCommand("tables", "Manages the tables.")
+
+	listTablesCmd := tablesCmd.NewCommand("list", "Lists the tables.")
+	listZone := listTablesCmd.String("z", "zone", &argparse.Options{Help: "only the tables of the zone"})
+	listFeatures := listTablesCmd.StringList("f", "feature", &argparse.Options{Help: "only the tables with the feature, repeated to require several"})
+	listMinCapacity := listTablesCmd.Int("", "min-capacity", &argparse.Options{Default: 0, Help: "only the tables seating at least the given persons"})
+	add(listTablesCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		return listTables(ctx, c, out, client.TableListOptions{Zone: *listZone, Features: *listFeatures, MinCapacity: *listMinCapacity})
+	})
+
+	createTableCmd := tablesCmd.NewCommand("create", "Creates a table.")
+	capacity := createTableCmd.Int("c", "capacity", &argparse.Options{Required: true, Help: "seats of the table"})
+	label := createTableCmd.String("l", "label", &argparse.Options{Help: "human name of the table"})
+	zone := createTableCmd.String("z", "zone", &argparse.Options{Help: "zone or room of the table"})
+	features := createTableCmd.StringList("f", "feature", &argparse.Options{Help: "feature of the table (e.g. wheelchair-accessible), repeated for several"})
+	add(createTableCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		table, err := c.AddTable(ctx, api.CreateTableRequest{Label: *label, Zone: *zone, Capacity: *capacity, Features: *features})
+		if err != nil {
+			return err
+		}
+		return out.write(table, tableColumns, [][]string{tableRow(table)})
+	})
+
+	// guests
+	guestsCmd := parser.NewCommand("guests", "Manages the arrived guests.")
+
+	add(guestsCmd.NewCommand("list", "Lists the arrived guests."), listGuests)
+	add(guestsCmd.NewCommand("no-shows", "Lists the accepted reservations whose guest has not arrived."), listNoShows)
+
+	checkInCmd := guestsCmd.NewCommand("checkin", "Checks in the guest of a reservation, or one of its members.")
+	checkInName := checkInCmd.String("n", "name", &argparse.Options{Help: "name of the reservation"})
+	checkInID := checkInCmd.String("i", "id", &argparse.Options{Help: "id of the reservation, required when its name is shared"})
+	checkInMember := checkInCmd.String("m", "member", &argparse.Options{Help: "id of the party member arriving alone"})
+	checkInGuests := checkInCmd.Int("a", "accompanying", &argparse.Options{Default: 0, Help: "accompanying guests arriving with the guest"})
+	checkInPersons := checkInCmd.Int("p", "persons", &argparse.Options{Default: 0, Help: "persons of the reservation with the given id arriving, added to the ones present"})
+	add(checkInCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		var (
+			guest *api.CreateGuestResponse
+			err   error
+		)
+		switch {
+		case *checkInMember != "":
+			member, err := c.CheckInMember(ctx, *checkInMember)
+			if err != nil {
+				return err
+			}
+			return out.write(member, []string{"id", "name", "reservation"}, [][]string{{member.ID, member.Name, member.ReservationID}})
+		case *checkInID != "" && *checkInPersons > 0:
+			guest, err = c.Arrive(ctx, *checkInID, *checkInPersons)
+		case *checkInID != "":
+			guest, err = c.CheckInReservation(ctx, *checkInID, *checkInGuests)
+		case *checkInName != "":
+			guest, err = c.CheckIn(ctx, *checkInName, *checkInGuests)
+		default:
+			return fmt.Errorf("the name or the id of the reservation, or the id of a member is required")
+		}
+		if err != nil {
+			return err
+		}
+		return out.write(guest, []string{"id", "name", "arrived", "expected"}, [][]string{{guest.ID, guest.Name, strconv.Itoa(guest.Arrived), strconv.Itoa(guest.Expected)}})
+	})
+
+	checkOutCmd := guestsCmd.NewCommand("checkout", "Checks out the guest of a reservation, or one of its members.")
+	checkOutName := checkOutCmd.String("n", "name", &argparse.Options{Help: "name of the reservation"})
+	checkOutID := checkOutCmd.String("i", "id", &argparse.Options{Help: "id of the reservation, required when its name is shared"})
+	checkOutMember := checkOutCmd.String("m", "member", &argparse.Options{Help: "id of the party member leaving alone"})
+	checkOutPersons := checkOutCmd.Int("p", "persons", &argparse.Options{Default: 0, Help: "persons of the reservation with the given id leaving, all of them if 0"})
+	add(checkOutCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		var err error
+		switch {
+		case *checkOutMember != "":
+			member, err := c.CheckOutMember(ctx, *checkOutMember)
+			if err != nil {
+				return err
+			}
+			return out.write(member, []string{"id", "name", "reservation"}, [][]string{{member.ID, member.Name, member.ReservationID}})
+		case *checkOutID != "" && *checkOutPersons > 0:
+			err = c.Leave(ctx, *checkOutID, *checkOutPersons)
+		case *checkOutID != "":
+			err = c.CheckOutReservation(ctx, *checkOutID)
+		case *checkOutName != "":
+			err = c.CheckOut(ctx, *checkOutName)
+		default:
+			return fmt.Errorf("the name or the id of the reservation, or the id of a member is required")
+		}
+		if err != nil {
+			return err
+		}
+		return out.write(map[string]string{"id": *checkOutID, "name": *checkOutName}, []string{"id", "name"}, [][]string{{*checkOutID, *checkOutName}})
+	})
+
+	walkInCmd := guestsCmd.NewCommand("walk-in", "Seats a party arriving without reservation at a table with free seats.")
+	walkInName := walkInCmd.String("n", "name", &argparse.Options{Help: "name of the party, if given"})
+	walkInPersons := walkInCmd.Int("p", "persons", &argparse.Options{Required: true, Help: "persons of the party, the holder included"})
+	walkInRequirements := walkInCmd.StringList("r", "require", &argparse.Options{Help: "feature the table must have (e.g. wheelchair-accessible), repeated for several"})
+	walkInWith := walkInCmd.StringList("w", "with", &argparse.Options{Help: "id of a reservation the party sits at the same table with, repeated for several"})
+	walkInEmail := walkInCmd.String("", "email", &argparse.Options{Help: "email of the party holder"})
+	walkInPhone := walkInCmd.String("", "phone", &argparse.Options{Help: "phone of the party holder"})
+	add(walkInCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		var rules []api.Rule
+		for _, id := range *walkInWith {
+			rules = append(rules, api.Rule{Kind: models.ConstraintSameTable, With: id})
+		}
+		guest, err := c.WalkIn(ctx, api.CreateWalkInRequest{Name: *walkInName, Persons: *walkInPersons, Requirements: *walkInRequirements, Constraints: rules, Email: *walkInEmail, Phone: *walkInPhone})
+		if err != nil {
+			return err
+		}
+		return out.write(guest, []string{"id", "reservation", "name", "table", "arrived"}, [][]string{{guest.ID, guest.Reservation, guest.Name, strconv.Itoa(guest.Table), strconv.Itoa(guest.Arrived)}})
+	})
+
+	// guest list
+	guestListCmd := parser.NewCommand("guest-list", "Manages the guest list.")
+
+	addCmd := guestListCmd.NewCommand("add", "Invites a guest.")
+	row := reservationRow{
+		Name:         addCmd.String("n", "name", &argparse.Options{Required: true, Help: "name of the guest"}),
+		Table:        addCmd.String("t", "table", &argparse.Options{Required: true, Help: "table of the reservation"}),
+		Accompanying: addCmd.String("a", "accompanying", &argparse.Options{Default: "0", Help: "accompanying guests"}),
+		Email:        addCmd.String("", "email", &argparse.Options{Help: "email of the guest"}),
+		Phone:        addCmd.String("", "phone", &argparse.Options{Help: "phone of the guest"}),
+		RespondBy:    addCmd.String("", "respond-by", &argparse.Options{Help: "RFC 3339 deadline to answer the invitation"}),
+		StartsAt:     addCmd.String("", "starts-at", &argparse.Options{Help: "RFC 3339 start of the sitting, the whole event if not set"}),
+		EndsAt:       addCmd.String("", "ends-at", &argparse.Options{Help: "RFC 3339 end of the sitting"}),
+	}
+	add(addCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		name, req, err := row.request()
+		if err != nil {
+			return err
+		}
+		resp, err := c.CreateReservation(ctx, name, req)
+		if err != nil {
+			return err
+		}
+		return out.write(resp, []string{"id", "name", "status", "rsvp_token"}, [][]string{{resp.ID, resp.Name, resp.Status, resp.RSVPToken}})
+	})
+
+	importCmd := guestListCmd.NewCommand("import", "Invites the guests of a CSV file with the name, table, accompanying_guests, email, phone, respond_by, starts_at and ends_at columns.")
+	importFile := importCmd.String("f", "file", &argparse.Options{Required: true, Help: `CSV file to import, "-" for the standard input`})
+	add(importCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		return importGuestList(ctx, c, out, *importFile)
+	})
+
+	exportCmd := guestListCmd.NewCommand("export", "Prints the guest list.")
+	exportStatus := exportCmd.Selector("s", "status", []string{models.StatusInvited, models.StatusAccepted, models.StatusDeclined, models.StatusExpired}, &argparse.Options{Help: "only the reservations with the invitation status"})
+	add(exportCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		return exportGuestList(ctx, c, out, *exportStatus)
+	})
+
+	// seating constraints
+	seatingCmd := parser.NewCommand("seating", "Manages the seating constraints between reservations.")
+
+	listConstraintsCmd := seatingCmd.NewCommand("list", "Lists the seating constraints.")
+	listConstraintsOf := listConstraintsCmd.String("r", "reservation", &argparse.Options{Help: "only the constraints binding the reservation with the given id"})
+	add(listConstraintsCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		constraints, err := c.Constraints(ctx, *listConstraintsOf)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, len(constraints))
+		for i := range constraints {
+			rows[i] = constraintRow(&constraints[i])
+		}
+		return out.write(constraints, constraintColumns, rows)
+	})
+
+	addConstraintCmd := seatingCmd.NewCommand("add", "Binds two reservations by a seating constraint.")
+	constraintKind := addConstraintCmd.Selector("k", "kind", []string{models.ConstraintSameTable, models.ConstraintAdjacentZone, models.ConstraintDifferentTable}, &argparse.Options{Required: true, Help: "rule the tables of the reservations follow"})
+	constraintReservation := addConstraintCmd.String("r", "reservation", &argparse.Options{Required: true, Help: "id of the reservation"})
+	constraintOther := addConstraintCmd.String("w", "with", &argparse.Options{Required: true, Help: "id of the other reservation"})
+	constraintNote := addConstraintCmd.String("", "note", &argparse.Options{Help: "reason of the constraint"})
+	add(addConstraintCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		constraint, err := c.AddConstraint(ctx, api.CreateConstraintRequest{Kind: *constraintKind, Reservation: *constraintReservation, Other: *constraintOther, Note: *constraintNote})
+		if err != nil {
+			return err
+		}
+		return out.write(constraint, constraintColumns, [][]string{constraintRow(constraint)})
+	})
+
+	removeConstraintCmd := seatingCmd.NewCommand("remove", "Removes a seating constraint.")
+	constraintID := removeConstraintCmd.Int("i", "id", &argparse.Options{Required: true, Help: "id of the constraint"})
+	add(removeConstraintCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		if err := c.RemoveConstraint(ctx, *constraintID); err != nil {
+			return err
+		}
+		return out.write(map[string]int{"id": *constraintID}, []string{"id"}, [][]string{{strconv.Itoa(*constraintID)}})
+	})
+
+	add(seatingCmd.NewCommand("violations", "Lists the seating constraints broken by the tables of the reservations."), func(ctx context.Context, c *client.Client, out *output) error {
+		violations, err := c.Violations(ctx)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, len(violations))
+		for i, v := range violations {
+			rows[i] = []string{strconv.Itoa(v.Constraint), v.Kind, v.Reservation, v.Other, v.Reason}
+		}
+		return out.write(violations, []string{"constraint", "kind", "reservation", "other", "reason"}, rows)
+	})
+
+	// seats
+	seatsCmd := parser.NewCommand("seats", "Prints the seats empty.")
+	seatsAt := seatsCmd.String("", "at", &argparse.Options{Help: "RFC 3339 moment to print the seats not booked at, instead of the ones not taken by the arrived guests"})
+	add(seatsCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		seatsEmpty := c.Seats
+		if *seatsAt != "" {
+			at, err := time.Parse(time.RFC3339, *seatsAt)
+			if err != nil {
+				return fmt.Errorf(`invalid "%s" time, expected an RFC 3339 time`, *seatsAt)
+			}
+			seatsEmpty = func(ctx context.Context) (*api.GetSeatsEmptyRespose, error) { return c.SeatsAt(ctx, at) }
+		}
+
+		seats, err := seatsEmpty(ctx)
+		if err != nil {
+			return err
+		}
+
+		// a row per zone, then the total of the party
+		var rows [][]string
+		for _, z := range seats.Zones {
+			rows = append(rows, []string{z.Zone, strconv.Itoa(z.Capacity), strconv.Itoa(z.SeatsEmpty)})
+		}
+		rows = append(rows, []string{"(all)", "", strconv.Itoa(seats.SeatsEmpty)})
+		return out.write(seats, []string{"zone", "capacity", "seats_empty"}, rows)
+	})
+
+	// event settings
+	eventCmd := parser.NewCommand("event", "Manages the settings of the event.")
+
+	add(eventCmd.NewCommand("show", "Prints the settings of the event."), func(ctx context.Context, c *client.Client, out *output) error {
+		settings, err := c.EventSettings(ctx)
+		if err != nil {
+			return err
+		}
+		return out.write(settings, []string{"walk_ins"}, [][]string{{strconv.FormatBool(settings.WalkIns)}})
+	})
+
+	setEventCmd := eventCmd.NewCommand("set", "Changes the settings of the event while it runs.")
+	setWalkIns := setEventCmd.Selector("w", "walk-ins", []string{"true", "false"}, &argparse.Options{Help: "whether the parties arriving without reservation are seated"})
+	add(setEventCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		var req api.UpdateEventSettingsRequest
+		if *setWalkIns != "" {
+			enabled := *setWalkIns == "true"
+			req.WalkIns = &enabled
+		}
+
+		settings, err := c.UpdateEventSettings(ctx, req)
+		if err != nil {
+			return err
+		}
+		return out.write(settings, []string{"walk_ins"}, [][]string{{strconv.FormatBool(settings.WalkIns)}})
+	})
+
+	// stats
+	statsCmd := parser.NewCommand("stats", "Prints the figures of the event, its arrivals or the use of its tables.")
+	statsReport := statsCmd.Selector("r", "report", []string{"summary", "arrivals", "tables"}, &argparse.Options{Default: "summary", Help: "figures printed as table or csv"})
+	statsBucket := statsCmd.String("b", "bucket", &argparse.Options{Help: "length of the arrival buckets. Default: the one of the server"})
+	add(statsCmd, func(ctx context.Context, c *client.Client, out *output) error {
+		var bucket time.Duration
+		if *statsBucket != "" {
+			var err error
+			if bucket, err = time.ParseDuration(*statsBucket); err != nil {
+				return fmt.Errorf(`invalid "%s" bucket, expected a duration as 15m`, *statsBucket)
+			}
+		}
+
+		stats, err := c.Stats(ctx, bucket)
+		if err != nil {
+			return err
+		}
+
+		formatFloat := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+
+		switch *statsReport {
+		case "arrivals":
+			rows := make([][]string, len(stats.Arrivals))
+			for i, a := range stats.Arrivals {
+				rows[i] = []string{a.Start.Format(time.RFC3339), strconv.Itoa(a.Parties), strconv.Itoa(a.Persons)}
+			}
+			return out.write(stats.Arrivals, []string{"bucket_start", "parties", "persons"}, rows)
+
+		case "tables":
+			rows := make([][]string, len(stats.Tables))
+			for i, t := range stats.Tables {
+				rows[i] = []string{strconv.Itoa(t.Table), t.Label, t.Zone, strconv.Itoa(t.Capacity), strconv.Itoa(t.Booked), strconv.Itoa(t.Present), formatFloat(t.Utilization)}
+			}
+			return out.write(stats.Tables, []string{"table", "label", "zone", "capacity", "booked", "present", "utilization"}, rows)
+		}
+
+		var peakAt string
+		if stats.Peak.At != nil {
+			peakAt = stats.Peak.At.Format(time.RFC3339)
+		}
+		rows := [][]string{
+			{"reservations", strconv.Itoa(stats.Reservations)},
+			{"expected", strconv.Itoa(stats.Expected)},
+			{"no_shows", strconv.Itoa(stats.NoShows)},
+			{"no_show_rate", formatFloat(stats.NoShowRate)},
+			{"average_party_size", formatFloat(stats.AveragePartySize)},
+			{"peak_persons", strconv.Itoa(stats.Peak.Persons)},
+			{"peak_at", peakAt},
+		}
+		return out.write(stats, []string{"metric", "value"}, rows)
+	})
+
+	return commands
+}
+
+// runAdminCommand runs the command against the server of its profile
+func runAdminCommand(ctx context.Context, command adminCommand) error {
+	c, err := command.flags.client()
+	if err != nil {
+		return err
+	}
+
+	return command.run(ctx, c, &output{format: *command.output, w: os.Stdout})
+}
+
+// tableColumns are the columns of the tables printed as rows
+var tableColumns = []string{"id", "label", "zone", "capacity", "features"}
+
+func tableRow(t *models.Table) []string {
+	return []string{strconv.Itoa(t.ID), t.Label, t.Zone, strconv.Itoa(t.Capacity), strings.Join(t.Features, " ")}
+}
+
+// constraintColumns are the columns of the seating constraints printed as rows
+var constraintColumns = []string{"id", "kind", "reservation", "other", "note"}
+
+func constraintRow(c *models.SeatingConstraint) []string {
+	return []string{strconv.Itoa(c.ID), c.Kind, c.ReservationID, c.OtherID, c.Note}
+}
+
+func listTables(ctx context.Context, c *client.Client, out *output, opts client.TableListOptions) error {
+	var (
+		tables []models.Table
+		rows   [][]string
+	)
+
+	it := c.Tables(opts)
+	for it.Next(ctx) {
+		t := it.Table()
+		tables = append(tables, t)
+		rows = append(rows, tableRow(&t))
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return out.write(tables, tableColumns, rows)
+}
+
+func listGuests(ctx context.Context, c *client.Client, out *output) error {
+	var (
+		guests []models.Guest
+		rows   [][]string
+	)
+
+	it := c.Guests(client.ListOptions{})
+	for it.Next(ctx) {
+		g := it.Guest()
+		guests = append(guests, g)
+		rows = append(rows, []string{g.ID, g.Name, strconv.Itoa(g.TableID), strconv.Itoa(g.AccompanyingGuests), strconv.FormatBool(g.Oversize), g.CreatedAt.Format(time.RFC3339)})
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return out.write(guests, []string{"id", "name", "table", "accompanying_guests", "oversize", "time_arrived"}, rows)
+}
+
+func listNoShows(ctx context.Context, c *client.Client, out *output) error {
+	var (
+		reservations []models.Reservation
+		rows         [][]string
+	)
+
+	it := c.NoShows(client.ListOptions{})
+	for it.Next(ctx) {
+		r := it.Reservation()
+		reservations = append(reservations, r)
+		rows = append(rows, []string{r.ID, r.Name, strconv.Itoa(r.TableID), strconv.Itoa(r.AccompanyingGuests), r.Email, r.Phone})
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return out.write(reservations, []string{"id", "name", "table", "accompanying_guests", "email", "phone"}, rows)
+}
+
+// guestListHeader are the columns of the exported guest list, the import reads the same columns but the id,
+// the imported reservations are given new ids
+var guestListHeader = []string{"id", "name", "table", "accompanying_guests", "email", "phone", "respond_by", "starts_at", "ends_at", "status"}
+
+func exportGuestList(ctx context.Context, c *client.Client, out *output, status string) error {
+	var (
+		reservations []models.Reservation
+		rows         [][]string
+	)
+
+	it := c.Reservations(client.ReservationListOptions{Status: status})
+	for it.Next(ctx) {
+		r := it.Reservation()
+		reservations = append(reservations, r)
+
+		rows = append(rows, []string{r.ID, r.Name, strconv.Itoa(r.TableID), strconv.Itoa(r.AccompanyingGuests), r.Email, r.Phone,
+			formatTime(r.RespondBy), formatTime(r.StartsAt), formatTime(r.EndsAt), r.Status})
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return out.write(reservations, guestListHeader, rows)
+}
+
+// formatTime returns the RFC 3339 time, empty when not set
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// reservationRow holds the raw fields of a reservation given as flags or as a CSV row
+type reservationRow struct {
+	Name, Table, Accompanying, Email, Phone, RespondBy, StartsAt, EndsAt *string
+}
+
+// request parses the row into the reservation request
+func (r reservationRow) request() (string, api.CreateReservationRequest, error) {
+	var req api.CreateReservationRequest
+
+	value := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return strings.TrimSpace(*s)
+	}
+
+	table, err := strconv.Atoi(value(r.Table))
+	if err != nil {
+		return "", req, fmt.Errorf(`invalid "%s" table`, value(r.Table))
+	}
+	req.Table = table
+
+	if raw := value(r.Accompanying); raw != "" {
+		if req.AccompanyingGuests, err = strconv.Atoi(raw); err != nil {
+			return "", req, fmt.Errorf(`invalid "%s" accompanying guests`, raw)
+		}
+	}
+
+	// parse the optional times
+	for _, t := range []struct {
+		raw, field string
+		dst        **time.Time
+	}{
+		{value(r.RespondBy), "respond by", &req.RespondBy},
+		{value(r.StartsAt), "starts at", &req.StartsAt},
+		{value(r.EndsAt), "ends at", &req.EndsAt},
+	} {
+		if t.raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, t.raw)
+		if err != nil {
+			return "", req, fmt.Errorf(`invalid "%s" %s, expected an RFC 3339 time`, t.raw, t.field)
+		}
+		*t.dst = &parsed
+	}
+
+	req.Email = value(r.Email)
+	req.Phone = value(r.Phone)
+
+	return value(r.Name), req, nil
+}
+
+// importResult is the outcome of a row of the imported guest list
+type importResult struct {
+	Line      int    `json:"line"`
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Status    string `json:"status,omitempty"`
+	RSVPToken string `json:"rsvp_token,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func importGuestList(ctx context.Context, c *client.Client, out *output, path string) error {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("error opening guest list: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	records, err := csv.NewReader(in).ReadAll()
+	if err != nil {
+		return fmt.Errorf("error reading guest list: %v", err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("guest list is empty")
+	}
+
+	// map the columns by the header, the columns of the export not used by the import are ignored
+	columns := make(map[string]int)
+	for i, column := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range []string{"name", "table"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf(`guest list has no "%s" column`, required)
+		}
+	}
+
+	field := func(record []string, column string) *string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return nil
+		}
+		return &record[i]
+	}
+
+	var (
+		results []importResult
+		rows    [][]string
+		failed  int
+	)
+
+	for i, record := range records[1:] {
+		row := reservationRow{
+			Name:         field(record, "name"),
+			Table:        field(record, "table"),
+			Accompanying: field(record, "accompanying_guests"),
+			Email:        field(record, "email"),
+			Phone:        field(record, "phone"),
+			RespondBy:    field(record, "respond_by"),
+			StartsAt:     field(record, "starts_at"),
+			EndsAt:       field(record, "ends_at"),
+		}
+
+		result := importResult{Line: i + 2}
+
+		name, req, err := row.request()
+		result.Name = name
+		if err == nil {
+			var resp *api.CreateReservationResponse
+			if resp, err = c.CreateReservation(ctx, name, req); err == nil {
+				result.ID, result.Status, result.RSVPToken = resp.ID, resp.Status, resp.RSVPToken
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+			failed++
+		}
+
+		results = append(results, result)
+		rows = append(rows, []string{strconv.Itoa(result.Line), result.ID, result.Name, result.Status, result.RSVPToken, result.Error})
+	}
+
+	if err := out.write(results, []string{"line", "id", "name", "status", "rsvp_token", "error"}, rows); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d guests could not be imported", failed, len(results))
+	}
+
+	return nil
+}
+
+// runDoor shows the door screen against the server of the profile
+func runDoor(flags *clientFlags, refresh string) error {
+	interval, err := time.ParseDuration(refresh)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf(`invalid "%s" refresh interval`, refresh)
+	}
+
+	// the screen retries on its own, the client fails fast so it stays responsive while offline
+	c, err := flags.client(client.WithRetries(1, 100*time.Millisecond))
+	if err != nil {
+		return err
+	}
+
+	return door.Run(c, door.Config{Refresh: interval})
+}
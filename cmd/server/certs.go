@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/amaury95/GetGround-Party/certs"
+)
+
+// generateDevCerts writes the development certificates into dir and prints how to use them
+func generateDevCerts(dir string, hosts []string) error {
+	if err := certs.GenerateDev(dir, hosts); err != nil {
+		return fmt.Errorf("error generating certificates: %v", err)
+	}
+
+	log.Printf("certificates for %v written to %s", hosts, dir)
+	log.Printf("serve them with: party serve --tls-cert=%s --tls-key=%s --tls-client-ca=%s",
+		filepath.Join(dir, certs.ServerFile), filepath.Join(dir, certs.ServerKeyFile), filepath.Join(dir, certs.CAFile))
+
+	return nil
+}
@@ -1,55 +1,128 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
+	"os/signal"
+	"strings"
 
 	"github.com/akamensky/argparse"
-	"github.com/amaury95/GetGround-Party/api"
-	"github.com/amaury95/GetGround-Party/models"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
+	"github.com/amaury95/GetGround-Party/auth"
+	"github.com/amaury95/GetGround-Party/config"
+	"github.com/amaury95/GetGround-Party/door"
+	"github.com/amaury95/GetGround-Party/logging"
+	"go.uber.org/zap"
 )
 
 func main() {
 	// setup parser
 	parser := argparse.NewParser("party", "Party is the webserver to manage GetGround invitations and guests.")
 
-	// setup parser arguments
-	var (
-		port     = parser.Int("p", "port", &argparse.Options{Default: 3033, Help: `server port to listen for requests`})
-		username = parser.String("n", "username", &argparse.Options{Default: "root", Help: `mysql connection username`})
-		password = parser.String("k", "password", &argparse.Options{Default: "example", Help: `mysql connection password`})
-		url      = parser.String("u", "url", &argparse.Options{Default: "127.0.0.1:3306", Help: `mysql connection server url`})
-		database = parser.String("d", "database", &argparse.Options{Default: "party", Help: `mysql connection database name`})
-	)
-
-	err := parser.Parse(os.Args)
-	if err != nil {
+	// setup serve command arguments
+	serveCmd := parser.NewCommand("serve", "Runs the party webserver.")
+	flags := config.RegisterFlags(serveCmd)
+
+	// setup certs command arguments
+	certsCmd := parser.NewCommand("certs", "Manages the TLS certificates.")
+	devCmd := certsCmd.NewCommand("dev", "Generates a self-signed CA, a server and a client certificate for local testing.")
+	certsDir := devCmd.String("o", "out", &argparse.Options{Default: "dev-certs", Help: `directory the certificates are written to`})
+	certsHosts := devCmd.List("H", "host", &argparse.Options{Default: []string{"localhost", "127.0.0.1"}, Help: `host names and addresses of the server certificate`})
+
+	// setup token command arguments
+	tokenCmd := parser.NewCommand("token", "Manages the api tokens.")
+	tokenCreateCmd := tokenCmd.NewCommand("create", "Generates a token and prints its entry for the tokens file.")
+	tokenName := tokenCreateCmd.String("n", "name", &argparse.Options{Required: true, Help: `name identifying the holder of the token`})
+	tokenRole := tokenCreateCmd.Selector("r", "role", []string{string(auth.RoleAdmin), string(auth.RoleStaff), string(auth.RoleViewer)}, &argparse.Options{Required: true, Help: `role granted to the token`})
+
+	// setup door command arguments
+	doorCmd := parser.NewCommand("door", "Shows the check-in screen of the door staff.")
+	doorFlags := registerClientFlags(doorCmd)
+	doorRefresh := doorCmd.String("", "refresh", &argparse.Options{Default: door.DefaultRefresh.String(), Help: `interval between the refreshes of the guest list and the seats`})
+
+	// setup admin client commands
+	adminCmds := registerAdminCommands(parser)
+
+	if err := parser.Parse(defaultServe(os.Args)); err != nil {
 		// In case of error print error and print usage
 		// This can also be done by passing -h or --help flags
-		fmt.Print(parser.Usage(err))
+		fmt.Fprint(os.Stderr, parser.Usage(err))
+		os.Exit(2)
 	}
 
-	// open db connection
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=true", *username, *password, *url, *database)
-	db, err := gorm.Open(mysql.Open(dsn), new(gorm.Config))
-	if err != nil {
-		panic("error connecting to database: " + err.Error())
-	}
+	switch {
+	case serveCmd.Happened():
+		// load configuration layers
+		cfg, err := config.Load(flags, os.Environ())
+		if err != nil {
+			fmt.Fprint(os.Stderr, serveCmd.Usage(err))
+			os.Exit(2)
+		}
+
+		logger, err := logging.New(cfg.Logging())
+		if err != nil {
+			fmt.Fprint(os.Stderr, serveCmd.Usage(err))
+			os.Exit(2)
+		}
+
+		// route the standard library logs of the dependencies through the structured logger
+		restore := zap.RedirectStdLog(logger)
+
+		for _, line := range cfg.Report() {
+			logger.Info("config", zap.String("setting", line))
+		}
 
-	// migrate the models to create database tables
-	db.AutoMigrate(new(models.Table))
-	db.AutoMigrate(new(models.Guest))
-	db.AutoMigrate(new(models.Reservation))
+		if err := serve(cfg, logger); err != nil {
+			logger.Error("server failed", zap.Error(err))
+			logger.Sync()
+			os.Exit(1)
+		}
 
-	// create router instance
-	router := new(api.Handler).WithConnection(db).Router(&api.RouterConfig{
-		ShowLogs:    true,
-		ReleaseMode: true,
-	})
+		restore()
+		logger.Sync()
 
-	if err := router.Run(fmt.Sprintf(":%d", *port)); err != nil {
-		panic("error running the server: " + err.Error())
+	case devCmd.Happened():
+		if err := generateDevCerts(*certsDir, *certsHosts); err != nil {
+			log.Print(err)
+			os.Exit(1)
+		}
+
+	case tokenCreateCmd.Happened():
+		if err := createToken(*tokenName, auth.Role(*tokenRole)); err != nil {
+			log.Print(err)
+			os.Exit(1)
+		}
+
+	case doorCmd.Happened():
+		if err := runDoor(doorFlags, *doorRefresh); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	default:
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		for _, command := range adminCmds {
+			if !command.cmd.Happened() {
+				continue
+			}
+			if err := runAdminCommand(ctx, command); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				stop()
+				os.Exit(1)
+			}
+		}
 	}
 }
+
+// defaultServe runs the server when no command is given, as the party binary did before it had commands, keeping the
+// help of the commands on -h
+func defaultServe(args []string) []string {
+	if len(args) > 1 && (!strings.HasPrefix(args[1], "-") || args[1] == "-h" || args[1] == "--help") {
+		return args
+	}
+
+	return append([]string{args[0], "serve"}, args[1:]...)
+}
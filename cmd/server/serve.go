@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/auth"
+	"github.com/amaury95/GetGround-Party/certs"
+	"github.com/amaury95/GetGround-Party/config"
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/metrics"
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/notify"
+	"github.com/amaury95/GetGround-Party/occupancy"
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/amaury95/GetGround-Party/rpc"
+	"github.com/amaury95/GetGround-Party/tickets"
+	"github.com/amaury95/GetGround-Party/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// serve starts the server and blocks until it is stopped by a signal or fails.
+// Resources are released in the reverse order they were acquired.
+func serve(cfg *config.Config, logger *zap.Logger) (err error) {
+	var teardown []func() error
+	defer func() {
+		for i := len(teardown) - 1; i >= 0; i-- {
+			if cerr := teardown[i](); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	// setup tracing, shut down last to flush the spans of the teardown
+	var tracer *sdktrace.TracerProvider
+	if cfg.TracingConfig().Enabled() {
+		if tracer, err = tracing.New(context.Background(), cfg.TracingConfig(), os.Stdout); err != nil {
+			return fmt.Errorf("error setting up tracing: %v", err)
+		}
+		teardown = append(teardown, func() error {
+			logger.Info("flushing traces")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			return tracer.Shutdown(ctx)
+		})
+	}
+
+	// open db connection
+	db, err := gorm.Open(mysql.Open(cfg.Database.DSN()), &gorm.Config{
+		Logger: logging.NewGorm(logger, 200*time.Millisecond),
+	})
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+
+	conn, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("error retrieving database pool: %v", err)
+	}
+	conn.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	conn.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	conn.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	teardown = append(teardown, func() error {
+		logger.Info("closing database connections")
+		return conn.Close()
+	})
+
+	// migrate the models to create database tables
+	if err := models.Migrate(db); err != nil {
+		return fmt.Errorf("error migrating database: %v", err)
+	}
+
+	// load tickets signing key
+	key, err := tickets.LoadOrCreateKey(cfg.Tickets.KeyFile)
+	if err != nil {
+		return fmt.Errorf("error loading ticket key: %v", err)
+	}
+
+	// instrument database for prometheus
+	collectors := metrics.New()
+	if err := collectors.Instrument(db); err != nil {
+		return fmt.Errorf("error instrumenting database: %v", err)
+	}
+
+	// publish the occupancy changes to the gRPC watchers
+	broker := occupancy.NewBroker()
+	if err := broker.Instrument(db); err != nil {
+		return fmt.Errorf("error instrumenting database: %v", err)
+	}
+
+	// both transports run the operations of the same service
+	issuer := tickets.NewIssuer(key)
+	policy, err := party.ParseArrivalPolicy(cfg.Arrivals.Policy)
+	if err != nil {
+		return err
+	}
+	zones, err := party.ParseAdjacentZones(cfg.Seating.AdjacentZones)
+	if err != nil {
+		return err
+	}
+	service := party.New(db).WithTicketIssuer(issuer).WithArrivalPolicy(policy).WithWalkIns(cfg.Arrivals.WalkIns).
+		WithGracePeriods(cfg.Arrivals.EarlyGrace, cfg.Arrivals.LateGrace).WithTicketValidity(cfg.Tickets.TTL, cfg.Tickets.ClockSkew).
+		WithAdjacentZones(zones).
+		WithCommitHook(collectors.Refresh).WithCommitHook(broker.Refresh)
+
+	handler := new(api.Handler).WithConnection(db).WithTicketIssuer(issuer).WithService(service).WithMetrics(collectors)
+	rpcHandler := new(rpc.Handler).WithService(service).WithOccupancy(broker)
+
+	// require bearer tokens
+	if cfg.Auth.TokensFile != "" {
+		tokens, err := auth.LoadTokens(cfg.Auth.TokensFile)
+		if err != nil {
+			return fmt.Errorf("error loading auth tokens: %v", err)
+		}
+		handler.WithAuth(tokens)
+		rpcHandler.WithAuth(tokens)
+	}
+
+	// trace the requests and the database statements
+	if tracer != nil {
+		if err := tracing.Instrument(db, tracer); err != nil {
+			return fmt.Errorf("error instrumenting database: %v", err)
+		}
+		handler.WithTracing(tracer)
+		rpcHandler.WithTracing(tracer)
+	}
+
+	// setup notifications transport
+	var transport notify.Notifier
+	switch cfg.Notifications.Transport {
+	case "log":
+		out := os.Stdout
+		if cfg.Notifications.LogFile != "" {
+			if out, err = os.OpenFile(cfg.Notifications.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+				return fmt.Errorf("error opening notifications log: %v", err)
+			}
+			teardown = append(teardown, out.Close)
+		}
+		transport = notify.NewLog(out)
+	case "smtp":
+		transport = &notify.SMTP{
+			Host:     cfg.Notifications.SMTP.Host,
+			Port:     cfg.Notifications.SMTP.Port,
+			Username: cfg.Notifications.SMTP.Username,
+			Password: cfg.Notifications.SMTP.Password,
+			From:     cfg.Notifications.SMTP.From,
+		}
+	}
+
+	if transport != nil {
+		queue := notify.NewQueue(transport, 5, time.Second, logger)
+		teardown = append(teardown, func() error {
+			logger.Info("stopping notifications queue")
+			return queue.Close()
+		})
+		service.WithNotifier(queue)
+	}
+
+	// create router instance
+	router := handler.Router(cfg.RouterConfig(logger))
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// serve over TLS, reloading the certificates on SIGHUP
+	var reloader *certs.Reloader
+	if cfg.Server.TLS.Enabled() {
+		if reloader, err = certs.NewReloader(cfg.Server.TLS.Cert, cfg.Server.TLS.Key, cfg.Server.TLS.ClientCA); err != nil {
+			return fmt.Errorf("error loading tls certificates: %v", err)
+		}
+		server.TLSConfig = reloader.TLSConfig()
+
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
+		go func() {
+			for range hup {
+				if err := reloader.Reload(); err != nil {
+					logger.Error("error reloading tls certificates, keeping the current ones", zap.Error(err))
+					continue
+				}
+				logger.Info("tls certificates reloaded")
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan error, 2)
+
+	// serve the gRPC services on their own port, with the same certificates
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPCPort != 0 {
+		serverConfig := &rpc.ServerConfig{Logger: logger}
+		if reloader != nil {
+			serverConfig.Credentials = credentials.NewTLS(reloader.TLSConfig())
+		}
+		grpcServer = rpcHandler.Server(serverConfig)
+
+		addr := fmt.Sprintf(":%d", cfg.Server.GRPCPort)
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("error listening for grpc: %v", err)
+		}
+
+		go func() {
+			logger.Info("listening", zap.String("addr", addr), zap.String("protocol", "grpc"), zap.Bool("tls", reloader != nil))
+			if err := grpcServer.Serve(lis); err != nil {
+				failed <- fmt.Errorf("grpc: %v", err)
+			}
+		}()
+	}
+
+	go func() {
+		var err error
+		if reloader != nil {
+			logger.Info("listening", zap.String("addr", server.Addr), zap.Bool("tls", true), zap.Bool("mutual_tls", reloader.MutualTLS()))
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			logger.Info("listening", zap.String("addr", server.Addr), zap.Bool("tls", false))
+			err = server.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
+
+	// a server failing stops the other one through the same shutdown
+	var failure error
+	select {
+	case err := <-failed:
+		failure = fmt.Errorf("error running the server: %v", err)
+		logger.Error("server failed, shutting down", zap.Error(err))
+	case <-ctx.Done():
+	}
+
+	// stop listening and drain the in-flight requests before releasing the resources they use
+	logger.Info("shutting down, draining requests", zap.Duration("timeout", cfg.Server.ShutdownTimeout))
+
+	drain, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if grpcServer != nil {
+		// occupancy watchers never finish, they are cut once the drain deadline is reached
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		defer func() {
+			select {
+			case <-stopped:
+			case <-drain.Done():
+				grpcServer.Stop()
+			}
+		}()
+	}
+
+	if err := server.Shutdown(drain); err != nil && failure == nil {
+		return fmt.Errorf("error draining the server: %v", err)
+	}
+
+	return failure
+}
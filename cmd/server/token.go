@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/amaury95/GetGround-Party/auth"
+	"gopkg.in/yaml.v3"
+)
+
+// createToken generates a token for the role and prints it with the entry to append to the tokens file
+func createToken(name string, role auth.Role) error {
+	value, err := auth.Generate()
+	if err != nil {
+		return fmt.Errorf("error generating token: %v", err)
+	}
+
+	entry, err := yaml.Marshal([]auth.Token{{Name: name, SHA256: auth.Hash(value), Role: role}})
+	if err != nil {
+		return fmt.Errorf("error encoding token: %v", err)
+	}
+
+	fmt.Fprintf(os.Stdout, "token: %s\n\nappend to the tokens of the tokens file, the token itself is not stored:\n\n%s", value, entry)
+
+	return nil
+}
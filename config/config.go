@@ -0,0 +1,312 @@
+/*
+Package config holds the settings of the party server and their loading.
+
+Settings are layered, each source overriding the previous one:
+
+	defaults < config file (YAML or TOML) < PARTY_* environment variables < command line flags
+
+Every setting has a dotted key (e.g. "database.password") used in the config file. The environment variable
+is the key in upper case prefixed with PARTY_ and with the dots replaced by underscores (e.g. PARTY_DATABASE_PASSWORD).
+*/
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/amaury95/GetGround-Party/tracing"
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+// Config holds every setting of the server
+type Config struct {
+	Server        Server
+	Database      Database
+	Router        Router
+	Log           Log
+	Tracing       Tracing
+	Auth          Auth
+	Tickets       Tickets
+	Notifications Notifications
+	Arrivals      Arrivals
+	Seating       Seating
+
+	// sources records where each setting was loaded from
+	sources map[string]string
+}
+
+// Server holds the http server settings
+type Server struct {
+	Port            int
+	GRPCPort        int
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	TLS             TLS
+}
+
+// TLS holds the certificates served by the http server. Client certificates are verified when ClientCA is set.
+type TLS struct {
+	Cert     string
+	Key      string
+	ClientCA string
+}
+
+// Enabled reports whether the server is served over TLS
+func (t *TLS) Enabled() bool { return t.Cert != "" }
+
+// Database holds the mysql connection and pool settings
+type Database struct {
+	Username        string
+	Password        string
+	URL             string
+	Name            string
+	Timeout         time.Duration
+	TLS             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// Router holds the api router settings
+type Router struct {
+	ReleaseMode  bool
+	HealthChecks bool
+}
+
+// Log holds the structured logging settings
+type Log struct {
+	Level       string
+	Format      string
+	RedactNames bool
+	RedactKey   string
+}
+
+// Tracing holds the OpenTelemetry exporter settings
+type Tracing struct {
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+}
+
+// Auth holds the api authentication settings
+type Auth struct {
+	TokensFile string
+}
+
+// Tickets holds the offline tickets settings, the clock skew is the drift allowed to the clocks of the door devices
+type Tickets struct {
+	KeyFile   string
+	TTL       time.Duration
+	ClockSkew time.Duration
+}
+
+// Arrivals holds the check-in settings, the grace periods widen the slots of the reservations
+type Arrivals struct {
+	Policy     string
+	WalkIns    bool
+	EarlyGrace time.Duration
+	LateGrace  time.Duration
+}
+
+// Seating holds the seating plan settings, the adjacent zones are comma separated pairs of zones joined by a colon
+type Seating struct {
+	AdjacentZones string
+}
+
+// Notifications holds the notifications transport settings
+type Notifications struct {
+	Transport string
+	LogFile   string
+	SMTP      SMTP
+}
+
+// SMTP holds the email server settings
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Default returns the configuration used when no source overrides the settings
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:            3033,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Database: Database{
+			Username:        "root",
+			URL:             "127.0.0.1:3306",
+			Name:            "party",
+			Timeout:         10 * time.Second,
+			TLS:             "false",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Router: Router{
+			ReleaseMode:  true,
+			HealthChecks: true,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: logging.FormatJSON,
+		},
+		Tracing: Tracing{
+			Exporter:    tracing.ExporterNone,
+			Endpoint:    "localhost:4318",
+			SampleRatio: 1,
+		},
+		Tickets: Tickets{
+			KeyFile:   "ticket_ed25519.pem",
+			TTL:       party.DefaultTicketTTL,
+			ClockSkew: party.DefaultClockSkew,
+		},
+		Arrivals: Arrivals{
+			Policy:     string(party.ArrivalsFlag),
+			EarlyGrace: 15 * time.Minute,
+		},
+		Notifications: Notifications{
+			Transport: "none",
+			SMTP: SMTP{
+				Host: "localhost",
+				Port: 25,
+				From: "party@localhost",
+			},
+		},
+	}
+}
+
+// DSN returns the mysql data source name of the database settings, escaping the credentials
+func (d *Database) DSN() string {
+	dsn := mysql.NewConfig()
+	dsn.User = d.Username
+	dsn.Passwd = d.Password
+	dsn.Net = "tcp"
+	dsn.Addr = d.URL
+	dsn.DBName = d.Name
+	dsn.Params = map[string]string{"charset": "utf8mb4"}
+	dsn.ParseTime = true
+	dsn.Timeout = d.Timeout
+	dsn.TLSConfig = d.TLS
+
+	return dsn.FormatDSN()
+}
+
+// Logging returns the logging configuration
+func (c *Config) Logging() logging.Config {
+	return logging.Config{
+		Level:       c.Log.Level,
+		Format:      c.Log.Format,
+		RedactNames: c.Log.RedactNames,
+		RedactKey:   c.Log.RedactKey,
+	}
+}
+
+// TracingConfig returns the tracing configuration
+func (c *Config) TracingConfig() tracing.Config {
+	return tracing.Config{
+		Exporter:    c.Tracing.Exporter,
+		Endpoint:    c.Tracing.Endpoint,
+		Insecure:    c.Tracing.Insecure,
+		SampleRatio: c.Tracing.SampleRatio,
+	}
+}
+
+// RouterConfig returns the api router configuration logging the requests with the given logger
+func (c *Config) RouterConfig(logger *zap.Logger) *api.RouterConfig {
+	return &api.RouterConfig{
+		Logger:       logger,
+		ReleaseMode:  c.Router.ReleaseMode,
+		HealthChecks: c.Router.HealthChecks,
+	}
+}
+
+// Validate checks the settings values are consistent
+func (c *Config) Validate() error {
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		return fmt.Errorf(`invalid "%d" server port`, c.Server.Port)
+	}
+
+	if c.Server.GRPCPort < 0 || c.Server.GRPCPort > 65535 || c.Server.GRPCPort == c.Server.Port {
+		return fmt.Errorf(`invalid "%d" grpc port`, c.Server.GRPCPort)
+	}
+
+	durations := map[string]time.Duration{
+		"server.read_timeout":     c.Server.ReadTimeout,
+		"server.write_timeout":    c.Server.WriteTimeout,
+		"server.idle_timeout":     c.Server.IdleTimeout,
+		"server.shutdown_timeout": c.Server.ShutdownTimeout,
+		"database.timeout":        c.Database.Timeout,
+	}
+	for key, d := range durations {
+		if d <= 0 {
+			return fmt.Errorf(`%s must be positive, got "%s"`, key, d)
+		}
+	}
+
+	if (c.Server.TLS.Cert == "") != (c.Server.TLS.Key == "") {
+		return fmt.Errorf("server.tls.cert and server.tls.key must be set together")
+	}
+
+	if c.Server.TLS.ClientCA != "" && !c.Server.TLS.Enabled() {
+		return fmt.Errorf("server.tls.client_ca requires server.tls.cert and server.tls.key")
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		return fmt.Errorf("database pool sizes can not be negative")
+	}
+
+	switch c.Database.TLS {
+	case "true", "false", "skip-verify", "preferred":
+	default:
+		return fmt.Errorf(`invalid "%s" database tls mode, expected true, false, skip-verify or preferred`, c.Database.TLS)
+	}
+
+	if err := c.Logging().Validate(); err != nil {
+		return err
+	}
+
+	if err := c.TracingConfig().Validate(); err != nil {
+		return err
+	}
+
+	switch c.Notifications.Transport {
+	case "none", "log", "smtp":
+	default:
+		return fmt.Errorf(`invalid "%s" notifications transport, expected none, log or smtp`, c.Notifications.Transport)
+	}
+
+	if _, err := party.ParseArrivalPolicy(c.Arrivals.Policy); err != nil {
+		return err
+	}
+
+	if c.Tickets.TTL <= 0 {
+		return fmt.Errorf("the validity of the tickets must be positive")
+	}
+	if c.Tickets.ClockSkew < 0 {
+		return fmt.Errorf("the clock skew of the door devices can not be negative")
+	}
+
+	if c.Arrivals.EarlyGrace < 0 || c.Arrivals.LateGrace < 0 {
+		return fmt.Errorf("the arrival grace periods can not be negative")
+	}
+
+	if _, err := party.ParseAdjacentZones(c.Seating.AdjacentZones); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,321 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/akamensky/argparse"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources a setting can be loaded from
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// EnvPrefix is the prefix of the environment variables holding settings
+const EnvPrefix = "PARTY_"
+
+// setting binds a key of the configuration to its field, its flag and its help message
+type setting struct {
+	key    string
+	short  string
+	flag   string
+	help   string
+	secret bool
+	value  interface{}
+}
+
+// settings returns the bindings of every setting of the configuration
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "server.port", short: "p", flag: "port", help: "server port to listen for requests", value: &c.Server.Port},
+		{key: "server.grpc_port", flag: "grpc-port", help: "port of the gRPC server, sharing the TLS settings of the http server. Disabled if 0", value: &c.Server.GRPCPort},
+		{key: "server.read_timeout", flag: "read-timeout", help: "maximum duration for reading a request", value: &c.Server.ReadTimeout},
+		{key: "server.write_timeout", flag: "write-timeout", help: "maximum duration before timing out the response writes", value: &c.Server.WriteTimeout},
+		{key: "server.idle_timeout", flag: "idle-timeout", help: "maximum duration to wait for the next request on keep-alive connections", value: &c.Server.IdleTimeout},
+		{key: "server.shutdown_timeout", flag: "shutdown-timeout", help: "deadline to drain the in-flight requests on shutdown", value: &c.Server.ShutdownTimeout},
+		{key: "server.tls.cert", flag: "tls-cert", help: "PEM certificate to serve over TLS, reloaded on SIGHUP", value: &c.Server.TLS.Cert},
+		{key: "server.tls.key", flag: "tls-key", help: "PEM private key of the TLS certificate", value: &c.Server.TLS.Key},
+		{key: "server.tls.client_ca", flag: "tls-client-ca", help: "PEM bundle of authorities the client certificates are verified against (mutual TLS)", value: &c.Server.TLS.ClientCA},
+
+		{key: "database.username", short: "n", flag: "username", help: "mysql connection username", value: &c.Database.Username},
+		{key: "database.password", short: "k", flag: "password", help: "mysql connection password", secret: true, value: &c.Database.Password},
+		{key: "database.url", short: "u", flag: "url", help: "mysql connection server url", value: &c.Database.URL},
+		{key: "database.name", short: "d", flag: "database", help: "mysql connection database name", value: &c.Database.Name},
+		{key: "database.timeout", flag: "db-timeout", help: "mysql connection dial timeout", value: &c.Database.Timeout},
+		{key: "database.tls", flag: "db-tls", help: "mysql connection tls mode (true, false, skip-verify or preferred)", value: &c.Database.TLS},
+		{key: "database.max_open_conns", flag: "db-max-open-conns", help: "maximum amount of open database connections, 0 is unlimited", value: &c.Database.MaxOpenConns},
+		{key: "database.max_idle_conns", flag: "db-max-idle-conns", help: "maximum amount of idle database connections", value: &c.Database.MaxIdleConns},
+		{key: "database.conn_max_lifetime", flag: "db-conn-max-lifetime", help: "maximum duration a database connection is reused", value: &c.Database.ConnMaxLifetime},
+
+		{key: "router.release_mode", flag: "release-mode", help: "run the router in release mode", value: &c.Router.ReleaseMode},
+		{key: "router.health_checks", flag: "health-checks", help: "expose the liveness and readiness probes", value: &c.Router.HealthChecks},
+
+		{key: "log.level", flag: "log-level", help: "minimum level of the logs (debug, info, warn, error or off)", value: &c.Log.Level},
+		{key: "log.format", flag: "log-format", help: "format of the logs (json or console)", value: &c.Log.Format},
+		{key: "log.redact_names", flag: "log-redact-names", help: "replace the guest names in the logs by a keyed hash", value: &c.Log.RedactNames},
+		{key: "log.redact_key", flag: "log-redact-key", help: "secret key of the hash replacing the guest names, random on each start if empty", secret: true, value: &c.Log.RedactKey},
+
+		{key: "tracing.exporter", flag: "tracing-exporter", help: "exporter of the request traces (none, stdout or otlp)", value: &c.Tracing.Exporter},
+		{key: "tracing.endpoint", flag: "tracing-endpoint", help: "host and port of the OTLP/HTTP collector", value: &c.Tracing.Endpoint},
+		{key: "tracing.insecure", flag: "tracing-insecure", help: "export the traces to the collector over plain HTTP", value: &c.Tracing.Insecure},
+		{key: "tracing.sample_ratio", flag: "tracing-sample-ratio", help: "fraction of the traces started by the server that are sampled", value: &c.Tracing.SampleRatio},
+
+		{key: "auth.tokens_file", flag: "auth-tokens", help: "YAML file of the bearer tokens allowed to call the api, the api is open if empty", value: &c.Auth.TokensFile},
+
+		{key: "tickets.key_file", short: "t", flag: "ticket-key", help: "ed25519 private key used to sign tickets, generated if missing", value: &c.Tickets.KeyFile},
+		{key: "tickets.ttl", flag: "ticket-ttl", help: "how long the tickets are valid once issued, the ones of slotted reservations at least until their slot ends", value: &c.Tickets.TTL},
+		{key: "tickets.clock_skew", flag: "clock-skew", help: "how far ahead of the server the clocks of the door devices syncing offline check-ins may run", value: &c.Tickets.ClockSkew},
+
+		{key: "arrivals.policy", flag: "arrival-policy", help: "check-in of the guests arriving with more accompanying guests than booked (reject, or flag when the table has room)", value: &c.Arrivals.Policy},
+		{key: "arrivals.early_grace", flag: "early-grace", help: "how early before the start of its slot a reserved party is checked in", value: &c.Arrivals.EarlyGrace},
+		{key: "arrivals.late_grace", flag: "late-grace", help: "how late after the end of its slot a reserved party is checked in", value: &c.Arrivals.LateGrace},
+		{key: "arrivals.walk_ins", flag: "walk-ins", help: "seat the parties arriving without reservation at the tables with free seats", value: &c.Arrivals.WalkIns},
+
+		{key: "seating.adjacent_zones", flag: "adjacent-zones", help: "zones next to each other for the adjacent_zone seating constraints, as comma separated pairs such as garden:terrace", value: &c.Seating.AdjacentZones},
+
+		{key: "notifications.transport", flag: "notifier", help: "transport used to message the reservation holders (none, log or smtp)", value: &c.Notifications.Transport},
+		{key: "notifications.log_file", flag: "notify-log", help: "file the log notifier writes to, standard output if empty", value: &c.Notifications.LogFile},
+		{key: "notifications.smtp.host", flag: "smtp-host", help: "smtp server host", value: &c.Notifications.SMTP.Host},
+		{key: "notifications.smtp.port", flag: "smtp-port", help: "smtp server port", value: &c.Notifications.SMTP.Port},
+		{key: "notifications.smtp.username", flag: "smtp-username", help: "smtp server username", value: &c.Notifications.SMTP.Username},
+		{key: "notifications.smtp.password", flag: "smtp-password", help: "smtp server password", secret: true, value: &c.Notifications.SMTP.Password},
+		{key: "notifications.smtp.from", flag: "smtp-from", help: "sender address of the emails", value: &c.Notifications.SMTP.From},
+	}
+}
+
+// env returns the name of the environment variable of the setting
+func (s setting) env() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+// set parses the raw value into the setting field
+func (s setting) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch v := s.value.(type) {
+	case *string:
+		*v = raw
+	case *int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf(`%s expects an integer, got "%s"`, s.key, raw)
+		}
+		*v = i
+	case *float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf(`%s expects a number, got "%s"`, s.key, raw)
+		}
+		*v = f
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf(`%s expects a boolean, got "%s"`, s.key, raw)
+		}
+		*v = b
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf(`%s expects a duration such as "15s", got "%s"`, s.key, raw)
+		}
+		*v = d
+	default:
+		return fmt.Errorf("%s has an unsupported type %T", s.key, s.value)
+	}
+
+	return nil
+}
+
+// format returns the printable value of the setting, secrets are redacted
+func (s setting) format() string {
+	var value string
+	switch v := s.value.(type) {
+	case *string:
+		value = strconv.Quote(*v)
+	case *int:
+		value = strconv.Itoa(*v)
+	case *float64:
+		value = strconv.FormatFloat(*v, 'g', -1, 64)
+	case *bool:
+		value = strconv.FormatBool(*v)
+	case *time.Duration:
+		value = v.String()
+	}
+
+	if s.secret && value != `""` {
+		return "********"
+	}
+
+	return value
+}
+
+// Flags are the command line arguments of the settings
+type Flags struct {
+	file   *string
+	values map[string]*string
+}
+
+// RegisterFlags adds a command line argument for every setting and the config file path to the command
+func RegisterFlags(cmd *argparse.Command) *Flags {
+	f := &Flags{
+		file:   cmd.String("c", "config", &argparse.Options{Help: "YAML or TOML configuration file, also read from " + EnvPrefix + "CONFIG"}),
+		values: make(map[string]*string),
+	}
+
+	for _, s := range Default().settings() {
+		help := s.help
+		if value := strings.Trim(s.format(), `"`); value != "" {
+			help += ". Default: " + value
+		}
+
+		f.values[s.key] = cmd.String(s.short, s.flag, &argparse.Options{Help: help})
+	}
+
+	return f
+}
+
+// Load builds the configuration from the defaults, the config file, the environment and the flags.
+// The environment is given as a list of KEY=value pairs, as returned by os.Environ.
+func Load(flags *Flags, environ []string) (*Config, error) {
+	c := Default()
+	c.sources = make(map[string]string)
+
+	settings := c.settings()
+	for _, s := range settings {
+		c.sources[s.key] = SourceDefault
+	}
+
+	env := make(map[string]string, len(environ))
+	for _, pair := range environ {
+		if i := strings.Index(pair, "="); i > 0 {
+			env[pair[:i]] = pair[i+1:]
+		}
+	}
+
+	// config file
+	path := env[EnvPrefix+"CONFIG"]
+	if flags != nil && *flags.file != "" {
+		path = *flags.file
+	}
+
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		byKey := make(map[string]setting, len(settings))
+		for _, s := range settings {
+			byKey[s.key] = s
+		}
+
+		for key, value := range values {
+			s, ok := byKey[key]
+			if !ok {
+				return nil, fmt.Errorf(`unknown setting "%s" in %s`, key, path)
+			}
+			// empty settings keep their default
+			if value == nil {
+				continue
+			}
+			if err := s.set(fmt.Sprint(value)); err != nil {
+				return nil, fmt.Errorf("error in %s: %v", path, err)
+			}
+			c.sources[key] = SourceFile + " " + path
+		}
+	}
+
+	// environment variables
+	for _, s := range settings {
+		if value, ok := env[s.env()]; ok {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("error in %s: %v", s.env(), err)
+			}
+			c.sources[s.key] = SourceEnv + " " + s.env()
+		}
+	}
+
+	// command line flags
+	if flags != nil {
+		for _, s := range settings {
+			if value := *flags.values[s.key]; value != "" {
+				if err := s.set(value); err != nil {
+					return nil, fmt.Errorf("error in --%s: %v", s.flag, err)
+				}
+				c.sources[s.key] = SourceFlag + " --" + s.flag
+			}
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Report returns a line per setting with its value and the source it was loaded from, secrets are redacted
+func (c *Config) Report() []string {
+	var lines []string
+	for _, s := range c.settings() {
+		source := c.sources[s.key]
+		if source == "" {
+			source = SourceDefault
+		}
+		lines = append(lines, fmt.Sprintf("%s = %s (%s)", s.key, s.format(), source))
+	}
+	return lines
+}
+
+// readFile decodes the YAML or TOML file into a map of dotted keys to values
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	tree := make(map[string]interface{})
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf(`unsupported "%s" config file format, expected .yaml, .yml or .toml`, ext)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error decoding config file: %v", err)
+	}
+
+	values := make(map[string]interface{})
+	flatten("", tree, values)
+
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, values map[string]interface{}) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(key, nested, values)
+			continue
+		}
+
+		values[key] = value
+	}
+}
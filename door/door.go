@@ -0,0 +1,386 @@
+/*
+Package door implements the terminal screen the door staff use to check in the guests.
+
+It talks to the api through the client, so it can run on any laptop reaching the server. The screen keeps
+showing the last state fetched while the server is not reachable, and resumes on its own when it comes back.
+*/
+package door
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/client"
+	"github.com/amaury95/GetGround-Party/models"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Default timings of the screen
+const (
+	DefaultRefresh = 3 * time.Second
+	DefaultTimeout = 5 * time.Second
+)
+
+// Config holds the timings of the screen
+type Config struct {
+	// Refresh is the interval between the fetches of the party state
+	Refresh time.Duration
+	// Timeout bounds every call to the api
+	Timeout time.Duration
+}
+
+type (
+	snapshotMsg   struct{ snapshot *Snapshot }
+	syncFailedMsg struct{ err error }
+	tickMsg       struct{}
+	actionMsg     struct {
+		verb string
+		name string
+		err  error
+	}
+)
+
+// Model is the state of the door screen
+type Model struct {
+	client *client.Client
+	config Config
+
+	snapshot *Snapshot
+	syncing  bool
+	syncErr  error
+
+	query      string
+	cursor     int
+	persons    int
+	showTables bool
+	status     string
+	height     int
+}
+
+// New returns the door screen of the party served behind the client
+func New(c *client.Client, config Config) *Model {
+	if config.Refresh <= 0 {
+		config.Refresh = DefaultRefresh
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	return &Model{client: c, config: config, syncing: true}
+}
+
+// Run shows the door screen on the terminal until the staff quits it
+func Run(c *client.Client, config Config) error {
+	return tea.NewProgram(New(c, config), tea.WithAltScreen()).Start()
+}
+
+// Init fetches the first state of the party
+func (m *Model) Init() tea.Cmd { return m.sync }
+
+// sync fetches the state of the party
+func (m *Model) sync() tea.Msg {
+	ctx, cancel := context.WithTimeout(context.Background(), m.config.Timeout)
+	defer cancel()
+
+	snapshot, err := fetchSnapshot(ctx, m.client)
+	if err != nil {
+		return syncFailedMsg{err}
+	}
+	return snapshotMsg{snapshot}
+}
+
+// tick schedules the next fetch of the state
+func (m *Model) tick() tea.Cmd {
+	return tea.Tick(m.config.Refresh, func(time.Time) tea.Msg { return tickMsg{} })
+}
+
+// checkIn registers the arrival of persons of the party of the reservation, added to the ones present
+func (m *Model) checkIn(r models.Reservation, persons int) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), m.config.Timeout)
+		defer cancel()
+
+		_, err := m.client.Arrive(ctx, r.ID, persons)
+		return actionMsg{verb: "checked in", name: r.Name, err: err}
+	}
+}
+
+// arriving returns the persons of the party of the reservation checked in by the next arrival: the count entered by
+// the staff, or the ones not present yet
+func (m *Model) arriving(r models.Reservation) int {
+	if m.persons > 0 {
+		return m.persons
+	}
+	return r.Guests() - m.snapshot.Present(r.ID)
+}
+
+// checkOut registers the departure of the guest of the reservation
+func (m *Model) checkOut(r models.Reservation) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), m.config.Timeout)
+		defer cancel()
+
+		return actionMsg{verb: "checked out", name: r.Name, err: m.client.CheckOutReservation(ctx, r.ID)}
+	}
+}
+
+// Online reports whether the last fetch of the state succeeded
+func (m *Model) Online() bool { return m.snapshot != nil && m.syncErr == nil }
+
+// Update handles the keys of the staff and the responses of the api
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+
+	case snapshotMsg:
+		m.snapshot, m.syncErr, m.syncing = msg.snapshot, nil, false
+		m.clampCursor()
+		return m, m.tick()
+
+	case syncFailedMsg:
+		// keep showing the last state until the server is reachable again
+		m.syncErr, m.syncing = msg.err, false
+		return m, m.tick()
+
+	case tickMsg:
+		if m.syncing {
+			return m, nil
+		}
+		m.syncing = true
+		return m, m.sync
+
+	case actionMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("%s could not be %s: %s", msg.name, msg.verb, describe(msg.err))
+			return m, nil
+		}
+		m.status = fmt.Sprintf("%s %s", msg.name, msg.verb)
+		if m.syncing {
+			return m, nil
+		}
+		m.syncing = true
+		return m, m.sync
+
+	case tea.KeyMsg:
+		return m.key(msg)
+	}
+
+	return m, nil
+}
+
+func (m *Model) key(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyTab:
+		m.showTables = !m.showTables
+
+	case tea.KeyEsc:
+		m.query, m.cursor, m.persons = "", 0, 0
+
+	case tea.KeyBackspace:
+		if runes := []rune(m.query); len(runes) > 0 {
+			m.query, m.cursor, m.persons = string(runes[:len(runes)-1]), 0, 0
+		}
+
+	case tea.KeyRunes:
+		m.query, m.cursor, m.persons = m.query+string(msg.Runes), 0, 0
+
+	case tea.KeySpace:
+		m.query, m.cursor, m.persons = m.query+" ", 0, 0
+
+	case tea.KeyUp, tea.KeyCtrlP:
+		if m.cursor > 0 {
+			m.cursor, m.persons = m.cursor-1, 0
+		}
+
+	case tea.KeyDown, tea.KeyCtrlN:
+		m.cursor, m.persons = m.cursor+1, 0
+		m.clampCursor()
+
+	// the persons arriving, when the party comes in stages
+	case tea.KeyRight:
+		if r, ok := m.selected(); ok {
+			m.persons = m.arriving(r) + 1
+		}
+
+	case tea.KeyLeft:
+		if r, ok := m.selected(); ok && m.arriving(r) > 1 {
+			m.persons = m.arriving(r) - 1
+		}
+
+	case tea.KeyCtrlR:
+		if !m.syncing {
+			m.syncing = true
+			return m, m.sync
+		}
+
+	case tea.KeyEnter:
+		if r, ok := m.selected(); ok {
+			persons := m.arriving(r)
+			if persons < 1 {
+				m.status = r.Name + " is already checked in"
+				return m, nil
+			}
+			m.status, m.persons = fmt.Sprintf("checking in %d of %s...", persons, r.Name), 0
+			return m, m.checkIn(r, persons)
+		}
+
+	case tea.KeyCtrlX:
+		if r, ok := m.selected(); ok {
+			if !m.snapshot.Arrived(r.ID) {
+				m.status = r.Name + " is not checked in"
+				return m, nil
+			}
+			m.status = "checking out " + r.Name + "..."
+			return m, m.checkOut(r)
+		}
+	}
+
+	return m, nil
+}
+
+// matches returns the reservations matching the search
+func (m *Model) matches() []models.Reservation {
+	if m.snapshot == nil {
+		return nil
+	}
+	return m.snapshot.Search(m.query)
+}
+
+// selected returns the reservation under the cursor
+func (m *Model) selected() (models.Reservation, bool) {
+	matches := m.matches()
+	if m.cursor >= len(matches) {
+		return models.Reservation{}, false
+	}
+	return matches[m.cursor], true
+}
+
+func (m *Model) clampCursor() {
+	if n := len(m.matches()); m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// describe returns the reason of a failed call for the staff
+func describe(err error) string {
+	var apiErr *client.Error
+	switch {
+	case errors.Is(err, client.ErrCapacityExceeded):
+		return "the table is full"
+	case errors.Is(err, client.ErrNotAccepted):
+		return "the invitation is not accepted"
+	case errors.As(err, &apiErr):
+		return apiErr.Message
+	case errors.Is(err, context.DeadlineExceeded):
+		return "the server is not reachable"
+	}
+	return "the server is not reachable (" + err.Error() + ")"
+}
+
+// View renders the screen
+func (m *Model) View() string {
+	var b strings.Builder
+
+	// header with the connection state and the seats counter
+	switch {
+	case m.snapshot == nil && m.syncErr == nil:
+		b.WriteString("Party door · connecting...\n")
+	case m.snapshot == nil:
+		fmt.Fprintf(&b, "Party door · OFFLINE, retrying: %s\n", describe(m.syncErr))
+	default:
+		state := "online"
+		if m.syncErr != nil {
+			state = "OFFLINE, retrying"
+		}
+		fmt.Fprintf(&b, "Party door · %s · synced %s · seats empty: %d\n", state, m.snapshot.At.Format("15:04:05"), m.snapshot.SeatsEmpty())
+	}
+	b.WriteString("\n")
+
+	if m.snapshot != nil {
+		if m.showTables {
+			m.viewTables(&b)
+		} else {
+			m.viewGuestList(&b)
+		}
+	}
+
+	b.WriteString("\n")
+	if m.status != "" {
+		b.WriteString(m.status + "\n")
+	}
+	b.WriteString("type to search · ↑/↓ select · ←/→ persons arriving · enter check in · ctrl+x check out · tab tables · esc clear · ctrl+r refresh · ctrl+c quit\n")
+
+	return b.String()
+}
+
+func (m *Model) viewGuestList(b *strings.Builder) {
+	fmt.Fprintf(b, "Search: %s_\n\n", m.query)
+
+	matches := m.matches()
+	if len(matches) == 0 {
+		b.WriteString("  no accepted reservation matches\n")
+		return
+	}
+
+	// scroll the list to keep the cursor visible
+	rows := m.height - 9
+	if rows < 5 {
+		rows = 15
+	}
+	start := 0
+	if m.cursor >= rows {
+		start = m.cursor - rows + 1
+	}
+	end := start + rows
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	for i := start; i < end; i++ {
+		r := matches[i]
+
+		pointer, arrived := " ", "·"
+		if i == m.cursor {
+			pointer = ">"
+		}
+		if m.snapshot.Arrived(r.ID) {
+			arrived = "✓"
+		}
+		fmt.Fprintf(b, "%s %s %-32s table %-4d +%d", pointer, arrived, r.Name, r.TableID, r.AccompanyingGuests)
+		if present := m.snapshot.Present(r.ID); present > 0 && present < r.Guests() {
+			fmt.Fprintf(b, "  %d/%d arrived", present, r.Guests())
+		}
+		if i == m.cursor && m.persons > 0 {
+			fmt.Fprintf(b, "  arriving: %d", m.persons)
+		}
+		b.WriteString("\n")
+	}
+	if end < len(matches) {
+		fmt.Fprintf(b, "  ... %d more\n", len(matches)-end)
+	}
+}
+
+func (m *Model) viewTables(b *strings.Builder) {
+	const width = 20
+
+	b.WriteString("TABLE  PRESENT                        BOOKED\n")
+	for _, t := range m.snapshot.Fill() {
+		filled := 0
+		if t.Capacity > 0 {
+			filled = t.Present * width / t.Capacity
+		}
+		if filled > width {
+			filled = width
+		}
+		fmt.Fprintf(b, "%-6d [%s%s] %3d/%-3d  %3d\n", t.ID, strings.Repeat("#", filled), strings.Repeat("-", width-filled), t.Present, t.Capacity, t.Booked)
+	}
+}
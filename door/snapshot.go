@@ -0,0 +1,154 @@
+package door
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/client"
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/search"
+)
+
+// Snapshot is the state of the party the door screen shows, fetched from the api
+type Snapshot struct {
+	// Reservations are the accepted reservations, the ones allowed to check in, ordered by name
+	Reservations []models.Reservation
+	// Guests are the arrived guests by reservation id
+	Guests map[string]models.Guest
+	// Tables are the tables ordered by id
+	Tables []models.Table
+	// At is the moment the snapshot was fetched
+	At time.Time
+}
+
+// fetchSnapshot reads the accepted reservations, the guests and the tables from the api
+func fetchSnapshot(ctx context.Context, c *client.Client) (*Snapshot, error) {
+	s := &Snapshot{Guests: make(map[string]models.Guest), At: time.Now()}
+
+	reservations := c.Reservations(client.ReservationListOptions{Status: models.StatusAccepted})
+	for reservations.Next(ctx) {
+		s.Reservations = append(s.Reservations, reservations.Reservation())
+	}
+	if err := reservations.Err(); err != nil {
+		return nil, err
+	}
+
+	guests := c.Guests(client.ListOptions{})
+	for guests.Next(ctx) {
+		g := guests.Guest()
+		s.Guests[g.ReservationID] = g
+	}
+	if err := guests.Err(); err != nil {
+		return nil, err
+	}
+
+	tables := c.Tables(client.TableListOptions{})
+	for tables.Next(ctx) {
+		s.Tables = append(s.Tables, tables.Table())
+	}
+	if err := tables.Err(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Arrived reports whether the guest of the reservation with the given id is checked in
+func (s *Snapshot) Arrived(id string) bool {
+	_, ok := s.Guests[id]
+	return ok
+}
+
+// Present returns the persons of the party of the reservation with the given id checked in
+func (s *Snapshot) Present(id string) int {
+	if g, ok := s.Guests[id]; ok {
+		return g.TotalGuests()
+	}
+	return 0
+}
+
+// SeatsEmpty is the amount of seats not taken by the arrived guests
+func (s *Snapshot) SeatsEmpty() int {
+	var seats int
+	for _, t := range s.Tables {
+		seats += t.Capacity
+	}
+	for _, g := range s.Guests {
+		seats -= g.TotalGuests()
+	}
+	return seats
+}
+
+// TableFill is the occupancy of a table
+type TableFill struct {
+	ID       int
+	Capacity int
+	// Booked are the seats of the accepted reservations
+	Booked int
+	// Present are the seats taken by the arrived guests
+	Present int
+}
+
+// Fill returns the occupancy of the tables ordered by id
+func (s *Snapshot) Fill() []TableFill {
+	fill := make([]TableFill, len(s.Tables))
+	index := make(map[int]int, len(s.Tables))
+	for i, t := range s.Tables {
+		fill[i] = TableFill{ID: t.ID, Capacity: t.Capacity}
+		index[t.ID] = i
+	}
+
+	for _, r := range s.Reservations {
+		if i, ok := index[r.TableID]; ok {
+			fill[i].Booked += r.Guests()
+		}
+	}
+	for _, g := range s.Guests {
+		if i, ok := index[g.TableID]; ok {
+			fill[i].Present += g.TotalGuests()
+		}
+	}
+
+	return fill
+}
+
+// Search returns the reservations whose name contains the query, ignoring the case and the accents as the server search.
+// Names starting by the query come first, then names with a word starting by it.
+func (s *Snapshot) Search(query string) []models.Reservation {
+	query = search.Normalize(query)
+
+	type match struct {
+		rank        int
+		reservation models.Reservation
+	}
+
+	var matches []match
+	for _, r := range s.Reservations {
+		if rank, ok := rankName(search.Normalize(r.Name), query); ok {
+			matches = append(matches, match{rank, r})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].rank < matches[j].rank })
+
+	result := make([]models.Reservation, len(matches))
+	for i, m := range matches {
+		result[i] = m.reservation
+	}
+	return result
+}
+
+// rankName scores how well the name matches the query, lower is better
+func rankName(name, query string) (int, bool) {
+	switch {
+	case strings.HasPrefix(name, query):
+		return 0, true
+	case strings.Contains(" "+name, " "+query):
+		return 1, true
+	case strings.Contains(name, query):
+		return 2, true
+	}
+	return 0, false
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Gorm is the gorm logger writing the statements through the logger carried by the query context,
+// so that database queries are tagged with the id of the request that triggered them.
+type Gorm struct {
+	base          *zap.Logger
+	slowThreshold time.Duration
+}
+
+// NewGorm returns a gorm logger falling back to base for queries without a request scoped logger
+func NewGorm(base *zap.Logger, slowThreshold time.Duration) *Gorm {
+	return &Gorm{base: base, slowThreshold: slowThreshold}
+}
+
+func (l *Gorm) logger(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+			return logger
+		}
+	}
+	return l.base
+}
+
+// LogMode is a no-op, levels are driven by the zap logger
+func (l *Gorm) LogMode(gormlogger.LogLevel) gormlogger.Interface { return l }
+
+// Info logs at info level
+func (l *Gorm) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).Sugar().Infof(msg, args...)
+}
+
+// Warn logs at warn level
+func (l *Gorm) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).Sugar().Warnf(msg, args...)
+}
+
+// Error logs at error level
+func (l *Gorm) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).Sugar().Errorf(msg, args...)
+}
+
+// Trace logs the executed statement: failures as errors, slow queries as warnings and the rest at debug level.
+// The statement is logged with its values, so it is left out when the names are redacted.
+func (l *Gorm) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	logger := l.logger(ctx)
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		logger.Error("query failed", zap.Error(err), zap.Duration("elapsed", elapsed), zap.Int64("rows", rows), statement(sql))
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold:
+		sql, rows := fc()
+		logger.Warn("slow query", zap.Duration("elapsed", elapsed), zap.Int64("rows", rows), statement(sql))
+	case logger.Core().Enabled(zap.DebugLevel):
+		sql, rows := fc()
+		logger.Debug("query", zap.Duration("elapsed", elapsed), zap.Int64("rows", rows), statement(sql))
+	}
+}
+
+// statement returns the field of the executed statement, skipped when the names are redacted
+func statement(sql string) zap.Field {
+	if redacted() {
+		return zap.Skip()
+	}
+
+	return zap.String("sql", sql)
+}
@@ -0,0 +1,169 @@
+/*
+Package logging holds the structured logging of the application.
+
+Loggers are scoped to the requests: the api middleware stores a logger carrying the request id into the request
+context, and the code handling the request (controllers, model hooks and database queries) retrieves it with FromContext.
+*/
+package logging
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Log formats
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// LevelOff is the level disabling the logs
+const LevelOff = "off"
+
+// Config holds the logging settings. An empty level disables the logs. The guest names are redacted with a keyed
+// hash of the RedactKey, a random key is used when empty so the names are only correlated within the process.
+type Config struct {
+	Level       string
+	Format      string
+	RedactNames bool
+	RedactKey   string
+}
+
+// Validate checks the level and format are supported
+func (c Config) Validate() error {
+	if c.Level != "" && c.Level != LevelOff {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+			return fmt.Errorf(`invalid "%s" log level, expected debug, info, warn, error or off`, c.Level)
+		}
+	}
+
+	switch c.Format {
+	case FormatJSON, FormatConsole, "":
+	default:
+		return fmt.Errorf(`invalid "%s" log format, expected json or console`, c.Format)
+	}
+
+	return nil
+}
+
+// redactNames and redactKey are shared by every logger since names are logged from packages without access to the config
+var redactNames, redactKey atomic.Value
+
+// the names logged before the configuration is loaded are redacted with a random key
+func init() { RedactKey("") }
+
+// New returns a logger writing to the standard output with the configured level and format
+func New(config Config) (*zap.Logger, error) {
+	RedactNames(config.RedactNames)
+	RedactKey(config.RedactKey)
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Level == "" || config.Level == LevelOff {
+		return zap.NewNop(), nil
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+		return nil, err
+	}
+
+	zc := zap.NewProductionConfig()
+	zc.EncoderConfig.TimeKey = "time"
+	zc.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	if config.Format == FormatConsole {
+		zc = zap.NewDevelopmentConfig()
+	}
+
+	zc.Level = zap.NewAtomicLevelAt(level)
+	zc.Sampling = nil
+	zc.OutputPaths = []string{"stdout"}
+
+	return zc.Build()
+}
+
+// RedactNames sets whether the guest names are replaced by a hash in the logs
+func RedactNames(enabled bool) {
+	redactNames.Store(enabled)
+}
+
+// RedactKey sets the secret key of the hash replacing the guest names, a random one when empty
+func RedactKey(key string) {
+	if key != "" {
+		redactKey.Store([]byte(key))
+		return
+	}
+
+	random := make([]byte, sha256.Size)
+	if _, err := rand.Read(random); err != nil {
+		panic(fmt.Sprintf("error generating redaction key: %v", err))
+	}
+	redactKey.Store(random)
+}
+
+func redacted() bool {
+	redact, _ := redactNames.Load().(bool)
+	return redact
+}
+
+// Redact returns the text with the guest names replaced by their hash when names are redacted
+func Redact(text string, names ...string) string {
+	if !redacted() {
+		return text
+	}
+
+	for _, name := range names {
+		if name != "" {
+			text = strings.ReplaceAll(text, name, hash(name))
+		}
+	}
+
+	return text
+}
+
+// hash returns the HMAC of the name with the redaction key, so the names can not be recovered from a dictionary
+// without the key
+func hash(name string) string {
+	mac := hmac.New(sha256.New, redactKey.Load().([]byte))
+	mac.Write([]byte(name))
+	return "redacted:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// Name returns the field of a guest name, replaced by a keyed hash when names are redacted
+func Name(key, name string) zap.Field {
+	if redacted() {
+		return zap.String(key, hash(name))
+	}
+
+	return zap.String(key, name)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of the context carrying the logger
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by the context, or a no-op logger
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+			return logger
+		}
+	}
+
+	return zap.NewNop()
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header the request id is propagated from and returned in
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDKey = "request_id"
+
+// Middleware returns the gin middleware scoping a logger to each request.
+// The request id is taken from the X-Request-ID header or generated, and returned in the response.
+func Middleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(g *gin.Context) {
+		start := time.Now()
+
+		id := g.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = NewRequestID()
+		}
+
+		g.Header(RequestIDHeader, id)
+		g.Set(requestIDKey, id)
+
+		scoped := logger.With(zap.String(requestIDKey, id))
+
+		// correlate the logs with the trace of the request
+		if span := trace.SpanContextFromContext(g.Request.Context()); span.IsValid() {
+			scoped = scoped.With(zap.String("trace_id", span.TraceID().String()))
+		}
+		g.Request = g.Request.WithContext(NewContext(g.Request.Context(), scoped))
+
+		g.Next()
+
+		route := g.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		fields := []zap.Field{
+			zap.String("method", g.Request.Method),
+			zap.String("route", route),
+			zap.Int("status", g.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("client_ip", g.ClientIP()),
+		}
+
+		// path parameters hold guest names
+		for _, param := range g.Params {
+			fields = append(fields, Name("param_"+param.Key, param.Value))
+		}
+
+		switch status := g.Writer.Status(); {
+		case status >= 500:
+			scoped.Error("request handled", fields...)
+		case status >= 400:
+			scoped.Warn("request handled", fields...)
+		default:
+			scoped.Info("request handled", fields...)
+		}
+	}
+}
+
+// RequestID returns the id of the request handled by the context
+func RequestID(g *gin.Context) string {
+	return g.GetString(requestIDKey)
+}
+
+// NewRequestID generates a random request id
+func NewRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+// occupancy tables, writes on them refresh the gauges
+var occupancy = map[string]bool{
+	"tables":       true,
+	"reservations": true,
+	"guests":       true,
+}
+
+// Instrument registers the gorm callbacks timing the statements and refreshing the occupancy gauges after every write
+// committed by itself. The writes of an outer transaction, as the ones of the party service, are only committed with
+// it, so the owner of the transaction refreshes the gauges after its commit (see party.Service.WithCommitHook).
+// The gauges are initialized with the current state of the database.
+func (m *Metrics) Instrument(db *gorm.DB) error {
+	cb := db.Callback()
+
+	// commit is the point after which the writes of the statement are visible, it is nil for read operations.
+	// Statements of an outer transaction are not committed by themselves, so they are skipped by the refresh.
+	hooks := []struct {
+		operation             string
+		before, after, commit registerer
+	}{
+		{"create", cb.Create().Before("*"), cb.Create().After("*"), cb.Create().After("gorm:commit_or_rollback_transaction")},
+		{"query", cb.Query().Before("*"), cb.Query().After("*"), nil},
+		{"update", cb.Update().Before("*"), cb.Update().After("*"), cb.Update().After("gorm:commit_or_rollback_transaction")},
+		{"delete", cb.Delete().Before("*"), cb.Delete().After("*"), cb.Delete().After("gorm:commit_or_rollback_transaction")},
+		{"row", cb.Row().Before("*"), cb.Row().After("*"), nil},
+		{"raw", cb.Raw().Before("*"), cb.Raw().After("*"), cb.Raw().After("*")},
+	}
+
+	for _, h := range hooks {
+		if err := h.before.Register("metrics:before_"+h.operation, m.before); err != nil {
+			return fmt.Errorf("error registering %s callback: %v", h.operation, err)
+		}
+
+		if err := h.after.Register("metrics:after_"+h.operation, m.after(h.operation)); err != nil {
+			return fmt.Errorf("error registering %s callback: %v", h.operation, err)
+		}
+
+		if h.commit == nil {
+			continue
+		}
+
+		if err := h.commit.Register("metrics:refresh_"+h.operation, m.refresh); err != nil {
+			return fmt.Errorf("error registering %s callback: %v", h.operation, err)
+		}
+	}
+
+	return m.Refresh(db)
+}
+
+// inTransaction reports whether the statement runs in an outer transaction, still open once the statement completes
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+type registerer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+func (m *Metrics) before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (m *Metrics) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		m.queries.WithLabelValues(operation, db.Statement.Table).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *Metrics) refresh(db *gorm.DB) {
+	if db.Error != nil || db.Statement.RowsAffected == 0 || inTransaction(db) {
+		return
+	}
+
+	// raw statements are not bound to a table
+	if db.Statement.Table != "" && !occupancy[db.Statement.Table] {
+		return
+	}
+
+	if err := m.Refresh(db.Session(&gorm.Session{NewDB: true})); err != nil {
+		logging.FromContext(db.Statement.Context).Error("error refreshing occupancy metrics", zap.Error(err))
+	}
+}
+
+// Refresh recomputes the occupancy gauges from the seats of the database, see models.LoadSeats
+func (m *Metrics) Refresh(db *gorm.DB) error {
+	seats, err := models.LoadSeats(db)
+	if err != nil {
+		return err
+	}
+
+	m.capacity.Set(float64(seats.Capacity))
+	m.booked.Set(float64(seats.Booked))
+	m.present.Set(float64(seats.Present))
+
+	m.seatsEmpty.Reset()
+	for _, t := range seats.Tables {
+		m.seatsEmpty.WithLabelValues(strconv.Itoa(t.ID)).Set(float64(t.Capacity - t.Present))
+	}
+
+	return nil
+}
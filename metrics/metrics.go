@@ -0,0 +1,121 @@
+/*
+Package metrics holds the Prometheus instrumentation of the application.
+
+It exposes the HTTP request telemetry of the api router, the timings of the database queries
+and the occupancy of the party. Occupancy gauges are refreshed after every write on the database
+instead of being recomputed on each scrape.
+*/
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "party"
+
+// Metrics is the set of collectors of the application registered in its own registry
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	queries  *prometheus.HistogramVec
+
+	capacity   prometheus.Gauge
+	booked     prometheus.Gauge
+	present    prometheus.Gauge
+	seatsEmpty *prometheus.GaugeVec
+}
+
+// New returns the collectors registered into a new registry
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Amount of HTTP requests handled by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the HTTP requests by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Latency of the database statements by operation and table.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation", "table"}),
+		capacity: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "seats_capacity",
+			Help:      "Total amount of seats of the party tables.",
+		}),
+		booked: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "seats_booked",
+			Help:      "Amount of seats taken by accepted reservations.",
+		}),
+		present: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "guests_present",
+			Help:      "Amount of people checked in, including the accompanying guests.",
+		}),
+		seatsEmpty: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "seats_empty",
+			Help:      "Amount of empty seats by table.",
+		}, []string{"table"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.latency,
+		m.queries,
+		m.capacity,
+		m.booked,
+		m.present,
+		m.seatsEmpty,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Registry returns the registry holding the collectors
+func (m *Metrics) Registry() *prometheus.Registry { return m.registry }
+
+// Handler returns the HTTP handler exposing the metrics to be scraped
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware returns the gin middleware recording the request counters and latencies by route
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(g *gin.Context) {
+		start := time.Now()
+
+		g.Next()
+
+		// use the route pattern to keep the labels cardinality bounded
+		route := g.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		m.requests.WithLabelValues(route, g.Request.Method, strconv.Itoa(g.Writer.Status())).Inc()
+		m.latency.WithLabelValues(route, g.Request.Method).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of the seating constraints between two reservations
+const (
+	ConstraintSameTable      = "same_table"
+	ConstraintAdjacentZone   = "adjacent_zone"
+	ConstraintDifferentTable = "different_table"
+)
+
+// ValidConstraintKind reports whether the given seating constraint kind is known
+func ValidConstraintKind(kind string) bool {
+	switch kind {
+	case ConstraintSameTable, ConstraintAdjacentZone, ConstraintDifferentTable:
+		return true
+	}
+	return false
+}
+
+/*
+SeatingConstraint is the object mapping to a seating rule between two reservations into the database
+
+It is composed of the attibutes:
+	 - ID: identifier of the constraint
+	 - Kind: rule the tables of the reservations follow (same_table, adjacent_zone or different_table)
+	 - ReservationID, OtherID: identifiers of the reservations the rule binds, in no particular order
+	 - Note: optional reason of the rule
+
+It is related to the following models:
+	 - Reservation (one-to-many, twice), the constraint is deleted with any of its reservations
+*/
+type SeatingConstraint struct {
+	ID            int    `gorm:"primarykey" json:"id"`
+	Kind          string `gorm:"size:16" json:"kind"`
+	ReservationID string `gorm:"size:36;index" json:"reservation"`
+	OtherID       string `gorm:"size:36;index" json:"other"`
+	Note          string `gorm:"size:255" json:"note,omitempty"`
+
+	Reservation *Reservation `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Other       *Reservation `gorm:"foreignKey:OtherID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// Validate seating constraint fields.
+func (c *SeatingConstraint) Validate(db *gorm.DB) error {
+	if !ValidConstraintKind(c.Kind) {
+		return fmt.Errorf(`invalid "%s" constraint kind, expected same_table, adjacent_zone or different_table`, c.Kind)
+	}
+
+	if c.ReservationID == "" || c.OtherID == "" {
+		return fmt.Errorf("the constraint requires two reservations")
+	}
+
+	if c.ReservationID == c.OtherID {
+		return fmt.Errorf("the constraint binds the reservation %s to itself", c.ReservationID)
+	}
+
+	return nil
+}
+
+func (c *SeatingConstraint) BeforeCreate(db *gorm.DB) (err error) {
+	db, end := traceHook(db, "SeatingConstraint.BeforeCreate")
+	defer func() { end(err) }()
+
+	if err := c.Validate(db); err != nil {
+		return fmt.Errorf("error creating the seating constraint: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// EventID is the identifier of the row holding the settings of the event, the only one of its table
+const EventID = 1
+
+/*
+Event is the object mapping to the settings of the event changed while it runs into the database
+
+It is composed of the attibutes:
+	 - ID: identifier of the event, always EventID
+	 - WalkIns: whether the parties arriving without reservation are seated, the server default when nil
+	 - UpdatedAt: moment the settings last changed
+*/
+type Event struct {
+	ID        int       `gorm:"primarykey" json:"-"`
+	WalkIns   *bool     `json:"walk_ins,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/amaury95/GetGround-Party/logging"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -11,15 +13,24 @@ import (
 Guest is the object mapping to the guest record into the database
 
 It is composed of the attibutes:
+	 - ID: generated identifier of the guest
+	 - ReservationID: identifier of the reservation the guest arrived for
 	 - Name: name of the guest
 	 - AccompanyingGuests: number of persons that accompany the guest
+	 - Oversize: whether the guest arrived with more accompanying persons than booked
 
 It is related to the following models:
 	 - Table (one-to-many)
+	 - Reservation (one-to-one), the guest is deleted with its reservation
 */
 type Guest struct {
-	Name               string `gorm:"primarykey"`
+	ID                 string `gorm:"primarykey;size:36" json:"id"`
+	ReservationID      string `gorm:"size:36;uniqueIndex" json:"reservation"`
+	Name               string `gorm:"size:191;index"`
 	AccompanyingGuests int    `json:"accompanying_guests"`
+	Oversize           bool   `json:"oversize"`
+
+	Reservation *Reservation `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
 
 	TableID   int       `json:"table"`
 	CreatedAt time.Time `json:"time_arrived"`
@@ -41,11 +52,38 @@ func (g *Guest) Validate(db *gorm.DB) error {
 	return nil
 }
 
-func (g *Guest) BeforeCreate(db *gorm.DB) error {
+func (g *Guest) BeforeCreate(db *gorm.DB) (err error) {
+	db, end := traceHook(db, "Guest.BeforeCreate")
+	defer func() { end(err) }()
+
 	if err := g.Validate(db); err != nil {
 		return fmt.Errorf("error creating the guest reservation: %v", err)
 	}
 
+	if g.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return fmt.Errorf("error generating guest id: %v", err)
+		}
+		g.ID = id
+	}
+
+	return g.validateCapacity(db)
+}
+
+func (g *Guest) BeforeUpdate(db *gorm.DB) (err error) {
+	db, end := traceHook(db, "Guest.BeforeUpdate")
+	defer func() { end(err) }()
+
+	if err := g.Validate(db); err != nil {
+		return fmt.Errorf("error updating the guest reservation: %v", err)
+	}
+
+	return g.validateCapacity(db)
+}
+
+// validateCapacity checks the persons of the guest fit next to the other guests present at the table
+func (g *Guest) validateCapacity(db *gorm.DB) error {
 	// check table exists
 	var table Table
 	if err := db.Find(&table, g.TableID).Error; err != nil {
@@ -60,14 +98,23 @@ func (g *Guest) BeforeCreate(db *gorm.DB) error {
 		return fmt.Errorf("error loading table guests: %v", err)
 	}
 
-	// calculate sum of table reservations
+	// calculate sum of table reservations, the guest itself is counted once when updated
 	var sum int
 	for _, r := range guests {
-		sum += r.TotalGuests()
+		if r.ID != g.ID {
+			sum += r.TotalGuests()
+		}
 	}
 
 	if sum+g.TotalGuests() > table.Capacity {
-		return fmt.Errorf("table capacity is exceded by: %d", sum+g.TotalGuests()-table.Capacity)
+		logging.FromContext(db.Statement.Context).Info("guest exceeds table capacity",
+			logging.Name("guest", g.Name),
+			zap.Int("table", g.TableID),
+			zap.Int("party_size", g.TotalGuests()),
+			zap.Int("present", sum),
+			zap.Int("capacity", table.Capacity),
+		)
+		return fmt.Errorf("%w by: %d", ErrCapacityExceeded, sum+g.TotalGuests()-table.Capacity)
 	}
 
 	return nil
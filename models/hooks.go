@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/amaury95/GetGround-Party/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"gorm.io/gorm"
+)
+
+// traceHook starts the span of a model hook and returns the connection bound to it, so the queries of the hook
+// are nested in its span. The returned function ends the span with the result of the hook.
+func traceHook(db *gorm.DB, name string) (*gorm.DB, func(error)) {
+	ctx, span := tracing.Start(db.Statement.Context, name)
+
+	return db.Session(&gorm.Session{NewDB: true, Context: ctx}), func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
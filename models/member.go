@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Age groups of the party members
+const (
+	AgeAdult  = "adult"
+	AgeChild  = "child"
+	AgeInfant = "infant"
+)
+
+// ValidAgeGroup reports whether the given age group is known, the age group of a member is optional
+func ValidAgeGroup(group string) bool {
+	switch group {
+	case "", AgeAdult, AgeChild, AgeInfant:
+		return true
+	}
+	return false
+}
+
+/*
+Member is the object mapping to the named person of a reservation into the database
+
+It is composed of the attibutes:
+	 - ID: generated identifier of the member
+	 - ReservationID: identifier of the reservation the member belongs to
+	 - Position: order of the member in the party
+	 - Name: name of the member
+	 - Email: optional contact of the member
+	 - Dietary: dietary needs of the member, if any
+	 - AgeGroup: optional age group of the member (adult, child or infant)
+	 - ArrivedAt: moment the member checked in, nil while not arrived
+
+It is related to the following models:
+	 - Reservation (one-to-many), the members are deleted with their reservation
+*/
+type Member struct {
+	ID            string `gorm:"primarykey;size:36" json:"id"`
+	ReservationID string `gorm:"size:36;index" json:"reservation"`
+	Position      int    `json:"-"`
+
+	Name     string `gorm:"size:191" json:"name"`
+	Email    string `gorm:"size:255" json:"email,omitempty"`
+	Dietary  string `gorm:"size:255" json:"dietary,omitempty"`
+	AgeGroup string `gorm:"size:16" json:"age_group,omitempty"`
+
+	ArrivedAt *time.Time `json:"arrived_at,omitempty"`
+}
+
+// Arrived reports whether the member checked in
+func (m *Member) Arrived() bool { return m.ArrivedAt != nil }
+
+// Validate member fields.
+func (m *Member) Validate(db *gorm.DB) error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("member name is required")
+	}
+
+	if m.Email != "" && !strings.Contains(m.Email, "@") {
+		return fmt.Errorf(`invalid "%s" email`, m.Email)
+	}
+
+	if !ValidAgeGroup(m.AgeGroup) {
+		return fmt.Errorf(`invalid "%s" age group, expected adult, child or infant`, m.AgeGroup)
+	}
+
+	return nil
+}
+
+func (m *Member) BeforeCreate(db *gorm.DB) (err error) {
+	db, end := traceHook(db, "Member.BeforeCreate")
+	defer func() { end(err) }()
+
+	if err := m.Validate(db); err != nil {
+		return fmt.Errorf("error creating the party member: %v", err)
+	}
+
+	if m.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return fmt.Errorf("error generating member id: %v", err)
+		}
+		m.ID = id
+	}
+
+	return nil
+}
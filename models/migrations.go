@@ -0,0 +1,246 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Models are the models migrated into the database, in order of creation
+var Models = []interface{}{
+	new(Table),
+	new(Reservation),
+	new(Member),
+	new(Guest),
+	new(SeatingConstraint),
+	new(ReservationTrigram),
+	new(Movement),
+	new(SyncedCheckIn),
+	new(Event),
+}
+
+// Migrate creates or updates the database tables of the models and indexes the reservations missing in the search index.
+// The reservations and guests created when they were identified by name are given ids and linked by them, the guests
+// left without reservation are deleted before the foreign key is created. The reservations created before the
+// invitations are accepted, and the ones created before their arrival was recorded take it from their guest. The
+// guests present when the movements started to be recorded are logged as arrived.
+func Migrate(db *gorm.DB) error {
+	if err := migrateNameKeys(db); err != nil {
+		return err
+	}
+
+	if err := migrateGuestReservations(db); err != nil {
+		return err
+	}
+
+	if err := migrateReservationStatus(db); err != nil {
+		return err
+	}
+
+	if err := migrateReservationArrivals(db); err != nil {
+		return err
+	}
+
+	if err := migrateMovements(db); err != nil {
+		return err
+	}
+
+	if err := migrateTrigramCollation(db); err != nil {
+		return err
+	}
+
+	for _, model := range Models {
+		if err := db.AutoMigrate(model); err != nil {
+			return fmt.Errorf("error migrating %T: %v", model, err)
+		}
+	}
+
+	return indexReservations(db)
+}
+
+// migrateGuestReservations links the guests arrived before they referenced their reservation and deletes the guests
+// whose reservation was cancelled, so the foreign key of the guests can be created
+func migrateGuestReservations(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(new(Guest)) || !m.HasTable(new(Reservation)) || m.HasConstraint(new(Guest), "Reservation") {
+		return nil
+	}
+
+	if !m.HasColumn(new(Guest), "ReservationID") {
+		if err := m.AddColumn(new(Guest), "ReservationID"); err != nil {
+			return fmt.Errorf("error adding reservation to guests: %v", err)
+		}
+	}
+
+	// link the guests arrived before the reservations had ids
+	if err := db.Exec("UPDATE guests JOIN reservations ON reservations.name = guests.name SET guests.reservation_id = reservations.id WHERE guests.reservation_id IS NULL OR guests.reservation_id = ''").Error; err != nil {
+		return fmt.Errorf("error linking guests to their reservations: %v", err)
+	}
+
+	if err := db.Exec("DELETE FROM guests WHERE NOT EXISTS (SELECT 1 FROM reservations WHERE reservations.id = guests.reservation_id)").Error; err != nil {
+		return fmt.Errorf("error deleting guests without reservation: %v", err)
+	}
+
+	return nil
+}
+
+// migrateReservationStatus adds the status of the reservations created before the invitations had one, marking them
+// as accepted so they keep their seats and can check in
+func migrateReservationStatus(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(new(Reservation)) || m.HasColumn(new(Reservation), "Status") {
+		return nil
+	}
+
+	if err := m.AddColumn(new(Reservation), "Status"); err != nil {
+		return fmt.Errorf("error adding status to reservations: %v", err)
+	}
+
+	if err := db.Exec("UPDATE reservations SET status = ? WHERE status IS NULL OR status = ''", StatusAccepted).Error; err != nil {
+		return fmt.Errorf("error accepting the reservations created before the invitations: %v", err)
+	}
+
+	return nil
+}
+
+// migrateReservationArrivals adds the arrival of the reservations created before it was recorded, taken from the
+// guests present
+func migrateReservationArrivals(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(new(Reservation)) || !m.HasTable(new(Guest)) || m.HasColumn(new(Reservation), "ArrivedAt") {
+		return nil
+	}
+
+	if err := m.AddColumn(new(Reservation), "ArrivedAt"); err != nil {
+		return fmt.Errorf("error adding arrival to reservations: %v", err)
+	}
+
+	if err := db.Exec("UPDATE reservations JOIN guests ON guests.reservation_id = reservations.id SET reservations.arrived_at = guests.created_at WHERE reservations.arrived_at IS NULL").Error; err != nil {
+		return fmt.Errorf("error recording the arrival of the guests present: %v", err)
+	}
+
+	return nil
+}
+
+// migrateMovements creates the movements of the guests present before they were recorded
+func migrateMovements(db *gorm.DB) error {
+	m := db.Migrator()
+	if m.HasTable(new(Movement)) || !m.HasTable(new(Guest)) {
+		return nil
+	}
+
+	if err := m.CreateTable(new(Movement)); err != nil {
+		return fmt.Errorf("error creating movements: %v", err)
+	}
+
+	if err := db.Exec("INSERT INTO movements (reservation_id, persons, created_at) SELECT reservation_id, 1 + accompanying_guests, created_at FROM guests").Error; err != nil {
+		return fmt.Errorf("error recording the arrival of the guests present: %v", err)
+	}
+
+	return nil
+}
+
+// migrateTrigramCollation drops the search index created before its trigrams were compared as binary, to be built again
+func migrateTrigramCollation(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(new(ReservationTrigram)) {
+		return nil
+	}
+
+	var collations []string
+	if err := db.Raw("SELECT collation_name AS collation_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+		"reservation_trigrams", "trigram").Scan(&collations).Error; err != nil {
+		return fmt.Errorf("error loading search index collation: %v", err)
+	}
+
+	if len(collations) == 0 || collations[0] == "utf8mb4_bin" {
+		return nil
+	}
+
+	if err := m.DropTable(new(ReservationTrigram)); err != nil {
+		return fmt.Errorf("error dropping search index: %v", err)
+	}
+
+	return nil
+}
+
+// migrateNameKeys replaces the name primary key of the reservations and guests tables, created before they had ids,
+// by a generated id. The search index of the names is dropped to be built again by id.
+func migrateNameKeys(db *gorm.DB) error {
+	m := db.Migrator()
+
+	for _, model := range []interface{}{new(Reservation), new(Guest)} {
+		if !m.HasTable(model) || m.HasColumn(model, "ID") {
+			continue
+		}
+
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("error parsing %T: %v", model, err)
+		}
+		table := clause.Table{Name: stmt.Schema.Table}
+
+		for _, sql := range []string{
+			"ALTER TABLE ? ADD COLUMN id VARCHAR(36) NOT NULL DEFAULT ''",
+			"UPDATE ? SET id = UUID()",
+			"ALTER TABLE ? DROP PRIMARY KEY, ADD PRIMARY KEY (id)",
+		} {
+			if err := db.Exec(sql, table).Error; err != nil {
+				return fmt.Errorf("error adding ids to %s: %v", stmt.Schema.Table, err)
+			}
+		}
+	}
+
+	if m.HasColumn(new(ReservationTrigram), "reservation_name") {
+		if err := m.DropTable(new(ReservationTrigram)); err != nil {
+			return fmt.Errorf("error dropping search index: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// PendingMigrations returns the tables and columns of the models missing in the database
+func PendingMigrations(db *gorm.DB) ([]string, error) {
+	var (
+		tables  []string
+		schemas []*gorm.Statement
+	)
+
+	for _, model := range Models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("error parsing %T: %v", model, err)
+		}
+		schemas = append(schemas, stmt)
+		tables = append(tables, stmt.Schema.Table)
+	}
+
+	var columns []struct {
+		TableName  string
+		ColumnName string
+	}
+
+	// MySQL 8 names the columns of information_schema in upper case, they are aliased to scan them
+	if err := db.Raw("SELECT table_name AS table_name, column_name AS column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name IN ?", tables).
+		Scan(&columns).Error; err != nil {
+		return nil, fmt.Errorf("error loading database columns: %v", err)
+	}
+
+	existing := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		existing[c.TableName+"."+c.ColumnName] = true
+	}
+
+	var pending []string
+	for _, stmt := range schemas {
+		for _, column := range stmt.Schema.DBNames {
+			if name := stmt.Schema.Table + "." + column; !existing[name] {
+				pending = append(pending, name)
+			}
+		}
+	}
+
+	return pending, nil
+}
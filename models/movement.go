@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+/*
+Movement is the object mapping to the arrival or departure of persons of a reservation into the database
+
+It is composed of the attibutes:
+	 - ID: identifier of the movement
+	 - ReservationID: identifier of the reservation whose persons arrived or left
+	 - Persons: number of persons arriving, negative when they leave
+	 - CreatedAt: moment of the arrival or departure
+
+Movements are the log of the event, kept once the guests leave and their reservations are cancelled, so they hold
+no foreign key.
+*/
+type Movement struct {
+	ID            int       `gorm:"primarykey" json:"id"`
+	ReservationID string    `gorm:"size:36;index" json:"reservation"`
+	Persons       int       `json:"persons"`
+	CreatedAt     time.Time `gorm:"index" json:"time"`
+}
+
+/*
+SyncedCheckIn is the object mapping to an offline check-in applied by a sync into the database, so a batch sent again
+by a door device is not counted twice
+
+It is composed of the attibutes:
+	 - Device: name of the door device that recorded the check-in
+	 - ReservationID: identifier of the reservation whose persons arrived
+	 - CheckedInAt: moment of the arrival recorded by the device, stored to the millisecond
+	 - CreatedAt: moment the check-in was applied
+
+The synced check-ins are the log of the syncs, kept once the reservations are cancelled, so they hold no foreign key.
+*/
+type SyncedCheckIn struct {
+	Device        string    `gorm:"primarykey;size:64" json:"device"`
+	ReservationID string    `gorm:"primarykey;size:36" json:"reservation"`
+	CheckedInAt   time.Time `gorm:"primarykey" json:"checked_in_at"`
+	CreatedAt     time.Time `json:"time"`
+}
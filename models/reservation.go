@@ -1,31 +1,129 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/amaury95/GetGround-Party/logging"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+// Reservation statuses along the invitation workflow
+const (
+	StatusInvited  = "invited"
+	StatusAccepted = "accepted"
+	StatusDeclined = "declined"
+	StatusExpired  = "expired"
+)
+
+// Sources of the reservations
+const (
+	SourceGuestList = "guest_list"
+	SourceWalkIn    = "walk_in"
+)
+
+// ValidSource reports whether the given source is known
+func ValidSource(source string) bool {
+	switch source {
+	case SourceGuestList, SourceWalkIn:
+		return true
+	}
+	return false
+}
+
+// ValidStatus reports whether the given status belongs to the invitation workflow
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusInvited, StatusAccepted, StatusDeclined, StatusExpired:
+		return true
+	}
+	return false
+}
+
 /*
 Reservation is the object mapping to the guest list record into the database
 
 It is composed of the attibutes:
-	 - Name: name of the guest
+	 - ID: generated identifier of the reservation
+	 - Name: name of the guest, several reservations may share it
 	 - AccompanyingGuests: number of persons that accompany the guest
+	 - Requirements: features the table of the reservation must have
+	 - StartsAt, EndsAt: slot of the sitting booked at the table, the reservations without slot last the whole event
+	 - Status: stage of the invitation (invited, accepted, declined or expired)
+	 - Token: secret used by the guest to answer the invitation, never serialized as it is only returned on creation
+	 - RespondBy: deadline to answer the invitation, if any
+	 - RespondedAt: moment the guest answered the invitation
+	 - Email, Phone: contact of the reservation holder for the notifications
+	 - Source: how the reservation was made (guest_list, or walk_in for the parties seated at their arrival)
+	 - ArrivedAt: moment the party first checked in, kept once its persons leave
+	 - Members: named persons of the party, the holder included. Reservations without members are
+	   parties of anonymous persons
 
 It is related to the following models:
 	 - Table (one-to-many)
+	 - Member (many-to-one)
 */
 type Reservation struct {
-	Name               string `gorm:"primarykey" json:"name"`
+	ID                 string `gorm:"primarykey;size:36" json:"id"`
+	Name               string `gorm:"size:191;index" json:"name"`
 	AccompanyingGuests int    `json:"accompanying_guests"`
 
-	TableID int `json:"table"`
+	TableID      int      `json:"table"`
+	Requirements Features `gorm:"size:255" json:"requirements,omitempty"`
+
+	StartsAt *time.Time `gorm:"index" json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+
+	Status      string     `gorm:"size:16;index" json:"status"`
+	Token       string     `gorm:"size:64;uniqueIndex" json:"-"`
+	RespondBy   *time.Time `json:"respond_by,omitempty"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+
+	Email string `gorm:"size:255" json:"email,omitempty"`
+	Phone string `gorm:"size:32" json:"phone,omitempty"`
+
+	Source    string     `gorm:"size:16;default:guest_list" json:"source"`
+	ArrivedAt *time.Time `json:"arrived_at,omitempty"`
+
+	Members []Member `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"members,omitempty"`
+
+	// Arrivals are only loaded with a single reservation
+	Arrivals *Arrivals `gorm:"-" json:"arrivals,omitempty"`
+}
+
+// Arrivals holds the persons of a party present and expected at the party
+type Arrivals struct {
+	Arrived  int `json:"arrived"`
+	Expected int `json:"expected"`
 }
 
 // Guests amount of accompanying people including the guest
 func (r *Reservation) Guests() int { return 1 + r.AccompanyingGuests }
 
+// ArrivalsOf returns the arrivals of the party given its guest, nil while nobody arrived
+func (r *Reservation) ArrivalsOf(guest *Guest) *Arrivals {
+	a := &Arrivals{Expected: r.Guests()}
+	if guest != nil {
+		a.Arrived = guest.TotalGuests()
+	}
+	return a
+}
+
+// Slotted reports whether the reservation books a sitting instead of the whole event
+func (r *Reservation) Slotted() bool { return r.StartsAt != nil }
+
+// Accepted reports whether the guest accepted the invitation
+func (r *Reservation) Accepted() bool { return r.Status == StatusAccepted }
+
+// Expired reports whether the invitation deadline passed without an answer
+func (r *Reservation) Expired(now time.Time) bool {
+	return r.Status == StatusExpired || (r.Status == StatusInvited && r.RespondBy != nil && r.RespondBy.Before(now))
+}
+
 // Validate guest reservation fields.
 func (r *Reservation) Validate(db *gorm.DB) error {
 	if len(r.Name) < 6 {
@@ -36,37 +134,265 @@ func (r *Reservation) Validate(db *gorm.DB) error {
 		return fmt.Errorf(`invalid "%d" guests amount`, r.AccompanyingGuests)
 	}
 
+	if r.Email != "" && !strings.Contains(r.Email, "@") {
+		return fmt.Errorf(`invalid "%s" email`, r.Email)
+	}
+
+	if r.Status != "" && !ValidStatus(r.Status) {
+		return fmt.Errorf(`invalid "%s" reservation status`, r.Status)
+	}
+
+	if r.Source != "" && !ValidSource(r.Source) {
+		return fmt.Errorf(`invalid "%s" reservation source`, r.Source)
+	}
+
+	if err := r.Requirements.Validate(); err != nil {
+		return err
+	}
+
+	if (r.StartsAt == nil) != (r.EndsAt == nil) {
+		return fmt.Errorf("the slot requires both its start and its end")
+	}
+
+	if r.Slotted() && !r.EndsAt.After(*r.StartsAt) {
+		return fmt.Errorf("the slot should end after it starts")
+	}
+
+	if len(r.Members) > r.Guests() {
+		return fmt.Errorf("the party of %d can not hold its %d members", r.Guests(), len(r.Members))
+	}
+
+	for i := range r.Members {
+		if err := r.Members[i].Validate(db); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (r *Reservation) BeforeCreate(db *gorm.DB) error {
+func (r *Reservation) BeforeCreate(db *gorm.DB) (err error) {
+	db, end := traceHook(db, "Reservation.BeforeCreate")
+	defer func() { end(err) }()
+
 	if err := r.Validate(db); err != nil {
 		return fmt.Errorf("error creating the guest reservation: %v", err)
 	}
 
+	if r.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return fmt.Errorf("error generating reservation id: %v", err)
+		}
+		r.ID = id
+	}
+
+	// reservations start as invitations of the guest list
+	if r.Status == "" {
+		r.Status = StatusInvited
+	}
+
+	if r.Source == "" {
+		r.Source = SourceGuestList
+	}
+
+	if r.Token == "" {
+		token, err := newToken()
+		if err != nil {
+			return fmt.Errorf("error generating rsvp token: %v", err)
+		}
+		r.Token = token
+	}
+
 	// check table exists
 	var table Table
 	if err := db.Find(&table, r.TableID).Error; err != nil {
 		return fmt.Errorf(`error loading table with id "%d": %v`, r.TableID, err)
 	}
 
-	// validate table capacity
+	if err := r.validateRequirements(&table); err != nil {
+		return err
+	}
+
+	if r.Guests() > table.Capacity {
+		r.logRejection(db, table.Capacity, 0)
+		return fmt.Errorf("%w by: %d", ErrCapacityExceeded, r.Guests()-table.Capacity)
+	}
+
+	if !r.Accepted() {
+		return nil
+	}
+
+	return r.validateCapacity(db, &table)
+}
+
+func (r *Reservation) BeforeUpdate(db *gorm.DB) (err error) {
+	db, end := traceHook(db, "Reservation.BeforeUpdate")
+	defer func() { end(err) }()
+
+	if err := r.Validate(db); err != nil {
+		return fmt.Errorf("error updating the guest reservation: %v", err)
+	}
+
+	// only accepted reservations take seats
+	if !r.Accepted() && len(r.Requirements) == 0 {
+		return nil
+	}
+
+	// check table exists
+	var table Table
+	if err := db.Find(&table, r.TableID).Error; err != nil {
+		return fmt.Errorf(`error loading table with id "%d": %v`, r.TableID, err)
+	}
+
+	if err := r.validateRequirements(&table); err != nil {
+		return err
+	}
+
+	if !r.Accepted() {
+		return nil
+	}
+
+	return r.validateCapacity(db, &table)
+}
+
+// AfterCreate indexes the name of the reservation for the searches
+func (r *Reservation) AfterCreate(db *gorm.DB) (err error) {
+	db, end := traceHook(db, "Reservation.AfterCreate")
+	defer func() { end(err) }()
+
+	return indexTrigrams(db, r)
+}
+
+// AfterDelete removes the name of the reservation from the search index
+func (r *Reservation) AfterDelete(db *gorm.DB) (err error) {
+	db, end := traceHook(db, "Reservation.AfterDelete")
+	defer func() { end(err) }()
+
+	if err := db.Delete(new(ReservationTrigram), "reservation_id = ?", r.ID).Error; err != nil {
+		return fmt.Errorf("error removing reservation from the search index: %v", err)
+	}
+
+	return nil
+}
+
+// AfterFind flags the invitations whose deadline passed as expired
+func (r *Reservation) AfterFind(db *gorm.DB) error {
+	if r.Expired(time.Now()) {
+		r.Status = StatusExpired
+	}
+
+	return nil
+}
+
+// validateRequirements checks the table has the features required by the reservation
+func (r *Reservation) validateRequirements(table *Table) error {
+	if missing := table.Features.Missing(r.Requirements); len(missing) > 0 {
+		return fmt.Errorf("%w: %s missing at table %d", ErrUnsuitableTable, strings.Join(missing, ", "), table.ID)
+	}
+	return nil
+}
+
+// validateCapacity checks the reservation fits next to the accepted reservations of the table at the busiest moment of
+// its slot. The reservations without slot overlap any other and last the whole event from the moment they are booked,
+// so the slots already ended no longer take their seats.
+func (r *Reservation) validateCapacity(db *gorm.DB, table *Table) error {
 	var reservations []Reservation
 
-	// load table reservations
-	if err := db.Model(&table).Association("Reservations").Find(&reservations); err != nil {
+	from, until := time.Now(), (*time.Time)(nil)
+	if r.Slotted() {
+		from, until = *r.StartsAt, r.EndsAt
+	}
+
+	query := db.Model(table).Where("status = ? AND id <> ?", StatusAccepted, r.ID)
+	if r.Slotted() {
+		query = query.Where("starts_at IS NULL OR (starts_at < ? AND ends_at > ?)", until, from)
+	} else {
+		query = query.Where("ends_at IS NULL OR ends_at > ?", from)
+	}
+
+	// load table accepted reservations
+	if err := query.Association("Reservations").Find(&reservations); err != nil {
 		return fmt.Errorf("error loading table reservations: %v", err)
 	}
 
-	// calculate sum of table reservations
-	var sum int
+	booked := peakGuests(reservations, from, until)
+	if booked+r.Guests() > table.Capacity {
+		r.logRejection(db, table.Capacity, booked)
+		return fmt.Errorf("%w by: %d", ErrCapacityExceeded, booked+r.Guests()-table.Capacity)
+	}
+
+	return nil
+}
+
+// peakGuests returns the most guests of the reservations seated at once between from and until, the end of the event
+// when nil. The guests seated only grow when a slot starts, so the busiest moments are from and the starts within.
+func peakGuests(reservations []Reservation, from time.Time, until *time.Time) int {
+	moments := []time.Time{from}
 	for _, r := range reservations {
-		sum += r.Guests()
+		if r.Slotted() && r.StartsAt.After(from) && (until == nil || r.StartsAt.Before(*until)) {
+			moments = append(moments, *r.StartsAt)
+		}
 	}
 
-	if sum+r.Guests() > table.Capacity {
-		return fmt.Errorf("table capacity is exceded by: %d", sum+r.Guests()-table.Capacity)
+	var peak int
+	for _, at := range moments {
+		var seated int
+		for _, r := range reservations {
+			if !r.Slotted() || (!r.StartsAt.After(at) && r.EndsAt.After(at)) {
+				seated += r.Guests()
+			}
+		}
+		if seated > peak {
+			peak = seated
+		}
 	}
+	return peak
+}
 
-	return nil
+// logRejection logs the reservation rejected for exceeding the capacity of its table
+func (r *Reservation) logRejection(db *gorm.DB, capacity, booked int) {
+	logging.FromContext(db.Statement.Context).Info("reservation exceeds table capacity",
+		zap.String("reservation", r.ID),
+		logging.Name("guest", r.Name),
+		zap.Int("table", r.TableID),
+		zap.Int("party_size", r.Guests()),
+		zap.Int("booked", booked),
+		zap.Int("capacity", capacity),
+	)
+}
+
+// WithStatus scopes a reservations query to the ones in the given status,
+// taking into account the invitations whose deadline passed.
+func WithStatus(status string, now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch status {
+		case StatusInvited:
+			return db.Where("status = ? AND (respond_by IS NULL OR respond_by >= ?)", StatusInvited, now)
+		case StatusExpired:
+			return db.Where("status = ? OR (status = ? AND respond_by < ?)", StatusExpired, StatusInvited, now)
+		default:
+			return db.Where("status = ?", status)
+		}
+	}
+}
+
+// newID returns a random (version 4) UUID
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	buf[6] = buf[6]&0x0f | 0x40
+	buf[8] = buf[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:]), nil
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/amaury95/GetGround-Party/search"
+	"gorm.io/gorm"
+)
+
+/*
+ReservationTrigram is the object mapping to the search index of the guest list into the database
+
+It is composed of the attibutes:
+	 - Trigram: trigram of the normalized name of the guest, see the search package
+	 - ReservationID: identifier of the reservation whose name holds the trigram
+
+The primary key indexes the reservations by trigram, so searches read the index instead of the guest list. The trigrams
+are compared as binary, the case and accent insensitive collations would find distinct trigrams of a name duplicated.
+*/
+type ReservationTrigram struct {
+	Trigram       string `gorm:"primarykey;size:12;type:varchar(12) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin"`
+	ReservationID string `gorm:"primarykey;size:36;index"`
+}
+
+// Reindex replaces the trigrams of the reservation in the search index, to be called once its name changed
+func (r *Reservation) Reindex(db *gorm.DB) error {
+	if err := db.Delete(new(ReservationTrigram), "reservation_id = ?", r.ID).Error; err != nil {
+		return fmt.Errorf("error removing reservation from the search index: %v", err)
+	}
+
+	return indexTrigrams(db, r)
+}
+
+// indexTrigrams adds the trigrams of the reservation name to the search index
+func indexTrigrams(db *gorm.DB, r *Reservation) error {
+	trigrams := search.Trigrams(r.Name)
+	if len(trigrams) == 0 {
+		return nil
+	}
+
+	rows := make([]ReservationTrigram, len(trigrams))
+	for i, t := range trigrams {
+		rows[i] = ReservationTrigram{Trigram: t, ReservationID: r.ID}
+	}
+
+	if err := db.Create(&rows).Error; err != nil {
+		return fmt.Errorf("error indexing reservation name: %v", err)
+	}
+
+	return nil
+}
+
+// indexReservations indexes the reservations missing in the search index, created before it existed
+func indexReservations(db *gorm.DB) error {
+	var reservations []Reservation
+	if err := db.Select("id", "name").
+		Where("id NOT IN (?)", db.Model(new(ReservationTrigram)).Distinct("reservation_id")).
+		Find(&reservations).Error; err != nil {
+		return fmt.Errorf("error loading reservations to index: %v", err)
+	}
+
+	for i := range reservations {
+		if err := indexTrigrams(db, &reservations[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Seats is the use of the seats of the party, shared by the occupancy stream and the metrics
+type Seats struct {
+	// Capacity is the amount of seats of all the tables
+	Capacity int
+	// Booked is the amount of seats of the accepted reservations
+	Booked int
+	// Present is the amount of seats taken by the arrived guests
+	Present int
+	// Tables is the use of the seats of every table, ordered by identifier
+	Tables []TableSeats
+}
+
+// TableSeats is the use of the seats of a table
+type TableSeats struct {
+	ID       int
+	Capacity int
+	Present  int
+}
+
+// LoadSeats computes the use of the seats from the database, the totals of the party are the sums of its tables
+func LoadSeats(db *gorm.DB) (Seats, error) {
+	var s Seats
+
+	if err := db.Table("reservations").Select("COALESCE(SUM(1 + accompanying_guests), 0)").Where("status = ?", StatusAccepted).Scan(&s.Booked).Error; err != nil {
+		return s, fmt.Errorf("error calculating booked seats: %v", err)
+	}
+
+	if err := db.Table("tables").
+		Select("tables.id, tables.capacity, COALESCE(SUM(1 + guests.accompanying_guests), 0) AS present").
+		Joins("LEFT JOIN guests ON guests.table_id = tables.id").
+		Group("tables.id, tables.capacity").
+		Order("tables.id").
+		Scan(&s.Tables).Error; err != nil {
+		return s, fmt.Errorf("error calculating table occupancy: %v", err)
+	}
+
+	for _, t := range s.Tables {
+		s.Capacity += t.Capacity
+		s.Present += t.Present
+	}
+
+	return s, nil
+}
@@ -1,24 +1,111 @@
 package models
 
 import (
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
+// ErrCapacityExceeded is returned by the hooks of the models taking seats of a table that has not enough free seats
+var ErrCapacityExceeded = errors.New("table capacity is exceded")
+
+// ErrUnsuitableTable is returned by the hooks of the reservations seated at a table lacking the features they require
+var ErrUnsuitableTable = errors.New("table lacks the required features")
+
+// Features tagged on the tables, any lowercase tag made of letters, digits and dashes is accepted
+const (
+	FeatureWheelchairAccessible = "wheelchair-accessible"
+	FeatureNearStage            = "near-stage"
+	FeatureHighChair            = "high-chair"
+)
+
+var featurePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Features are the tags of a table, or the ones a reservation requires from its table. They are stored comma separated.
+type Features []string
+
+// GormDataType stores the features as a string
+func (Features) GormDataType() string { return "string" }
+
+// Value joins the features to be stored
+func (f Features) Value() (driver.Value, error) { return strings.Join(f, ","), nil }
+
+// Scan splits the stored features
+func (f *Features) Scan(src interface{}) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	default:
+		return fmt.Errorf("unsupported %T features", src)
+	}
+
+	*f = nil
+	if raw != "" {
+		*f = strings.Split(raw, ",")
+	}
+	return nil
+}
+
+// Validate checks the tags are well formed and not repeated
+func (f Features) Validate() error {
+	seen := make(map[string]bool, len(f))
+	for _, feature := range f {
+		if !featurePattern.MatchString(feature) {
+			return fmt.Errorf(`invalid "%s" feature, expected lowercase letters, digits and dashes`, feature)
+		}
+		if seen[feature] {
+			return fmt.Errorf(`repeated "%s" feature`, feature)
+		}
+		seen[feature] = true
+	}
+	return nil
+}
+
+// Missing returns the required features not in f
+func (f Features) Missing(required Features) Features {
+	var missing Features
+	for _, r := range required {
+		found := false
+		for _, feature := range f {
+			if feature == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
 /*
 Table is the object mapping to the table record into the database
 
 It is composed of the attibutes:
+	 - Label: human name of the table (e.g. "Table 12" or "VIP-A"), optional
+	 - Zone: zone or room the table is in, optional
 	 - Capacity: capacity of guests
+	 - Features: tags of the table (e.g. wheelchair-accessible, near-stage or high-chair)
 
 It is related to the following models:
 	 - Reservations (many-to-one)
 	 - Guests       (many-to-one)
 */
 type Table struct {
-	ID       int `gorm:"primarykey" json:"id"`
-	Capacity int `json:"capacity"`
+	ID       int      `gorm:"primarykey" json:"id"`
+	Label    string   `gorm:"size:64" json:"label,omitempty"`
+	Zone     string   `gorm:"size:64;index" json:"zone,omitempty"`
+	Capacity int      `json:"capacity"`
+	Features Features `gorm:"size:255" json:"features,omitempty"`
 
 	Reservations []Reservation `json:"reservations,omitempty"`
 	Guests       []Guest       `json:"guests,omitempty"`
@@ -30,7 +117,11 @@ func (t *Table) Validate(db *gorm.DB) error {
 		return fmt.Errorf(`capacity "%d" is not valid`, t.Capacity)
 	}
 
-	return nil
+	if len(t.Label) > 64 || len(t.Zone) > 64 {
+		return fmt.Errorf("label and zone should have at most 64 characters length")
+	}
+
+	return t.Features.Validate()
 }
 
 func (t *Table) BeforeCreate(tx *gorm.DB) error {
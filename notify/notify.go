@@ -0,0 +1,80 @@
+/*
+Package notify holds the messages sent to the reservation holders and the transports used to deliver them.
+
+Transports implement the Notifier interface so they can be plugged into the api handler, combined with Multi
+and wrapped into a Queue to retry the failed sends in the background.
+*/
+package notify
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNoAddress is returned by a transport when the recipient has no address it can deliver to
+var ErrNoAddress = errors.New("recipient has no address for this transport")
+
+// Recipient is the contact information of the reservation holder
+type Recipient struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// Message is a rendered notification ready to be delivered
+type Message struct {
+	Kind    Kind      `json:"kind"`
+	To      Recipient `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+}
+
+// Notifier delivers messages to their recipients
+type Notifier interface {
+	Notify(msg Message) error
+}
+
+// Multi delivers every message through all of its transports.
+// Transports without an address for the recipient are skipped.
+type Multi []Notifier
+
+// DeliveryError is returned by Multi when some of its transports failed, Failed holds the ones to retry so the
+// transports that delivered the message do not send it twice
+type DeliveryError struct {
+	Failed   Multi
+	failures []string
+}
+
+func (e *DeliveryError) Error() string {
+	return strings.Join(e.failures, "; ")
+}
+
+// Notify sends the message through every transport and returns the errors joined into a *DeliveryError
+func (m Multi) Notify(msg Message) error {
+	var (
+		failed    = new(DeliveryError)
+		delivered bool
+	)
+
+	for _, n := range m {
+		err := n.Notify(msg)
+		switch {
+		case err == nil:
+			delivered = true
+		case errors.Is(err, ErrNoAddress):
+		default:
+			failed.Failed = append(failed.Failed, n)
+			failed.failures = append(failed.failures, err.Error())
+		}
+	}
+
+	if len(failed.Failed) > 0 {
+		return failed
+	}
+
+	if !delivered {
+		return ErrNoAddress
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package notify
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/logging"
+	"go.uber.org/zap"
+)
+
+// ErrQueueClosed is returned when a message is sent to a closed queue
+var ErrQueueClosed = errors.New("notification queue closed")
+
+// job is a message to send, through the transports of the queue unless a previous attempt narrowed them
+type job struct {
+	msg      Message
+	notifier Notifier
+	attempt  int
+	at       time.Time
+}
+
+// Queue sends the messages in the background through a notifier and retries the failed sends with an exponential backoff.
+// Messages still failing after the maximum amount of attempts are kept as dead letters. The messages sent through a
+// Multi are only retried on the transports that failed.
+type Queue struct {
+	notifier Notifier
+	attempts int
+	backoff  time.Duration
+	logger   *zap.Logger
+
+	wake chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	pending []job
+	dead    []Message
+}
+
+// NewQueue returns a started queue delivering through the notifier and logging the undelivered messages, a nil logger
+// discards the logs
+func NewQueue(notifier Notifier, attempts int, backoff time.Duration, logger *zap.Logger) *Queue {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	q := &Queue{
+		notifier: notifier,
+		attempts: attempts,
+		backoff:  backoff,
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	q.wg.Add(1)
+	go q.run()
+
+	return q
+}
+
+// Notify enqueues the message to be sent in the background
+func (q *Queue) Notify(msg Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	q.pending = append(q.pending, job{msg: msg, notifier: q.notifier, at: time.Now()})
+
+	// wake up the worker without blocking the caller
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Pending returns the amount of messages waiting to be sent or retried
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// DeadLetters returns the messages that could not be delivered
+func (q *Queue) DeadLetters() []Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Message(nil), q.dead...)
+}
+
+// Close stops accepting messages and waits for the worker to make a last attempt on the pending ones
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.mu.Unlock()
+
+	close(q.done)
+	q.wg.Wait()
+
+	if dropped := q.Pending(); dropped > 0 {
+		q.logger.Warn("notification messages dropped on close", zap.Int("dropped", dropped))
+	}
+
+	return nil
+}
+
+func (q *Queue) run() {
+	defer q.wg.Done()
+
+	timer := time.NewTimer(q.backoff)
+	defer timer.Stop()
+
+	for {
+		next := q.flush(false)
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next)
+
+		select {
+		case <-q.wake:
+		case <-timer.C:
+		case <-q.done:
+			q.flush(true)
+			return
+		}
+	}
+}
+
+// flush sends the messages that are due and returns the time until the next retry.
+// When final is set every pending message is attempted once regardless of its schedule.
+func (q *Queue) flush(final bool) time.Duration {
+	now := time.Now()
+
+	q.mu.Lock()
+	var due, waiting []job
+	for _, j := range q.pending {
+		if final || !j.at.After(now) {
+			due = append(due, j)
+		} else {
+			waiting = append(waiting, j)
+		}
+	}
+	q.pending = waiting
+	q.mu.Unlock()
+
+	for _, j := range due {
+		err := j.notifier.Notify(j.msg)
+		if err == nil {
+			continue
+		}
+
+		// retry the transports that failed only
+		var partial *DeliveryError
+		if errors.As(err, &partial) {
+			j.notifier = partial.Failed
+		}
+
+		j.attempt++
+		if j.attempt >= q.attempts || errors.Is(err, ErrNoAddress) {
+			q.logger.Error("giving up notification message",
+				zap.String("kind", string(j.msg.Kind)),
+				logging.Name("guest", j.msg.To.Name),
+				zap.Int("attempts", j.attempt),
+				zap.Error(err),
+			)
+			q.mu.Lock()
+			q.dead = append(q.dead, j.msg)
+			q.mu.Unlock()
+			continue
+		}
+
+		j.at = time.Now().Add(q.backoff << uint(j.attempt-1))
+		q.mu.Lock()
+		q.pending = append(q.pending, j)
+		q.mu.Unlock()
+	}
+
+	// schedule the worker for the closest retry
+	next := q.backoff
+	q.mu.Lock()
+	for _, j := range q.pending {
+		if wait := time.Until(j.at); wait < next {
+			next = wait
+		}
+	}
+	q.mu.Unlock()
+
+	if next < 0 {
+		next = 0
+	}
+
+	return next
+}
@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/models"
+	"go.uber.org/zap"
+)
+
+// Reservation sends a message of the given kind to the holder of the reservation.
+// Sending failures are only logged, the notifier is responsible of retrying them.
+func Reservation(ctx context.Context, n Notifier, kind Kind, reservation *models.Reservation, previousTable int) {
+	if n == nil {
+		return
+	}
+
+	logger := logging.FromContext(ctx).With(zap.String("kind", string(kind)), logging.Name("guest", reservation.Name))
+
+	to := Recipient{
+		Name:  reservation.Name,
+		Email: reservation.Email,
+		Phone: reservation.Phone,
+	}
+
+	msg, err := Render(kind, to, Data{
+		Name:          reservation.Name,
+		Table:         reservation.TableID,
+		PreviousTable: previousTable,
+		PartySize:     reservation.Guests(),
+		Status:        reservation.Status,
+		RSVPToken:     reservation.Token,
+		RespondBy:     reservation.RespondBy,
+	})
+	if err != nil {
+		logger.Error("error rendering message", zap.Error(err))
+		return
+	}
+
+	if err := n.Notify(msg); err != nil {
+		logger.Error("error sending message", zap.Error(err))
+	}
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// Kind identifies the template of a message
+type Kind string
+
+// Kinds of messages sent to the reservation holders
+const (
+	Confirmation Kind = "confirmation"
+	Reminder     Kind = "reminder"
+	TableChange  Kind = "table_change"
+	Cancellation Kind = "cancellation"
+	CheckIn      Kind = "check_in"
+)
+
+// Data is the information available to the message templates
+type Data struct {
+	Name          string
+	Table         int
+	PreviousTable int
+	PartySize     int
+	Status        string
+	RSVPToken     string
+	RespondBy     *time.Time
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+func newTemplate(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+var templates = map[Kind]messageTemplate{
+	Confirmation: newTemplate(
+		`Your reservation for {{.PartySize}} at table {{.Table}}`,
+		`Hi {{.Name}},
+
+your reservation for {{.PartySize}} people at table {{.Table}} is {{.Status}}.
+{{- if eq .Status "invited"}}
+Please answer the invitation using the code {{.RSVPToken}}{{if .RespondBy}} before {{.RespondBy.Format "Jan 2, 2006 15:04"}}{{end}}.
+{{- end}}
+`),
+	Reminder: newTemplate(
+		`Reminder: your reservation at table {{.Table}}`,
+		`Hi {{.Name}},
+
+this is a reminder of your reservation for {{.PartySize}} people at table {{.Table}}.
+{{- if eq .Status "invited"}}
+You have not answered the invitation yet, please use the code {{.RSVPToken}}{{if .RespondBy}} before {{.RespondBy.Format "Jan 2, 2006 15:04"}}{{end}}.
+{{- end}}
+`),
+	TableChange: newTemplate(
+		`Your table changed to {{.Table}}`,
+		`Hi {{.Name}},
+
+your reservation for {{.PartySize}} people moved from table {{.PreviousTable}} to table {{.Table}}.
+`),
+	Cancellation: newTemplate(
+		`Your reservation was cancelled`,
+		`Hi {{.Name}},
+
+your reservation for {{.PartySize}} people at table {{.Table}} was cancelled.
+`),
+	CheckIn: newTemplate(
+		`Welcome to the party`,
+		`Hi {{.Name}},
+
+welcome! Your party of {{.PartySize}} is checked in at table {{.Table}}.
+`),
+}
+
+// Render returns the message of the given kind for the recipient
+func Render(kind Kind, to Recipient, data Data) (Message, error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return Message{}, fmt.Errorf(`unknown "%s" message kind`, kind)
+	}
+
+	var subject, body bytes.Buffer
+
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return Message{}, fmt.Errorf("error rendering subject: %v", err)
+	}
+
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return Message{}, fmt.Errorf("error rendering body: %v", err)
+	}
+
+	return Message{Kind: kind, To: to, Subject: subject.String(), Body: body.String()}, nil
+}
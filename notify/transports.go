@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	SMTP transport
+*/
+
+// SMTP delivers messages by email through an SMTP server
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Notify sends the message to the recipient email
+func (s *SMTP) Notify(msg Message) error {
+	if msg.To.Email == "" {
+		return ErrNoAddress
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", s.From)
+	fmt.Fprintf(&body, "To: %s\r\n", msg.To.Email)
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&body, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	if err := smtp.SendMail(addr, auth, s.From, []string{msg.To.Email}, []byte(body.String())); err != nil {
+		return fmt.Errorf("error sending email: %v", err)
+	}
+
+	return nil
+}
+
+/*
+	Log transport
+*/
+
+// Log writes the messages as JSON lines into a writer, it is meant for local development
+type Log struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLog returns a log transport writing into w
+func NewLog(w io.Writer) *Log {
+	return &Log{w: w}
+}
+
+// Notify writes the message into the log
+func (l *Log) Notify(msg Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := json.NewEncoder(l.w).Encode(msg); err != nil {
+		return fmt.Errorf("error writing message: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+/*
+Package occupancy tracks the seats of the party and broadcasts their changes to the subscribers.
+
+The Broker is fed by gorm callbacks running after every committed write on the tables, reservations and guests,
+so the changes made through any transport are published. Writes made by other instances are not seen.
+*/
+package occupancy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Occupancy is the state of the seats of the party
+type Occupancy struct {
+	// Capacity is the amount of seats of all the tables
+	Capacity int `json:"capacity"`
+	// Booked is the amount of seats of the accepted reservations
+	Booked int `json:"booked"`
+	// Present is the amount of seats taken by the arrived guests
+	Present    int     `json:"present"`
+	SeatsEmpty int     `json:"seats_empty"`
+	Tables     []Table `json:"tables"`
+}
+
+// Table is the state of the seats of a table
+type Table struct {
+	ID         int `json:"id"`
+	Capacity   int `json:"capacity"`
+	Present    int `json:"present"`
+	SeatsEmpty int `json:"seats_empty"`
+}
+
+// Load computes the occupancy from the seats of the database, see models.LoadSeats
+func Load(db *gorm.DB) (Occupancy, error) {
+	seats, err := models.LoadSeats(db)
+	if err != nil {
+		return Occupancy{}, err
+	}
+
+	o := Occupancy{
+		Capacity:   seats.Capacity,
+		Booked:     seats.Booked,
+		Present:    seats.Present,
+		SeatsEmpty: seats.Capacity - seats.Present,
+	}
+
+	for _, t := range seats.Tables {
+		o.Tables = append(o.Tables, Table{
+			ID:         t.ID,
+			Capacity:   t.Capacity,
+			Present:    t.Present,
+			SeatsEmpty: t.Capacity - t.Present,
+		})
+	}
+
+	return o, nil
+}
+
+// tracked are the tables whose writes change the occupancy
+var tracked = map[string]bool{
+	"tables":       true,
+	"reservations": true,
+	"guests":       true,
+}
+
+// Broker broadcasts the occupancy to its subscribers every time it changes
+type Broker struct {
+	mu      sync.Mutex
+	current Occupancy
+	subs    map[chan Occupancy]struct{}
+}
+
+// NewBroker returns a broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[chan Occupancy]struct{})}
+}
+
+// Instrument registers the gorm callbacks publishing the occupancy after every write committed by itself. The writes
+// of an outer transaction, as the ones of the party service, are only committed with it, so the owner of the
+// transaction refreshes the broker after its commit (see party.Service.WithCommitHook), and the writes rolled back are
+// never published. The broker is initialized with the current state of the database.
+func (b *Broker) Instrument(db *gorm.DB) error {
+	cb := db.Callback()
+
+	hooks := []struct {
+		operation string
+		commit    interface {
+			Register(name string, fn func(*gorm.DB)) error
+		}
+	}{
+		{"create", cb.Create().After("gorm:commit_or_rollback_transaction")},
+		{"update", cb.Update().After("gorm:commit_or_rollback_transaction")},
+		{"delete", cb.Delete().After("gorm:commit_or_rollback_transaction")},
+		{"raw", cb.Raw().After("*")},
+	}
+
+	for _, h := range hooks {
+		if err := h.commit.Register("occupancy:publish_"+h.operation, b.refresh); err != nil {
+			return fmt.Errorf("error registering %s callback: %v", h.operation, err)
+		}
+	}
+
+	return b.Refresh(db)
+}
+
+func (b *Broker) refresh(db *gorm.DB) {
+	if db.Error != nil || db.Statement.RowsAffected == 0 {
+		return
+	}
+
+	// the statements of an outer transaction are published by its owner once committed
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return
+	}
+
+	// raw statements are not bound to a table
+	if db.Statement.Table != "" && !tracked[db.Statement.Table] {
+		return
+	}
+
+	if err := b.Refresh(db.Session(&gorm.Session{NewDB: true})); err != nil {
+		logging.FromContext(db.Statement.Context).Error("error refreshing occupancy", zap.Error(err))
+	}
+}
+
+// Refresh loads the occupancy from the database and publishes it when it changed
+func (b *Broker) Refresh(db *gorm.DB) error {
+	o, err := Load(db)
+	if err != nil {
+		return err
+	}
+
+	b.Publish(o)
+	return nil
+}
+
+// Current returns the last occupancy published
+func (b *Broker) Current() Occupancy {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.current
+}
+
+// Publish sends the occupancy to the subscribers when it differs from the current one
+func (b *Broker) Publish(o Occupancy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if reflect.DeepEqual(b.current, o) {
+		return
+	}
+	b.current = o
+
+	for ch := range b.subs {
+		// slow subscribers only get the latest occupancy
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- o
+	}
+}
+
+// Subscribe returns a channel receiving the current occupancy, then every change of it until the context is done
+func (b *Broker) Subscribe(ctx context.Context) <-chan Occupancy {
+	ch := make(chan Occupancy, 1)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	ch <- b.current
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.subs, ch)
+		close(ch)
+		b.mu.Unlock()
+	}()
+
+	return ch
+}
@@ -0,0 +1,98 @@
+package party
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"gorm.io/gorm"
+)
+
+// Kinds of the errors returned by the service, matched with errors.Is. Errors of no kind are internal failures.
+var (
+	ErrInvalid               = errors.New("invalid request")
+	ErrNotFound              = errors.New("not found")
+	ErrAmbiguous             = errors.New("ambiguous name")
+	ErrNotAccepted           = errors.New("reservation not accepted")
+	ErrCapacityExceeded      = models.ErrCapacityExceeded
+	ErrUnsuitableTable       = models.ErrUnsuitableTable
+	ErrConstraintViolated    = errors.New("seating constraint violated")
+	ErrOversizeArrival       = errors.New("arrival exceeds reservation")
+	ErrArrivalConflict       = errors.New("arrival conflict")
+	ErrOutsideSlot           = errors.New("arrival outside the reservation slot")
+	ErrInvitationExpired     = errors.New("invitation expired")
+	ErrNotificationsDisabled = errors.New("notifications are disabled")
+	ErrTicketsDisabled       = errors.New("tickets are disabled")
+	ErrWalkInsDisabled       = errors.New("walk-ins are disabled")
+)
+
+// Error is the failure of an operation, its message is meant for the caller. Names holds the guest names the message
+// mentions, to be redacted from the logs.
+type Error struct {
+	Kind    error
+	Message string
+	Names   []string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Is matches the kind of the error
+func (e *Error) Is(target error) bool { return target == e.Kind }
+
+func errorf(kind error, format string, values ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, values...)}
+}
+
+// namedf returns an error of the kind whose message mentions the guest names
+func namedf(kind error, names []string, format string, values ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, values...), Names: names}
+}
+
+// Names returns the guest names mentioned in the message of the error
+func Names(err error) []string {
+	var named *Error
+	if errors.As(err, &named) {
+		return named.Names
+	}
+
+	var ambiguous *AmbiguousError
+	if errors.As(err, &ambiguous) {
+		return []string{ambiguous.Name}
+	}
+
+	return nil
+}
+
+// AmbiguousError is the failure of an operation referencing by name a reservation whose name is shared,
+// the candidates are the reservations holding the name
+type AmbiguousError struct {
+	Name       string
+	Candidates []models.Reservation
+}
+
+func (e *AmbiguousError) Error() string {
+	ids := make([]string, len(e.Candidates))
+	for i, c := range e.Candidates {
+		ids[i] = c.ID
+	}
+	return fmt.Sprintf(`name "%s" is shared by the reservations %s, reference one of them by id`, e.Name, strings.Join(ids, ", "))
+}
+
+// Is matches the ErrAmbiguous kind
+func (e *AmbiguousError) Is(target error) bool { return target == ErrAmbiguous }
+
+// queryError classifies the error of a database operation. Missing records and the capacity and table requirement
+// rules enforced by the model hooks are caller errors, anything else is an internal failure.
+func queryError(err error, message string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return errorf(ErrNotFound, "%s: %v", message, err)
+	case errors.Is(err, models.ErrCapacityExceeded):
+		return errorf(ErrCapacityExceeded, "%s: %v", message, err)
+	case errors.Is(err, models.ErrUnsuitableTable):
+		return errorf(ErrUnsuitableTable, "%s: %v", message, err)
+	default:
+		return fmt.Errorf("%s: %w", message, err)
+	}
+}
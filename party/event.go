@@ -0,0 +1,63 @@
+package party
+
+import (
+	"context"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventSettings holds the settings of the event changed while it runs, the ones never set follow the server defaults
+type EventSettings struct {
+	WalkIns bool
+}
+
+// EventSettings returns the settings of the event
+func (s *Service) EventSettings(ctx context.Context) (*EventSettings, error) {
+	var settings *EventSettings
+	err := s.transaction(ctx, func(tx *gorm.DB) (err error) {
+		settings, err = s.eventSettings(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// SetWalkIns sets whether the parties arriving without reservation are seated at the event, and returns its settings
+func (s *Service) SetWalkIns(ctx context.Context, enabled bool) (*EventSettings, error) {
+	var settings *EventSettings
+	err := s.transaction(ctx, func(tx *gorm.DB) (err error) {
+		event := models.Event{ID: models.EventID, WalkIns: &enabled}
+		if err := tx.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"walk_ins", "updated_at"})}).
+			Create(&event).Error; err != nil {
+			return queryError(err, "error updating event settings")
+		}
+
+		settings, err = s.eventSettings(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// eventSettings loads the settings of the event, taking the server defaults for the ones never set
+func (s *Service) eventSettings(tx *gorm.DB) (*EventSettings, error) {
+	var events []models.Event
+	if err := tx.Where("id = ?", models.EventID).Limit(1).Find(&events).Error; err != nil {
+		return nil, queryError(err, "error loading event settings")
+	}
+
+	settings := &EventSettings{WalkIns: s.walkIns}
+	if len(events) > 0 && events[0].WalkIns != nil {
+		settings.WalkIns = *events[0].WalkIns
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,439 @@
+package party
+
+import (
+	"context"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/notify"
+	"gorm.io/gorm"
+)
+
+// CheckIn registers the arrival of persons of an accepted reservation at its table. The first arrival is the guest
+// of the reservation, recorded as the arrival of the party, and the holder is notified of it; the following ones add
+// their persons to the guest. The members of the reservation not checked in yet are marked as arrived once the whole
+// party is present. The first arrival of a slotted reservation must happen within its slot, widened by the grace
+// periods.
+func (s *Service) CheckIn(ctx context.Context, ref Ref, persons int) (*models.Guest, error) {
+	if persons < 1 {
+		return nil, errorf(ErrInvalid, `invalid "%d" arriving persons`, persons)
+	}
+
+	record := models.Guest{Name: ref.Name}
+
+	// validate model, the guests checked in by id are validated once named after their reservation
+	if ref.ID == "" {
+		if err := record.Validate(s.db); err != nil {
+			return nil, errorf(ErrInvalid, "error validating guest: %v", err)
+		}
+	}
+
+	var (
+		reservation models.Reservation
+		first       bool
+	)
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := ref.find(tx, &reservation); err != nil {
+			return err
+		}
+
+		if !reservation.Accepted() {
+			return errorf(ErrNotAccepted, `reservation is "%s", only accepted reservations can check in`, reservation.Status)
+		}
+
+		var guests []models.Guest
+		if err := tx.Where("reservation_id = ?", reservation.ID).Find(&guests).Error; err != nil {
+			return queryError(err, "error retrieving guest")
+		}
+
+		if first = len(guests) == 0; first {
+			if err := s.checkSlot(&reservation, time.Now()); err != nil {
+				return err
+			}
+
+			record = models.Guest{
+				ReservationID:      reservation.ID,
+				Name:               reservation.Name,
+				AccompanyingGuests: persons - 1,
+				TableID:            reservation.TableID,
+			}
+		} else {
+			record = guests[0]
+			record.AccompanyingGuests += persons
+		}
+
+		if err := record.Validate(tx); err != nil {
+			return errorf(ErrInvalid, "error validating guest: %v", err)
+		}
+
+		if err := s.admit(tx, &reservation, &record); err != nil {
+			return err
+		}
+
+		// capacity rules are enforced by the model hooks
+		if first {
+			if err := tx.Create(&record).Error; err != nil {
+				return queryError(err, "error creating guest")
+			}
+			if err := markArrival(tx, &reservation, record.CreatedAt); err != nil {
+				return err
+			}
+		} else if err := tx.Save(&record).Error; err != nil {
+			return queryError(err, "error updating guest")
+		}
+
+		now := time.Now()
+		if err := recordMovement(tx, reservation.ID, persons, now); err != nil {
+			return err
+		}
+
+		if record.TotalGuests() < reservation.Guests() {
+			return nil
+		}
+		return markMembers(tx, reservation.ID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if first {
+		s.notify(ctx, notify.CheckIn, &reservation, 0)
+	}
+
+	record.Reservation = &reservation
+	return &record, nil
+}
+
+// checkSlot fails when the party of the slotted reservation arrives out of its slot widened by the grace periods
+func (s *Service) checkSlot(reservation *models.Reservation, now time.Time) error {
+	if !reservation.Slotted() {
+		return nil
+	}
+
+	from, until := reservation.StartsAt.Add(-s.earlyGrace), reservation.EndsAt.Add(s.lateGrace)
+	if now.Before(from) || now.After(until) {
+		return errorf(ErrOutsideSlot, "the slot of the reservation admits arrivals from %s to %s",
+			from.Format(time.RFC3339), until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// admit applies the arrival policy to the guest arriving with more accompanying persons than booked in the reservation.
+// The flagged guests only take the seats of the table left free by the other accepted reservations whose slot has not
+// ended, counted by the persons booked or, once their guest arrived with more persons, by the ones present.
+func (s *Service) admit(tx *gorm.DB, reservation *models.Reservation, guest *models.Guest) error {
+	if guest.AccompanyingGuests <= reservation.AccompanyingGuests {
+		return nil
+	}
+
+	if s.arrivals == ArrivalsReject {
+		return errorf(ErrOversizeArrival, "%d persons arrived, %d were booked", guest.TotalGuests(), reservation.Guests())
+	}
+
+	// the reservations of the table overlapping the slot of the reservation
+	overlap, args := "", []interface{}{models.StatusAccepted, reservation.ID, time.Now()}
+	if reservation.Slotted() {
+		overlap, args = " AND (reservations.starts_at IS NULL OR reservations.starts_at < ?)", append(args, reservation.EndsAt)
+	}
+
+	var free []int
+	if err := tx.Table("tables").
+		Select("tables.capacity - COALESCE(SUM(GREATEST(1 + reservations.accompanying_guests, COALESCE(1 + guests.accompanying_guests, 0))), 0) AS free").
+		Joins("LEFT JOIN reservations ON reservations.table_id = tables.id AND reservations.status = ? AND reservations.id <> ? AND (reservations.ends_at IS NULL OR reservations.ends_at > ?)"+overlap, args...).
+		Joins("LEFT JOIN guests ON guests.reservation_id = reservations.id").
+		Where("tables.id = ?", guest.TableID).
+		Group("tables.id, tables.capacity").
+		Scan(&free).Error; err != nil {
+		return queryError(err, "error calculating free seats")
+	}
+
+	if len(free) > 0 && guest.TotalGuests() > free[0] {
+		return errorf(ErrCapacityExceeded, "%d persons arrived, %d seats of the table are not booked by the other reservations", guest.TotalGuests(), free[0])
+	}
+
+	guest.Oversize = true
+	return nil
+}
+
+// CheckOut registers the departure of persons of the reservation, all of them when persons is 0, freeing their seats.
+// The guest of the reservation, and the arrival of its members, are removed with the last person leaving. The persons
+// leaving by count are the ones not named, so the members present check out by name before the party does in part.
+func (s *Service) CheckOut(ctx context.Context, ref Ref, persons int) error {
+	if persons < 0 {
+		return errorf(ErrInvalid, `invalid "%d" leaving persons`, persons)
+	}
+
+	return s.transaction(ctx, func(tx *gorm.DB) error {
+		var reservation models.Reservation
+		if err := ref.find(tx, &reservation); err != nil {
+			return err
+		}
+
+		var guests []models.Guest
+		if err := tx.Where("reservation_id = ?", reservation.ID).Find(&guests).Error; err != nil {
+			return queryError(err, "error retrieving guest")
+		}
+
+		present := 0
+		if len(guests) > 0 {
+			present = guests[0].TotalGuests()
+		}
+		if persons == 0 {
+			persons = present
+		}
+
+		if persons > present {
+			return errorf(ErrArrivalConflict, "%d persons can not leave, %d are present", persons, present)
+		}
+
+		if persons == present {
+			if len(guests) > 0 {
+				if err := tx.Delete(&guests[0]).Error; err != nil {
+					return queryError(err, "error deleting guest")
+				}
+			}
+			if err := recordMovement(tx, reservation.ID, -persons, time.Now()); err != nil {
+				return err
+			}
+			return unmarkMembers(tx, reservation.ID)
+		}
+
+		guest := guests[0]
+
+		var arrived int64
+		if err := tx.Model(new(models.Member)).Where("reservation_id = ? AND arrived_at IS NOT NULL", reservation.ID).Count(&arrived).Error; err != nil {
+			return queryError(err, "error counting members arrived")
+		}
+
+		if remaining := guest.TotalGuests() - persons; int64(remaining) < arrived {
+			return errorf(ErrArrivalConflict, "%d members are present, %d persons would remain, the members leaving check out by name", arrived, remaining)
+		}
+
+		guest.AccompanyingGuests -= persons
+		if err := tx.Save(&guest).Error; err != nil {
+			return queryError(err, "error updating guest")
+		}
+		return recordMovement(tx, reservation.ID, -persons, time.Now())
+	})
+}
+
+// markArrival records the first arrival of the party of the reservation, kept once its guest leaves so the
+// reservation is not taken for a no-show. The reservation hooks are skipped as the arrival takes no seat.
+func markArrival(tx *gorm.DB, reservation *models.Reservation, at time.Time) error {
+	if reservation.ArrivedAt != nil {
+		return nil
+	}
+
+	reservation.ArrivedAt = &at
+	if err := tx.Model(reservation).UpdateColumn("arrived_at", reservation.ArrivedAt).Error; err != nil {
+		return queryError(err, "error recording reservation arrival")
+	}
+	return nil
+}
+
+// recordMovement logs the persons of the reservation arriving, or leaving when negative, for the stats of the event
+func recordMovement(tx *gorm.DB, reservation string, persons int, at time.Time) error {
+	if persons == 0 {
+		return nil
+	}
+
+	if err := tx.Create(&models.Movement{ReservationID: reservation, Persons: persons, CreatedAt: at}).Error; err != nil {
+		return queryError(err, "error recording guests movement")
+	}
+	return nil
+}
+
+// markMembers sets the arrival of the members of the reservation whose whole party checked in, keeping the arrival of
+// the members checked in before
+func markMembers(tx *gorm.DB, reservation string, arrivedAt time.Time) error {
+	if err := tx.Model(new(models.Member)).Where("reservation_id = ? AND arrived_at IS NULL", reservation).Update("arrived_at", arrivedAt).Error; err != nil {
+		return queryError(err, "error updating members arrival")
+	}
+	return nil
+}
+
+// unmarkMembers clears the arrival of the members of the reservation whose whole party checked out
+func unmarkMembers(tx *gorm.DB, reservation string) error {
+	if err := tx.Model(new(models.Member)).Where("reservation_id = ?", reservation).Update("arrived_at", nil).Error; err != nil {
+		return queryError(err, "error updating members arrival")
+	}
+	return nil
+}
+
+// CheckInMember registers the arrival of a named member of an accepted reservation, adding a person to the
+// guest of the reservation under the arrival policy. The persons of the party checked in without naming them are the
+// members not identified yet, so the member arriving is counted among them while any is left. The first member of a
+// slotted reservation must arrive within its slot, widened by the grace periods.
+func (s *Service) CheckInMember(ctx context.Context, id string) (*models.Member, error) {
+	var member models.Member
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.First(&member, "id = ?", id).Error; err != nil {
+			return queryError(err, "error retrieving member")
+		}
+
+		if member.Arrived() {
+			return namedf(ErrArrivalConflict, []string{member.Name}, "member %s already checked in", member.Name)
+		}
+
+		var reservation models.Reservation
+		if err := ByID(member.ReservationID).find(tx, &reservation); err != nil {
+			return err
+		}
+
+		if !reservation.Accepted() {
+			return errorf(ErrNotAccepted, `reservation is "%s", only accepted reservations can check in`, reservation.Status)
+		}
+
+		var guests []models.Guest
+		if err := tx.Where("reservation_id = ?", reservation.ID).Find(&guests).Error; err != nil {
+			return queryError(err, "error retrieving guest")
+		}
+
+		// the first member arriving is the guest of the reservation, the following ones accompany it.
+		// Capacity rules are enforced by the model hooks.
+		if len(guests) == 0 {
+			if err := s.checkSlot(&reservation, time.Now()); err != nil {
+				return err
+			}
+
+			guest := models.Guest{ReservationID: reservation.ID, Name: reservation.Name, TableID: reservation.TableID}
+			if err := tx.Create(&guest).Error; err != nil {
+				return queryError(err, "error creating guest")
+			}
+			if err := markArrival(tx, &reservation, guest.CreatedAt); err != nil {
+				return err
+			}
+			if err := recordMovement(tx, reservation.ID, 1, guest.CreatedAt); err != nil {
+				return err
+			}
+		} else {
+			var arrived int64
+			if err := tx.Model(new(models.Member)).Where("reservation_id = ? AND arrived_at IS NOT NULL", reservation.ID).Count(&arrived).Error; err != nil {
+				return queryError(err, "error counting members arrived")
+			}
+
+			if guest := guests[0]; int64(guest.TotalGuests()) <= arrived {
+				guest.AccompanyingGuests++
+				if err := s.admit(tx, &reservation, &guest); err != nil {
+					return err
+				}
+				if err := tx.Save(&guest).Error; err != nil {
+					return queryError(err, "error updating guest")
+				}
+				if err := recordMovement(tx, reservation.ID, 1, time.Now()); err != nil {
+					return err
+				}
+			}
+		}
+
+		now := time.Now()
+		member.ArrivedAt = &now
+		if err := tx.Model(&member).Update("arrived_at", member.ArrivedAt).Error; err != nil {
+			return queryError(err, "error updating member arrival")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// CheckOutMember registers the departure of a named member, removing a person from the guest of the reservation
+func (s *Service) CheckOutMember(ctx context.Context, id string) (*models.Member, error) {
+	var member models.Member
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.First(&member, "id = ?", id).Error; err != nil {
+			return queryError(err, "error retrieving member")
+		}
+
+		if !member.Arrived() {
+			return namedf(ErrArrivalConflict, []string{member.Name}, "member %s has not checked in", member.Name)
+		}
+
+		var guests []models.Guest
+		if err := tx.Where("reservation_id = ?", member.ReservationID).Find(&guests).Error; err != nil {
+			return queryError(err, "error retrieving guest")
+		}
+
+		// the guest of the reservation leaves with its last member
+		switch {
+		case len(guests) == 0:
+		case guests[0].AccompanyingGuests == 0:
+			if err := tx.Delete(&guests[0]).Error; err != nil {
+				return queryError(err, "error deleting guest")
+			}
+		default:
+			guests[0].AccompanyingGuests--
+			if err := tx.Save(&guests[0]).Error; err != nil {
+				return queryError(err, "error updating guest")
+			}
+		}
+
+		if len(guests) > 0 {
+			if err := recordMovement(tx, member.ReservationID, -1, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		member.ArrivedAt = nil
+		if err := tx.Model(&member).Update("arrived_at", nil).Error; err != nil {
+			return queryError(err, "error updating member arrival")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// NoShows returns the page of the accepted reservations whose party never arrived ordered by name and the key of the
+// next page, leaving out the slots not started yet. The parties arrived and gone are not no-shows.
+func (s *Service) NoShows(ctx context.Context, page Page) ([]models.Reservation, string, error) {
+	if err := page.validate(); err != nil {
+		return nil, "", err
+	}
+
+	var elements []models.Reservation
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		query := tx.Scopes(page.scopeByID("name")).
+			Where("status = ? AND arrived_at IS NULL", models.StatusAccepted).
+			Where("starts_at IS NULL OR starts_at <= ?", time.Now())
+		if err := query.Find(&elements).Error; err != nil {
+			return queryError(err, "error retrieving the no-shows")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	n, next := page.next(len(elements), func(last int) string { return keyByID(elements[last].Name, elements[last].ID) })
+	return elements[:n], next, nil
+}
+
+// Guests returns the page of the arrived guests ordered by name and the key of the next page
+func (s *Service) Guests(ctx context.Context, page Page) ([]models.Guest, string, error) {
+	if err := page.validate(); err != nil {
+		return nil, "", err
+	}
+
+	var elements []models.Guest
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Scopes(page.scopeByID("name")).Find(&elements).Error; err != nil {
+			return queryError(err, "error retrieving the guests")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	n, next := page.next(len(elements), func(last int) string { return keyByID(elements[last].Name, elements[last].ID) })
+	return elements[:n], next, nil
+}
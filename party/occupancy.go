@@ -0,0 +1,20 @@
+package party
+
+import (
+	"context"
+
+	"github.com/amaury95/GetGround-Party/occupancy"
+	"gorm.io/gorm"
+)
+
+// Occupancy returns the seats booked and taken at the party and at each table
+func (s *Service) Occupancy(ctx context.Context) (occupancy.Occupancy, error) {
+	var o occupancy.Occupancy
+	err := s.transaction(ctx, func(tx *gorm.DB) (err error) {
+		if o, err = occupancy.Load(tx); err != nil {
+			return queryError(err, "error loading occupancy")
+		}
+		return nil
+	})
+	return o, err
+}
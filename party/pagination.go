@@ -0,0 +1,74 @@
+package party
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaxPageSize is the largest page the lists return
+const MaxPageSize = 500
+
+// Page is the keyset pagination of a list: up to Limit elements whose key is greater than After.
+// Lists are returned whole when Limit is zero.
+type Page struct {
+	Limit int
+	After string
+}
+
+func (p Page) validate() error {
+	if p.Limit < 0 || p.Limit > MaxPageSize {
+		return errorf(ErrInvalid, `invalid "%d" limit, expected a value between 1 and %d`, p.Limit, MaxPageSize)
+	}
+	return nil
+}
+
+// keySeparator joins the values of the keys of the lists ordered by a shared value and then by id
+const keySeparator = "/"
+
+// scope orders the query by the key column and fetches one extra element to know whether a next page exists
+func (p Page) scope(column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if p.Limit == 0 {
+			return db
+		}
+
+		if p.After != "" {
+			db = db.Where(column+" > ?", p.After)
+		}
+
+		return db.Order(column).Limit(p.Limit + 1)
+	}
+}
+
+// scopeByID orders the query by a column whose values are shared, then by id. The keys of the pages are the
+// value and the id joined by keySeparator, a key without id starts the page at the first element of the value.
+func (p Page) scopeByID(column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if p.Limit == 0 {
+			return db
+		}
+
+		if p.After != "" {
+			value, id := p.After, ""
+			if i := strings.LastIndex(p.After, keySeparator); i >= 0 {
+				value, id = p.After[:i], p.After[i+1:]
+			}
+			db = db.Where("("+column+", id) > (?, ?)", value, id)
+		}
+
+		return db.Order(column).Order("id").Limit(p.Limit + 1)
+	}
+}
+
+// keyByID returns the key of an element of a list scoped by scopeByID
+func keyByID(value, id string) string { return value + keySeparator + id }
+
+// next returns the amount of fetched elements belonging to the page and the key of the next page,
+// empty on the last page
+func (p Page) next(fetched int, key func(last int) string) (int, string) {
+	if p.Limit == 0 || fetched <= p.Limit {
+		return fetched, ""
+	}
+	return p.Limit, key(p.Limit - 1)
+}
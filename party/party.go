@@ -0,0 +1,176 @@
+/*
+Package party holds the business operations of the party: seating the guest list, checking guests in and out,
+issuing tickets and reporting the occupancy.
+
+Every operation runs in its own transaction and fails with an *Error matching one of the Err* kinds, so the
+transports (the REST api, the gRPC services, the command line tools or background jobs) only translate their
+inputs and errors. The capacity rules themselves are enforced by the hooks of the models, the seating constraints
+between reservations by the service.
+*/
+package party
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/notify"
+	"github.com/amaury95/GetGround-Party/tickets"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ArrivalPolicy decides the check-in of the guests arriving with more accompanying persons than booked
+type ArrivalPolicy string
+
+// Arrival policies, the oversize arrivals are flagged by default
+const (
+	// ArrivalsReject fails the check-in of the oversize arrivals
+	ArrivalsReject ArrivalPolicy = "reject"
+	// ArrivalsFlag checks in the oversize arrivals when the table has room for them, flagging the guest
+	ArrivalsFlag ArrivalPolicy = "flag"
+)
+
+// ParseArrivalPolicy returns the arrival policy of the given name
+func ParseArrivalPolicy(name string) (ArrivalPolicy, error) {
+	switch policy := ArrivalPolicy(name); policy {
+	case ArrivalsReject, ArrivalsFlag:
+		return policy, nil
+	}
+	return "", fmt.Errorf(`invalid "%s" arrival policy, expected reject or flag`, name)
+}
+
+// Service runs the business operations against the database
+type Service struct {
+	db       *gorm.DB
+	notifier notify.Notifier
+	issuer   *tickets.Issuer
+	arrivals ArrivalPolicy
+	walkIns  bool
+
+	// grace periods widening the slots the parties arrive in
+	earlyGrace, lateGrace time.Duration
+
+	// validity of the tickets issued and drift allowed to the clocks of the door devices
+	ticketTTL, clockSkew time.Duration
+
+	adjacentZones AdjacentZones
+
+	// hooks run after the transactions writing to the database commit
+	commitHooks []func(db *gorm.DB) error
+}
+
+// New returns the service working on the given connection
+func New(db *gorm.DB) *Service {
+	if db != nil {
+		trackWrites(db)
+	}
+	return &Service{db: db, ticketTTL: DefaultTicketTTL, clockSkew: DefaultClockSkew}
+}
+
+// WithNotifier sets the notifier used to message the reservation holders and return the service
+func (s *Service) WithNotifier(notifier notify.Notifier) *Service {
+	s.notifier = notifier
+	return s
+}
+
+// WithTicketIssuer sets the issuer used to sign the guest tickets and return the service
+func (s *Service) WithTicketIssuer(issuer *tickets.Issuer) *Service {
+	s.issuer = issuer
+	return s
+}
+
+// WithArrivalPolicy sets the policy applied to the oversize arrivals and return the service
+func (s *Service) WithArrivalPolicy(policy ArrivalPolicy) *Service {
+	s.arrivals = policy
+	return s
+}
+
+// WithWalkIns sets whether the parties arriving without reservation are seated, until the event settings change it, and
+// return the service
+func (s *Service) WithWalkIns(enabled bool) *Service {
+	s.walkIns = enabled
+	return s
+}
+
+// WithGracePeriods sets how early before the start of their slot and how late after its end the parties are checked in
+// and return the service
+func (s *Service) WithGracePeriods(early, late time.Duration) *Service {
+	s.earlyGrace, s.lateGrace = early, late
+	return s
+}
+
+// WithTicketValidity sets how long the tickets issued are valid, the ones of slotted reservations lasting at least
+// until their slot ends, and how far ahead of the server the clocks of the door devices may run, and return the service
+func (s *Service) WithTicketValidity(ttl, clockSkew time.Duration) *Service {
+	s.ticketTTL, s.clockSkew = ttl, clockSkew
+	return s
+}
+
+// WithAdjacentZones sets the zones next to each other, for the adjacent_zone seating constraints, and return the service
+func (s *Service) WithAdjacentZones(zones AdjacentZones) *Service {
+	s.adjacentZones = zones
+	return s
+}
+
+// WithCommitHook adds a hook run after every transaction of the service writing to the database commits, such as the
+// refresh of the occupancy gauges, and return the service. The writes rolled back never reach the hooks.
+func (s *Service) WithCommitHook(hook func(db *gorm.DB) error) *Service {
+	s.commitHooks = append(s.commitHooks, hook)
+	return s
+}
+
+// transaction runs fn in a transaction bound to the context, committed when fn succeeds. The commit hooks run once
+// the writes of fn are committed.
+func (s *Service) transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	wrote := new(bool)
+	if err := s.db.WithContext(context.WithValue(ctx, writesKey{}, wrote)).Transaction(fn); err != nil {
+		return err
+	}
+
+	if *wrote {
+		for _, hook := range s.commitHooks {
+			if err := hook(s.db.WithContext(ctx)); err != nil {
+				logging.FromContext(ctx).Error("error running commit hook", zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// writesKey is the context key of the flag set by the writes of a transaction of the service
+type writesKey struct{}
+
+// trackWrites registers the gorm callbacks flagging the transactions of the service writing to the database, once
+// per connection
+func trackWrites(db *gorm.DB) {
+	cb := db.Callback()
+	if cb.Create().Get("party:track_writes") != nil {
+		return
+	}
+
+	flag := func(db *gorm.DB) {
+		if wrote, ok := db.Statement.Context.Value(writesKey{}).(*bool); ok && db.Error == nil && db.Statement.RowsAffected > 0 {
+			*wrote = true
+		}
+	}
+
+	for _, c := range []interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{cb.Create().After("*"), cb.Update().After("*"), cb.Delete().After("*"), cb.Raw().After("*")} {
+		if err := c.Register("party:track_writes", flag); err != nil {
+			db.Logger.Error(context.Background(), "error registering write tracking callback: %v", err)
+		}
+	}
+}
+
+// notify sends a message of the given kind to the reservation holder, once the changes are committed
+func (s *Service) notify(ctx context.Context, kind notify.Kind, reservation *models.Reservation, previousTable int) {
+	if s.notifier == nil {
+		return
+	}
+
+	notify.Reservation(ctx, s.notifier, kind, reservation, previousTable)
+}
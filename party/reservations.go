@@ -0,0 +1,465 @@
+package party
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/notify"
+	"gorm.io/gorm"
+)
+
+// Ref references a reservation by its id or, when the id is empty, by the name of its holder
+type Ref struct {
+	ID   string
+	Name string
+}
+
+// ByID references the reservation with the given id
+func ByID(id string) Ref { return Ref{ID: id} }
+
+// ByName references the only reservation held by the given name
+func ByName(name string) Ref { return Ref{Name: name} }
+
+// find loads the referenced reservation. A name shared by several reservations fails with an *AmbiguousError.
+func (r Ref) find(tx *gorm.DB, reservation *models.Reservation) error {
+	if r.ID != "" {
+		if err := tx.First(reservation, "id = ?", r.ID).Error; err != nil {
+			return queryError(err, "error retrieving guest reservation")
+		}
+		return nil
+	}
+
+	var matches []models.Reservation
+	if err := tx.Where("name = ?", r.Name).Order("id").Find(&matches).Error; err != nil {
+		return queryError(err, "error retrieving guest reservation")
+	}
+
+	switch len(matches) {
+	case 0:
+		return queryError(gorm.ErrRecordNotFound, "error retrieving guest reservation")
+	case 1:
+		*reservation = matches[0]
+		return nil
+	default:
+		return &AmbiguousError{Name: r.Name, Candidates: matches}
+	}
+}
+
+// Booking holds the reservation of a guest at a table. The named members, when given, are part of the party and
+// set the accompanying guests when not given. The constraints bind the reservation to already booked ones.
+type Booking struct {
+	Name               string
+	Table              int
+	AccompanyingGuests int
+	Requirements       models.Features
+	StartsAt           *time.Time
+	EndsAt             *time.Time
+	RespondBy          *time.Time
+	Email              string
+	Phone              string
+	Members            []models.Member
+	Constraints        []Constraint
+}
+
+// BookReservation invites the guest to the table and notifies the reservation holder. It fails when the table breaks
+// any of the constraints of the booking.
+func (s *Service) BookReservation(ctx context.Context, booking Booking) (*models.Reservation, error) {
+	record := models.Reservation{
+		Name:               booking.Name,
+		AccompanyingGuests: booking.AccompanyingGuests,
+		TableID:            booking.Table,
+		Requirements:       booking.Requirements,
+		StartsAt:           booking.StartsAt,
+		EndsAt:             booking.EndsAt,
+		RespondBy:          booking.RespondBy,
+		Email:              booking.Email,
+		Phone:              booking.Phone,
+		Members:            booking.Members,
+	}
+
+	if len(record.Members) > 0 && record.AccompanyingGuests == 0 {
+		record.AccompanyingGuests = len(record.Members) - 1
+	}
+	for i := range record.Members {
+		record.Members[i].Position = i
+	}
+
+	// validate model
+	if err := record.Validate(s.db); err != nil {
+		return nil, errorf(ErrInvalid, "error validating reservation: %v", err)
+	}
+
+	for _, c := range booking.Constraints {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return queryError(err, "error creating reservation")
+		}
+
+		if len(booking.Constraints) == 0 {
+			return nil
+		}
+
+		if _, err := addConstraints(tx, record.ID, booking.Constraints); err != nil {
+			return err
+		}
+		return s.checkConstraints(tx, record.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, notify.Confirmation, &record, 0)
+
+	return &record, nil
+}
+
+// ReservationChanges holds the fields of a reservation to change, the nil ones are kept. The start and the end of
+// the slot are changed together.
+type ReservationChanges struct {
+	Name               *string
+	Table              *int
+	AccompanyingGuests *int
+	Requirements       *models.Features
+	StartsAt           *time.Time
+	EndsAt             *time.Time
+	RespondBy          *time.Time
+	Email              *string
+	Phone              *string
+}
+
+// UpdateReservation changes the given fields of the reservation and notifies the holder,
+// of the new table when the guest was moved. Changing the table fails when the reservation breaks any of its seating
+// constraints, the guest of a party already arrived moves along with it. The status is changed by the invitation
+// response, which checks the constraints of the reservation accepted (see RespondInvitation).
+func (s *Service) UpdateReservation(ctx context.Context, ref Ref, changes ReservationChanges) (*models.Reservation, error) {
+	var record models.Reservation
+	var previousTable int
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := ref.find(tx, &record); err != nil {
+			return err
+		}
+
+		previousTable = record.TableID
+		previousName := record.Name
+
+		if changes.Name != nil {
+			record.Name = *changes.Name
+		}
+		if changes.Table != nil {
+			record.TableID = *changes.Table
+		}
+		if changes.AccompanyingGuests != nil {
+			record.AccompanyingGuests = *changes.AccompanyingGuests
+		}
+		if changes.Requirements != nil {
+			record.Requirements = *changes.Requirements
+		}
+		if changes.StartsAt != nil || changes.EndsAt != nil {
+			record.StartsAt, record.EndsAt = changes.StartsAt, changes.EndsAt
+		}
+		if changes.RespondBy != nil {
+			record.RespondBy = changes.RespondBy
+		}
+		if changes.Email != nil {
+			record.Email = *changes.Email
+		}
+		if changes.Phone != nil {
+			record.Phone = *changes.Phone
+		}
+
+		// validate model
+		if err := record.Validate(tx); err != nil {
+			return errorf(ErrInvalid, "error validating reservation: %v", err)
+		}
+
+		// the named members stay part of the party
+		if changes.AccompanyingGuests != nil {
+			var members int64
+			if err := tx.Model(new(models.Member)).Where("reservation_id = ?", record.ID).Count(&members).Error; err != nil {
+				return queryError(err, "error counting reservation members")
+			}
+			if int(members) > record.Guests() {
+				return errorf(ErrInvalid, "the party of %d can not hold its %d members", record.Guests(), members)
+			}
+		}
+
+		// capacity rules are enforced by the model hooks
+		if err := tx.Save(&record).Error; err != nil {
+			return queryError(err, "error updating reservation")
+		}
+
+		if record.Name != previousName {
+			if err := record.Reindex(tx); err != nil {
+				return queryError(err, "error renaming reservation")
+			}
+		}
+
+		if record.TableID == previousTable {
+			return nil
+		}
+
+		if err := s.checkConstraints(tx, record.ID); err != nil {
+			return err
+		}
+		return moveGuest(tx, &record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if record.TableID != previousTable {
+		s.notify(ctx, notify.TableChange, &record, previousTable)
+	} else {
+		s.notify(ctx, notify.Confirmation, &record, 0)
+	}
+
+	return &record, nil
+}
+
+// moveGuest seats the guest of the reservation, if arrived, at the table of the reservation. The capacity rules of the
+// new table are enforced by the model hooks.
+func moveGuest(tx *gorm.DB, reservation *models.Reservation) error {
+	var guests []models.Guest
+	if err := tx.Where("reservation_id = ?", reservation.ID).Find(&guests).Error; err != nil {
+		return queryError(err, "error retrieving guest")
+	}
+
+	for i := range guests {
+		guests[i].TableID = reservation.TableID
+		if err := tx.Save(&guests[i]).Error; err != nil {
+			return queryError(err, "error moving guest")
+		}
+	}
+	return nil
+}
+
+// CancelReservation removes the reservation from the guest list and notifies the holder. The persons of the party
+// present leave with it.
+func (s *Service) CancelReservation(ctx context.Context, ref Ref) (*models.Reservation, error) {
+	var record models.Reservation
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := ref.find(tx, &record); err != nil {
+			return err
+		}
+
+		// the guest is deleted along with its reservation
+		var guests []models.Guest
+		if err := tx.Where("reservation_id = ?", record.ID).Find(&guests).Error; err != nil {
+			return queryError(err, "error retrieving guest")
+		}
+		if len(guests) > 0 {
+			if err := recordMovement(tx, record.ID, -guests[0].TotalGuests(), time.Now()); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&record).Error; err != nil {
+			return queryError(err, "error cancelling reservation")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, notify.Cancellation, &record, 0)
+
+	return &record, nil
+}
+
+// RemindReservation sends a reminder to the reservation holder
+func (s *Service) RemindReservation(ctx context.Context, ref Ref) error {
+	if s.notifier == nil {
+		return errorf(ErrNotificationsDisabled, "notifications are disabled")
+	}
+
+	record, err := s.Reservation(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	s.notify(ctx, notify.Reminder, record, 0)
+
+	return nil
+}
+
+// Reservation returns the referenced reservation with its members and arrivals
+func (s *Service) Reservation(ctx context.Context, ref Ref) (*models.Reservation, error) {
+	var record models.Reservation
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := ref.find(tx, &record); err != nil {
+			return err
+		}
+
+		if err := tx.Where("reservation_id = ?", record.ID).Order("position").Find(&record.Members).Error; err != nil {
+			return queryError(err, "error retrieving reservation members")
+		}
+
+		var guests []models.Guest
+		if err := tx.Where("reservation_id = ?", record.ID).Find(&guests).Error; err != nil {
+			return queryError(err, "error retrieving reservation arrivals")
+		}
+
+		record.Arrivals = record.ArrivalsOf(nil)
+		if len(guests) > 0 {
+			record.Arrivals = record.ArrivalsOf(&guests[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// Reservations returns the page of the guest list ordered by name and the key of the next page,
+// only the reservations in the given status when it is not empty
+func (s *Service) Reservations(ctx context.Context, status string, page Page) ([]models.Reservation, string, error) {
+	if err := page.validate(); err != nil {
+		return nil, "", err
+	}
+
+	if status != "" && !models.ValidStatus(status) {
+		return nil, "", errorf(ErrInvalid, `invalid "%s" reservation status`, status)
+	}
+
+	var elements []models.Reservation
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		query := tx.Scopes(page.scopeByID("name"))
+		if status != "" {
+			query = query.Scopes(models.WithStatus(status, time.Now()))
+		}
+
+		if err := query.Find(&elements).Error; err != nil {
+			return queryError(err, "error retrieving reservations")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	n, next := page.next(len(elements), func(last int) string { return keyByID(elements[last].Name, elements[last].ID) })
+	return elements[:n], next, nil
+}
+
+// Summary holds the invitation counts per status and the response rates
+type Summary struct {
+	Total          int
+	Invited        int
+	Accepted       int
+	Declined       int
+	Expired        int
+	ResponseRate   float64
+	AcceptanceRate float64
+}
+
+// ReservationsSummary counts the invitations per status, the ones whose deadline passed are counted as expired
+func (s *Service) ReservationsSummary(ctx context.Context) (*Summary, error) {
+	var rows []struct {
+		Response string
+		Total    int
+	}
+
+	status := "CASE WHEN status = ? AND respond_by < ? THEN ? ELSE status END"
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Model(new(models.Reservation)).
+			Select(status+" AS response, COUNT(*) AS total", models.StatusInvited, time.Now(), models.StatusExpired).
+			Group("response").Scan(&rows).Error; err != nil {
+			return queryError(err, "error calculating summary")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var summary Summary
+	for _, row := range rows {
+		switch row.Response {
+		case models.StatusInvited:
+			summary.Invited = row.Total
+		case models.StatusAccepted:
+			summary.Accepted = row.Total
+		case models.StatusDeclined:
+			summary.Declined = row.Total
+		case models.StatusExpired:
+			summary.Expired = row.Total
+		}
+		summary.Total += row.Total
+	}
+
+	if responded := summary.Accepted + summary.Declined; responded > 0 {
+		summary.ResponseRate = float64(responded) / float64(summary.Total)
+		summary.AcceptanceRate = float64(summary.Accepted) / float64(responded)
+	}
+
+	return &summary, nil
+}
+
+// RespondInvitation accepts or declines the invitation identified by the rsvp token.
+// Accepting an invitation takes the seats of the reservation, so it fails when the table is full or breaks any of the
+// seating constraints of the reservation.
+// The expiration of an invitation answered after its deadline is recorded before failing.
+func (s *Service) RespondInvitation(ctx context.Context, token string, accept bool) (*models.Reservation, error) {
+	var reservation models.Reservation
+	var expired bool
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.First(&reservation, "token = ?", token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errorf(ErrNotFound, "invitation not found")
+			}
+			return queryError(err, "error retrieving invitation")
+		}
+
+		now := time.Now()
+
+		// persist the expiration of the invitation
+		if expired = reservation.Expired(now); expired {
+			if err := tx.Model(&reservation).Update("status", models.StatusExpired).Error; err != nil {
+				return queryError(err, "error expiring invitation")
+			}
+			return nil
+		}
+
+		previous := reservation.Status
+		reservation.Status = models.StatusDeclined
+		if accept {
+			reservation.Status = models.StatusAccepted
+		}
+		reservation.RespondedAt = &now
+
+		// capacity rules are enforced by the model hooks
+		if err := tx.Save(&reservation).Error; err != nil {
+			return queryError(err, "error updating invitation")
+		}
+
+		// the seating constraints of the declined reservations are skipped until they are accepted again
+		if reservation.Status != previous && reservation.Accepted() {
+			return s.checkConstraints(tx, reservation.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if expired {
+		return nil, errorf(ErrInvitationExpired, "invitation expired")
+	}
+
+	return &reservation, nil
+}
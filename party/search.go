@@ -0,0 +1,97 @@
+package party
+
+import (
+	"context"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/search"
+	"gorm.io/gorm"
+)
+
+// Search result limits
+const (
+	DefaultSearchResults = 10
+	MaxSearchResults     = 50
+)
+
+// Match is a reservation found by a search, scored by its similarity with the query between 0 and 1
+type Match struct {
+	Reservation models.Reservation
+	Score       float64
+}
+
+// SearchReservations finds the reservations whose holder name resembles the query, case and accent insensitive and
+// tolerating typos, the best matches first. Names are looked up in the trigram index and only the names sharing the
+// most trigrams with the query are ranked, up to limit results or DefaultSearchResults when it is zero.
+func (s *Service) SearchReservations(ctx context.Context, query string, limit int) ([]Match, error) {
+	if limit == 0 {
+		limit = DefaultSearchResults
+	}
+
+	if limit < 0 || limit > MaxSearchResults {
+		return nil, errorf(ErrInvalid, `invalid "%d" limit, expected a value between 1 and %d`, limit, MaxSearchResults)
+	}
+
+	trigrams := search.QueryTrigrams(query)
+	if len(trigrams) == 0 {
+		return nil, errorf(ErrInvalid, "search query should have at least a letter or digit")
+	}
+
+	var (
+		hits         []search.Candidate
+		reservations []models.Reservation
+	)
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		// rank more candidates than requested, the ones sharing the most trigrams are not always the closest
+		if err := tx.Model(new(models.ReservationTrigram)).
+			Select("reservation_id AS id, COUNT(*) AS hits").
+			Where("trigram IN ?", trigrams).
+			Group("reservation_id").
+			Order("hits DESC, reservation_id").
+			Limit(limit * 5).
+			Scan(&hits).Error; err != nil {
+			return queryError(err, "error searching reservations")
+		}
+
+		if len(hits) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(hits))
+		for i, c := range hits {
+			ids[i] = c.ID
+		}
+
+		if err := tx.Where("id IN ?", ids).Find(&reservations).Error; err != nil {
+			return queryError(err, "error retrieving reservations")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// name the candidates after their reservations
+	found := make(map[string]models.Reservation, len(reservations))
+	for _, r := range reservations {
+		found[r.ID] = r
+	}
+
+	var candidates []search.Candidate
+	for _, c := range hits {
+		if reservation, ok := found[c.ID]; ok {
+			candidates = append(candidates, search.Candidate{ID: c.ID, Name: reservation.Name, Hits: c.Hits})
+		}
+	}
+
+	var matches []Match
+	for _, result := range search.Rank(query, candidates) {
+		matches = append(matches, Match{Reservation: found[result.ID], Score: result.Score})
+		if len(matches) == limit {
+			break
+		}
+	}
+
+	return matches, nil
+}
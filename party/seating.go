@@ -0,0 +1,303 @@
+package party
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"gorm.io/gorm"
+)
+
+// AdjacentZones holds the zones next to each zone, a zone is always adjacent to itself
+type AdjacentZones map[string][]string
+
+// ParseAdjacentZones returns the adjacent zones of the comma separated pairs of zones joined by a colon,
+// such as "garden:terrace,terrace:hall"
+func ParseAdjacentZones(raw string) (AdjacentZones, error) {
+	zones := make(AdjacentZones)
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf(`invalid "%s" adjacent zones, expected two zones joined by a colon`, pair)
+		}
+
+		a, b := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		zones[a] = append(zones[a], b)
+		zones[b] = append(zones[b], a)
+	}
+	return zones, nil
+}
+
+// Adjacent reports whether the zones are the same or next to each other
+func (z AdjacentZones) Adjacent(a, b string) bool {
+	if a == b {
+		return true
+	}
+	for _, zone := range z[a] {
+		if zone == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Constraint holds a seating rule binding a reservation to the Other one
+type Constraint struct {
+	Kind  string
+	Other string
+	Note  string
+}
+
+func (c Constraint) validate() error {
+	if !models.ValidConstraintKind(c.Kind) {
+		return errorf(ErrInvalid, `invalid "%s" constraint kind, expected same_table, adjacent_zone or different_table`, c.Kind)
+	}
+	if c.Other == "" {
+		return errorf(ErrInvalid, "the %s constraint requires the other reservation", c.Kind)
+	}
+	return nil
+}
+
+// AddConstraint binds the reservation with the given id to the other reservation of the constraint. The constraint is
+// recorded even when the current plan breaks it, the violations report lists it until the reservations are moved.
+func (s *Service) AddConstraint(ctx context.Context, reservation string, constraint Constraint) (*models.SeatingConstraint, error) {
+	if err := constraint.validate(); err != nil {
+		return nil, err
+	}
+
+	if reservation == constraint.Other {
+		return nil, errorf(ErrInvalid, "the constraint binds the reservation %s to itself", reservation)
+	}
+
+	var records []models.SeatingConstraint
+	err := s.transaction(ctx, func(tx *gorm.DB) (err error) {
+		if err := ByID(reservation).find(tx, new(models.Reservation)); err != nil {
+			return err
+		}
+
+		records, err = addConstraints(tx, reservation, []Constraint{constraint})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &records[0], nil
+}
+
+// Constraints returns the seating constraints ordered by id, only the ones binding the reservation with the given id
+// when it is not empty
+func (s *Service) Constraints(ctx context.Context, reservation string) ([]models.SeatingConstraint, error) {
+	var elements []models.SeatingConstraint
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		query := tx.Order("id")
+		if reservation != "" {
+			query = query.Where("reservation_id = ? OR other_id = ?", reservation, reservation)
+		}
+
+		if err := query.Find(&elements).Error; err != nil {
+			return queryError(err, "error retrieving seating constraints")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return elements, nil
+}
+
+// RemoveConstraint deletes the seating constraint with the given id
+func (s *Service) RemoveConstraint(ctx context.Context, id int) error {
+	return s.transaction(ctx, func(tx *gorm.DB) error {
+		result := tx.Delete(new(models.SeatingConstraint), id)
+		if result.Error != nil {
+			return queryError(result.Error, "error removing seating constraint")
+		}
+		if result.RowsAffected == 0 {
+			return errorf(ErrNotFound, "seating constraint %d not found", id)
+		}
+		return nil
+	})
+}
+
+// Violation is a seating constraint broken by the current plan, along the tables and zones of its reservations and
+// the reason it is broken
+type Violation struct {
+	Constraint models.SeatingConstraint
+	Table      int
+	Zone       string
+	OtherTable int
+	OtherZone  string
+	Reason     string
+}
+
+// Violations returns the seating constraints broken by the tables of the reservations, the ones binding declined or
+// expired reservations are not taken into account
+func (s *Service) Violations(ctx context.Context) ([]Violation, error) {
+	var violations []Violation
+	err := s.transaction(ctx, func(tx *gorm.DB) (err error) {
+		violations, err = s.violations(tx, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return violations, nil
+}
+
+// placement is a seating constraint along the tables and zones its reservations are seated at
+type placement struct {
+	ID            int
+	Kind          string
+	ReservationID string
+	OtherID       string
+	Note          string
+	TableID       int
+	Zone          string
+	OtherTableID  int
+	OtherZone     string
+}
+
+// reason returns why the placement breaks its constraint, empty when it follows it
+func (p *placement) reason(zones AdjacentZones) string {
+	switch p.Kind {
+	case models.ConstraintSameTable:
+		if p.TableID != p.OtherTableID {
+			return fmt.Sprintf("seated at the tables %d and %d", p.TableID, p.OtherTableID)
+		}
+	case models.ConstraintDifferentTable:
+		if p.TableID == p.OtherTableID {
+			return fmt.Sprintf("both seated at the table %d", p.TableID)
+		}
+	case models.ConstraintAdjacentZone:
+		if !zones.Adjacent(p.Zone, p.OtherZone) {
+			return fmt.Sprintf(`seated in the zones "%s" and "%s", which are not adjacent`, p.Zone, p.OtherZone)
+		}
+	}
+	return ""
+}
+
+// violations returns the broken seating constraints, only the ones binding the reservation with the given id
+// when it is not empty. The constraints of the declined and expired invitations, the ones whose deadline passed
+// included, are skipped.
+func (s *Service) violations(tx *gorm.DB, reservation string) ([]Violation, error) {
+	skipped := []string{models.StatusDeclined, models.StatusExpired}
+	now := time.Now()
+
+	// pending returns the condition of the reservation with the given alias not declined nor expired
+	pending := func(alias string) string {
+		return alias + ".status NOT IN ? AND (" + alias + ".status <> ? OR " + alias + ".respond_by IS NULL OR " + alias + ".respond_by >= ?)"
+	}
+
+	query := tx.Table("seating_constraints").
+		Select("seating_constraints.id, seating_constraints.kind, seating_constraints.reservation_id, seating_constraints.other_id, seating_constraints.note, "+
+			"holder.table_id, holder_table.zone, other.table_id AS other_table_id, other_table.zone AS other_zone").
+		Joins("JOIN reservations holder ON holder.id = seating_constraints.reservation_id").
+		Joins("JOIN reservations other ON other.id = seating_constraints.other_id").
+		Joins("JOIN tables holder_table ON holder_table.id = holder.table_id").
+		Joins("JOIN tables other_table ON other_table.id = other.table_id").
+		Where(pending("holder")+" AND "+pending("other"), skipped, models.StatusInvited, now, skipped, models.StatusInvited, now).
+		Order("seating_constraints.id")
+	if reservation != "" {
+		query = query.Where("seating_constraints.reservation_id = ? OR seating_constraints.other_id = ?", reservation, reservation)
+	}
+
+	var placements []placement
+	if err := query.Scan(&placements).Error; err != nil {
+		return nil, queryError(err, "error loading seating constraints")
+	}
+
+	var violations []Violation
+	for _, p := range placements {
+		reason := p.reason(s.adjacentZones)
+		if reason == "" {
+			continue
+		}
+
+		violations = append(violations, Violation{
+			Constraint: models.SeatingConstraint{ID: p.ID, Kind: p.Kind, ReservationID: p.ReservationID, OtherID: p.OtherID, Note: p.Note},
+			Table:      p.TableID,
+			Zone:       p.Zone,
+			OtherTable: p.OtherTableID,
+			OtherZone:  p.OtherZone,
+			Reason:     reason,
+		})
+	}
+
+	return violations, nil
+}
+
+// checkConstraints fails when the table of the reservation with the given id breaks any of its seating constraints
+func (s *Service) checkConstraints(tx *gorm.DB, reservation string) error {
+	violations, err := s.violations(tx, reservation)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		v := violations[0]
+		return errorf(ErrConstraintViolated, "%s constraint %d between the reservations %s and %s violated: %s",
+			v.Constraint.Kind, v.Constraint.ID, v.Constraint.ReservationID, v.Constraint.OtherID, v.Reason)
+	}
+
+	return nil
+}
+
+// addConstraints creates the constraints binding the reservation with the given id, their other reservations must exist
+func addConstraints(tx *gorm.DB, reservation string, constraints []Constraint) ([]models.SeatingConstraint, error) {
+	records := make([]models.SeatingConstraint, len(constraints))
+	for i, c := range constraints {
+		if err := ByID(c.Other).find(tx, new(models.Reservation)); err != nil {
+			return nil, err
+		}
+
+		records[i] = models.SeatingConstraint{Kind: c.Kind, ReservationID: reservation, OtherID: c.Other, Note: c.Note}
+		if err := tx.Create(&records[i]).Error; err != nil {
+			return nil, queryError(err, "error creating seating constraint")
+		}
+	}
+	return records, nil
+}
+
+// seatingScopes returns the scopes restricting a tables query to the ones following the constraints, given the
+// tables and zones their other reservations are seated at
+func (s *Service) seatingScopes(tx *gorm.DB, constraints []Constraint) ([]func(*gorm.DB) *gorm.DB, error) {
+	scopes := make([]func(*gorm.DB) *gorm.DB, len(constraints))
+	for i, c := range constraints {
+		var seats []struct {
+			TableID int
+			Zone    string
+		}
+		if err := tx.Table("reservations").
+			Select("reservations.table_id, tables.zone").
+			Joins("JOIN tables ON tables.id = reservations.table_id").
+			Where("reservations.id = ?", c.Other).
+			Scan(&seats).Error; err != nil {
+			return nil, queryError(err, "error loading constrained reservation")
+		}
+		if len(seats) == 0 {
+			return nil, errorf(ErrNotFound, "reservation %s not found", c.Other)
+		}
+
+		seat, kind := seats[0], c.Kind
+		scopes[i] = func(db *gorm.DB) *gorm.DB {
+			switch kind {
+			case models.ConstraintSameTable:
+				return db.Where("tables.id = ?", seat.TableID)
+			case models.ConstraintDifferentTable:
+				return db.Where("tables.id <> ?", seat.TableID)
+			default:
+				return db.Where("tables.zone IN ?", append([]string{seat.Zone}, s.adjacentZones[seat.Zone]...))
+			}
+		}
+	}
+	return scopes, nil
+}
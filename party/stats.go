@@ -0,0 +1,156 @@
+package party
+
+import (
+	"context"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"gorm.io/gorm"
+)
+
+// Stats holds the figures of the event for its reports
+type Stats struct {
+	// Reservations is the amount of accepted reservations, Expected the ones whose slot started
+	Reservations int
+	Expected     int
+	// NoShows is the amount of expected reservations without arrivals
+	NoShows    int
+	NoShowRate float64
+	// AveragePartySize is the mean of the persons of the accepted reservations
+	AveragePartySize float64
+	Arrivals         []ArrivalBucket
+	Peak             Peak
+	Tables           []TableUse
+}
+
+// ArrivalBucket holds the parties with arrivals and the persons arrived in the bucket starting at Start
+type ArrivalBucket struct {
+	Start   time.Time
+	Parties int
+	Persons int
+}
+
+// Peak is the most persons present at once and the moment they were reached
+type Peak struct {
+	Persons int
+	At      *time.Time
+}
+
+// TableUse holds the seats of a table booked by the accepted reservations at its busiest sitting and taken by the
+// guests present
+type TableUse struct {
+	ID          int
+	Label       string
+	Zone        string
+	Capacity    int
+	Booked      int
+	Present     int
+	Utilization float64
+}
+
+// Stats computes the figures of the event from the movements of the guests, the arrivals being counted per bucket of
+// the given length. The parties arrived and gone are counted in the arrivals and the peak, and are not no-shows.
+func (s *Service) Stats(ctx context.Context, bucket time.Duration) (*Stats, error) {
+	if bucket < time.Second || bucket%time.Second != 0 {
+		return nil, errorf(ErrInvalid, `invalid "%s" bucket, expected a whole amount of seconds`, bucket)
+	}
+	seconds := int64(bucket / time.Second)
+
+	var stats Stats
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		// reservations, the ones whose slot has not started yet are not expected
+		started := "reservations.starts_at IS NULL OR reservations.starts_at <= ?"
+		now := time.Now()
+
+		var totals struct {
+			Reservations int
+			Expected     int
+			NoShows      int
+			PartySize    float64
+		}
+		if err := tx.Table("reservations").
+			Select("COUNT(*) AS reservations, "+
+				"COALESCE(SUM(CASE WHEN "+started+" THEN 1 ELSE 0 END), 0) AS expected, "+
+				"COALESCE(SUM(CASE WHEN reservations.arrived_at IS NULL AND ("+started+") THEN 1 ELSE 0 END), 0) AS no_shows, "+
+				"COALESCE(AVG(1 + reservations.accompanying_guests), 0) AS party_size", now, now).
+			Where("reservations.status = ?", models.StatusAccepted).
+			Scan(&totals).Error; err != nil {
+			return queryError(err, "error calculating reservation stats")
+		}
+		stats.Reservations, stats.Expected, stats.NoShows = totals.Reservations, totals.Expected, totals.NoShows
+		stats.AveragePartySize = totals.PartySize
+		if stats.Expected > 0 {
+			stats.NoShowRate = float64(stats.NoShows) / float64(stats.Expected)
+		}
+
+		// arrivals per bucket, the movements are stored in UTC and counted from the epoch without the session time
+		// zone UNIX_TIMESTAMP would apply
+		var buckets []struct {
+			Bucket  int64
+			Parties int
+			Persons int
+		}
+		if err := tx.Model(new(models.Movement)).
+			Select("FLOOR(TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', created_at) / ?) * ? AS bucket, COUNT(DISTINCT reservation_id) AS parties, SUM(persons) AS persons", seconds, seconds).
+			Where("persons > 0").
+			Group("bucket").
+			Order("bucket").
+			Scan(&buckets).Error; err != nil {
+			return queryError(err, "error calculating arrivals")
+		}
+		stats.Arrivals = make([]ArrivalBucket, len(buckets))
+		for i, b := range buckets {
+			stats.Arrivals[i] = ArrivalBucket{Start: time.Unix(b.Bucket, 0).UTC(), Parties: b.Parties, Persons: b.Persons}
+		}
+
+		// the persons present after each movement are the sum of the movements up to it, the peak being the first
+		// moment the most were. MySQL 5.7 has no window functions, the sum is a correlated subquery.
+		running := tx.Table("movements earlier").
+			Select("SUM(earlier.persons)").
+			Where("earlier.created_at < movements.created_at OR (earlier.created_at = movements.created_at AND earlier.id <= movements.id)")
+		if err := tx.Model(new(models.Movement)).
+			Select("(?) AS persons, movements.created_at AS at", running).
+			Order("persons DESC, movements.created_at, movements.id").
+			Limit(1).
+			Scan(&stats.Peak).Error; err != nil {
+			return queryError(err, "error calculating peak occupancy")
+		}
+
+		// seats of each table booked at its busiest sitting: the reservations seated grow when a slot starts, and the
+		// ones without slot are seated the whole event
+		sittings := tx.Table("reservations").
+			Select("DISTINCT table_id, starts_at").
+			Where("status = ?", models.StatusAccepted)
+		seated := tx.Table("(?) sittings", sittings).
+			Select("sittings.table_id, SUM(1 + reservations.accompanying_guests) AS persons").
+			Joins("JOIN reservations ON reservations.table_id = sittings.table_id AND reservations.status = ? AND "+
+				"(reservations.starts_at IS NULL OR (reservations.starts_at <= sittings.starts_at AND reservations.ends_at > sittings.starts_at))", models.StatusAccepted).
+			Group("sittings.table_id, sittings.starts_at")
+		booked := tx.Table("(?) seated", seated).
+			Select("table_id, MAX(persons) AS persons").
+			Group("table_id")
+		present := tx.Table("guests").
+			Select("table_id, SUM(1 + accompanying_guests) AS persons").
+			Group("table_id")
+		if err := tx.Table("tables").
+			Select("tables.id, tables.label, tables.zone, tables.capacity, COALESCE(booked.persons, 0) AS booked, COALESCE(present.persons, 0) AS present").
+			Joins("LEFT JOIN (?) booked ON booked.table_id = tables.id", booked).
+			Joins("LEFT JOIN (?) present ON present.table_id = tables.id", present).
+			Order("tables.id").
+			Scan(&stats.Tables).Error; err != nil {
+			return queryError(err, "error calculating table utilization")
+		}
+		for i := range stats.Tables {
+			if t := &stats.Tables[i]; t.Capacity > 0 {
+				t.Utilization = float64(t.Present) / float64(t.Capacity)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
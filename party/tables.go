@@ -0,0 +1,163 @@
+package party
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"gorm.io/gorm"
+)
+
+// TableSpec holds the table to add, its label, zone and features are optional
+type TableSpec struct {
+	Label    string
+	Zone     string
+	Capacity int
+	Features models.Features
+}
+
+// AddTable creates a table with the given capacity
+func (s *Service) AddTable(ctx context.Context, spec TableSpec) (*models.Table, error) {
+	record := models.Table{Label: spec.Label, Zone: spec.Zone, Capacity: spec.Capacity, Features: spec.Features}
+
+	// validate model
+	if err := record.Validate(s.db); err != nil {
+		return nil, errorf(ErrInvalid, "error validating table: %v", err)
+	}
+
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return queryError(err, "error creating table")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// TableFilter selects the tables listed, its zero value selects all of them
+type TableFilter struct {
+	Zone        string
+	Features    models.Features
+	MinCapacity int
+}
+
+// Tables returns the page of the tables matching the filter ordered by id and the key of the next page
+func (s *Service) Tables(ctx context.Context, filter TableFilter, page Page) ([]models.Table, string, error) {
+	if err := page.validate(); err != nil {
+		return nil, "", err
+	}
+
+	if err := filter.Features.Validate(); err != nil {
+		return nil, "", errorf(ErrInvalid, "%v", err)
+	}
+
+	var elements []models.Table
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		query := tx.Scopes(page.scope("id"), withFeatures(filter.Features))
+		if filter.Zone != "" {
+			query = query.Where("zone = ?", filter.Zone)
+		}
+		if filter.MinCapacity > 0 {
+			query = query.Where("capacity >= ?", filter.MinCapacity)
+		}
+
+		if err := query.Find(&elements).Error; err != nil {
+			return queryError(err, "error retrieving tables")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	n, next := page.next(len(elements), func(last int) string { return strconv.Itoa(elements[last].ID) })
+	return elements[:n], next, nil
+}
+
+// withFeatures scopes a tables query to the ones having all the features
+func withFeatures(features models.Features) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, feature := range features {
+			db = db.Where("FIND_IN_SET(?, tables.features)", feature)
+		}
+		return db
+	}
+}
+
+// ZoneSeats holds the seats of the tables of a zone, the tables without zone are grouped under the empty one
+type ZoneSeats struct {
+	Zone       string
+	Capacity   int
+	SeatsEmpty int
+}
+
+// SeatsEmpty returns the seats of the tables not taken by the arrived guests, in total and per zone
+func (s *Service) SeatsEmpty(ctx context.Context) (int, []ZoneSeats, error) {
+	// SET @availability := (SELECT COALESCE(SUM(capacity), 0) FROM tables);
+	// SELECT @availability - COUNT(*) - COALESCE(SUM(accompanying_guests), 0) FROM guests;
+
+	var (
+		capacity, occupied int
+		zones              []ZoneSeats
+	)
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Select("COALESCE(SUM(capacity), 0)").Table("tables").Scan(&capacity).Error; err != nil {
+			return queryError(err, "error calculating capacity")
+		}
+
+		if err := tx.Select("COUNT(*) + COALESCE(SUM(accompanying_guests), 0)").Table("guests").Scan(&occupied).Error; err != nil {
+			return queryError(err, "error getting occupancy")
+		}
+
+		present := tx.Table("guests").Select("table_id, SUM(1 + accompanying_guests) AS persons").Group("table_id")
+		if err := tx.Table("tables").
+			Select("tables.zone, SUM(tables.capacity) AS capacity, SUM(tables.capacity - COALESCE(present.persons, 0)) AS seats_empty").
+			Joins("LEFT JOIN (?) present ON present.table_id = tables.id", present).
+			Group("tables.zone").
+			Order("tables.zone").
+			Scan(&zones).Error; err != nil {
+			return queryError(err, "error getting occupancy per zone")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return capacity - occupied, zones, nil
+}
+
+// SeatsEmptyAt returns the seats of the tables not booked at the given moment, in total and per zone. The seats are
+// booked by the accepted reservations whose slot holds the moment, and by the ones without slot.
+func (s *Service) SeatsEmptyAt(ctx context.Context, at time.Time) (int, []ZoneSeats, error) {
+	var zones []ZoneSeats
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		booked := tx.Table("reservations").
+			Select("table_id, SUM(1 + accompanying_guests) AS persons").
+			Where("status = ? AND (starts_at IS NULL OR (starts_at <= ? AND ends_at > ?))", models.StatusAccepted, at, at).
+			Group("table_id")
+		if err := tx.Table("tables").
+			Select("tables.zone, SUM(tables.capacity) AS capacity, SUM(tables.capacity - COALESCE(booked.persons, 0)) AS seats_empty").
+			Joins("LEFT JOIN (?) booked ON booked.table_id = tables.id", booked).
+			Group("tables.zone").
+			Order("tables.zone").
+			Scan(&zones).Error; err != nil {
+			return queryError(err, "error getting seats booked")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var seats int
+	for _, z := range zones {
+		seats += z.SeatsEmpty
+	}
+	return seats, zones, nil
+}
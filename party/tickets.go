@@ -0,0 +1,267 @@
+package party
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/tickets"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Validity of the tickets by default
+const (
+	// DefaultTicketTTL is how long the tickets are valid once issued
+	DefaultTicketTTL = 30 * 24 * time.Hour
+	// DefaultClockSkew is how far ahead of the server the clocks of the door devices may run
+	DefaultClockSkew = 5 * time.Minute
+)
+
+// PublicKey returns the key the door devices use to verify the tickets offline
+func (s *Service) PublicKey() (ed25519.PublicKey, error) {
+	if s.issuer == nil {
+		return nil, errorf(ErrTicketsDisabled, "tickets are disabled")
+	}
+	return s.issuer.PublicKey(), nil
+}
+
+// Ticket issues a signed ticket for the referenced accepted reservation
+func (s *Service) Ticket(ctx context.Context, ref Ref) (string, error) {
+	if s.issuer == nil {
+		return "", errorf(ErrTicketsDisabled, "tickets are disabled")
+	}
+
+	reservation, err := s.Reservation(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	if !reservation.Accepted() {
+		return "", errorf(ErrNotAccepted, `reservation is "%s", only accepted reservations get tickets`, reservation.Status)
+	}
+
+	// the tickets of slotted reservations are valid at least until the last arrival their slot admits
+	now := time.Now()
+	expires := now.Add(s.ticketTTL)
+	if reservation.Slotted() && reservation.EndsAt.Add(s.lateGrace).After(expires) {
+		expires = reservation.EndsAt.Add(s.lateGrace)
+	}
+
+	ticket, err := s.issuer.Issue(tickets.Ticket{
+		Reservation: reservation.ID,
+		Name:        reservation.Name,
+		Table:       reservation.TableID,
+		PartySize:   reservation.Guests(),
+		IssuedAt:    now,
+		ExpiresAt:   expires,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error issuing ticket: %w", err)
+	}
+
+	return ticket, nil
+}
+
+// OfflineCheckIn is the arrival of persons of a party recorded by a door device without connection, identified by the
+// ticket of the guest. The device and the moment of the arrival identify the check-in when it is synced again.
+type OfflineCheckIn struct {
+	Device      string
+	Ticket      string
+	Persons     int
+	CheckedInAt time.Time
+}
+
+// Conflict is an offline check-in that could not be applied
+type Conflict struct {
+	Ticket string
+	Name   string
+	Reason string
+}
+
+// SyncResult holds the names of the guests registered by a sync and the check-ins that could not be applied
+type SyncResult struct {
+	Accepted  []string
+	Conflicts []Conflict
+}
+
+// errSynced is returned for the offline check-ins already applied by a previous sync
+var errSynced = errors.New("check-in already synced")
+
+// SyncCheckIns reconciles a batch of check-ins recorded offline by a door device. Check-ins are applied in the
+// order of the device timestamps, each in its own transaction, and the ones that can not be applied are reported as conflicts.
+// The device timestamps must be set, not ahead of the server clock by more than the clock skew allowed, and within the
+// validity of the ticket. The check-ins applied by a previous sync of the batch are accepted without counting their
+// persons again.
+func (s *Service) SyncCheckIns(ctx context.Context, checkIns []OfflineCheckIn) (*SyncResult, error) {
+	if s.issuer == nil {
+		return nil, errorf(ErrTicketsDisabled, "tickets are disabled")
+	}
+
+	// apply the check-ins in the order they happened at the door
+	sorted := append([]OfflineCheckIn(nil), checkIns...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CheckedInAt.Before(sorted[j].CheckedInAt)
+	})
+
+	result := &SyncResult{
+		Accepted:  []string{},
+		Conflicts: []Conflict{},
+	}
+
+	now := time.Now()
+	for _, checkIn := range sorted {
+		var name string
+		ticket, err := s.verifyCheckIn(checkIn, now)
+		if ticket != nil {
+			name = ticket.Name
+		}
+		if err == nil {
+			err = s.transaction(ctx, func(tx *gorm.DB) error {
+				return s.syncCheckIn(tx, ticket, checkIn)
+			})
+		}
+		if errors.Is(err, errSynced) {
+			logging.FromContext(ctx).Info("offline check-in already synced", logging.Name("guest", name), zap.String("device", checkIn.Device))
+			err = nil
+		}
+		if err != nil {
+			logging.FromContext(ctx).Warn("offline check-in conflict", logging.Name("guest", name), zap.Error(err))
+			result.Conflicts = append(result.Conflicts, Conflict{
+				Ticket: checkIn.Ticket,
+				Name:   name,
+				Reason: err.Error(),
+			})
+			continue
+		}
+
+		result.Accepted = append(result.Accepted, name)
+	}
+
+	return result, nil
+}
+
+// verifyCheckIn checks the timestamp recorded by the door device against the server clock and the ticket of the
+// offline check-in, which must have been valid at that moment, and returns the ticket
+func (s *Service) verifyCheckIn(checkIn OfflineCheckIn, now time.Time) (*tickets.Ticket, error) {
+	if checkIn.CheckedInAt.IsZero() {
+		return nil, errors.New("missing check-in time")
+	}
+	if checkIn.CheckedInAt.After(now.Add(s.clockSkew)) {
+		return nil, fmt.Errorf("check-in time %s is ahead of the server clock", checkIn.CheckedInAt.Format(time.RFC3339))
+	}
+
+	ticket, err := tickets.VerifyAt(s.issuer.PublicKey(), checkIn.Ticket, checkIn.CheckedInAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ticket shown at the door was issued before the arrival, up to the drift of the device clock
+	if checkIn.CheckedInAt.Before(ticket.IssuedAt.Add(-s.clockSkew)) {
+		return ticket, fmt.Errorf("check-in time %s is before the ticket was issued", checkIn.CheckedInAt.Format(time.RFC3339))
+	}
+
+	return ticket, nil
+}
+
+// syncCheckIn registers the persons of a single offline check-in with a verified ticket: the first arrival is the
+// guest of the reservation, within the slot of the reservation, and the following ones add their persons to it, as the
+// check-ins online. The check-in is recorded as synced with the movement of its persons.
+// The tickets issued before the reservations had ids reference them by name.
+func (s *Service) syncCheckIn(tx *gorm.DB, ticket *tickets.Ticket, checkIn OfflineCheckIn) error {
+	ref := ByID(ticket.Reservation)
+	if ticket.Reservation == "" {
+		ref = ByName(ticket.Name)
+	}
+
+	// get guest reservation
+	var reservation models.Reservation
+	if err := ref.find(tx, &reservation); err != nil {
+		return err
+	}
+
+	if !reservation.Accepted() {
+		return fmt.Errorf(`reservation is "%s"`, reservation.Status)
+	}
+
+	if checkIn.Persons < 1 {
+		return fmt.Errorf(`invalid "%d" arriving persons`, checkIn.Persons)
+	}
+
+	// the device timestamps are stored to the millisecond
+	synced := models.SyncedCheckIn{
+		Device:        checkIn.Device,
+		ReservationID: reservation.ID,
+		CheckedInAt:   checkIn.CheckedInAt.Truncate(time.Millisecond),
+	}
+
+	var applied int64
+	if err := tx.Model(new(models.SyncedCheckIn)).Where("device = ? AND reservation_id = ? AND checked_in_at = ?", synced.Device, synced.ReservationID, synced.CheckedInAt).
+		Count(&applied).Error; err != nil {
+		return fmt.Errorf("error checking synced check-ins: %v", err)
+	}
+	if applied > 0 {
+		return errSynced
+	}
+
+	// the persons add to the guest already registered
+	var guests []models.Guest
+	if err := tx.Where("reservation_id = ?", reservation.ID).Find(&guests).Error; err != nil {
+		return fmt.Errorf("error checking guest registry: %v", err)
+	}
+
+	// the first arrival of a slotted reservation must have happened within its slot
+	first := len(guests) == 0
+	if first {
+		if err := s.checkSlot(&reservation, checkIn.CheckedInAt); err != nil {
+			return err
+		}
+	}
+
+	record := models.Guest{
+		ReservationID:      reservation.ID,
+		Name:               reservation.Name,
+		AccompanyingGuests: checkIn.Persons - 1,
+		TableID:            reservation.TableID,
+		CreatedAt:          checkIn.CheckedInAt,
+	}
+	if !first {
+		record = guests[0]
+		record.AccompanyingGuests += checkIn.Persons
+	}
+
+	if err := s.admit(tx, &reservation, &record); err != nil {
+		return err
+	}
+
+	// capacity rules are enforced by the model hooks
+	if first {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		if err := markArrival(tx, &reservation, record.CreatedAt); err != nil {
+			return err
+		}
+	} else if err := tx.Save(&record).Error; err != nil {
+		return err
+	}
+
+	if err := recordMovement(tx, reservation.ID, checkIn.Persons, checkIn.CheckedInAt); err != nil {
+		return err
+	}
+
+	if err := tx.Create(&synced).Error; err != nil {
+		return fmt.Errorf("error recording synced check-in: %v", err)
+	}
+
+	// the members are marked once the whole party is present, as on the check-ins online
+	if record.TotalGuests() < reservation.Guests() {
+		return nil
+	}
+	return markMembers(tx, reservation.ID, checkIn.CheckedInAt)
+}
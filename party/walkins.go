@@ -0,0 +1,153 @@
+package party
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/notify"
+	"gorm.io/gorm"
+)
+
+// WalkInName is the name of the walk-ins arriving without giving one
+const WalkInName = "Walk-in guest"
+
+// WalkIn holds a party arriving without reservation, the features its table must have and the seating constraints
+// binding it to booked reservations
+type WalkIn struct {
+	Name         string
+	Persons      int
+	Requirements models.Features
+	Constraints  []Constraint
+	Email        string
+	Phone        string
+}
+
+// SeatWalkIn seats a party arriving without reservation at the table with the fewest free seats fitting it among the
+// ones following its seating constraints. The accepted reservation of the party, marked as a walk-in, its constraints
+// and its guest are created together, so the capacity rules of the models apply to both the seats booked and the ones
+// taken. The walk-ins must be enabled in the settings of the event.
+func (s *Service) SeatWalkIn(ctx context.Context, walkIn WalkIn) (*models.Guest, error) {
+	if walkIn.Persons < 1 {
+		return nil, errorf(ErrInvalid, `invalid "%d" walk-in persons`, walkIn.Persons)
+	}
+
+	name := strings.TrimSpace(walkIn.Name)
+	if name == "" {
+		name = WalkInName
+	}
+
+	now := time.Now()
+	reservation := models.Reservation{
+		Name:               name,
+		AccompanyingGuests: walkIn.Persons - 1,
+		Requirements:       walkIn.Requirements,
+		Status:             models.StatusAccepted,
+		RespondedAt:        &now,
+		Email:              walkIn.Email,
+		Phone:              walkIn.Phone,
+		Source:             models.SourceWalkIn,
+	}
+
+	// validate model
+	if err := reservation.Validate(s.db); err != nil {
+		return nil, errorf(ErrInvalid, "error validating walk-in: %v", err)
+	}
+
+	for _, c := range walkIn.Constraints {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var record models.Guest
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		settings, err := s.eventSettings(tx)
+		if err != nil {
+			return err
+		}
+		if !settings.WalkIns {
+			return errorf(ErrWalkInsDisabled, "walk-ins are disabled")
+		}
+
+		scopes, err := s.seatingScopes(tx, walkIn.Constraints)
+		if err != nil {
+			return err
+		}
+
+		table, err := freeTable(tx, walkIn.Persons, walkIn.Requirements, scopes...)
+		if err != nil {
+			return err
+		}
+		reservation.TableID = table
+
+		// the party arrives with its reservation
+		now := time.Now()
+		reservation.ArrivedAt = &now
+
+		// capacity rules are enforced by the model hooks
+		if err := tx.Create(&reservation).Error; err != nil {
+			return queryError(err, "error creating walk-in reservation")
+		}
+
+		if _, err := addConstraints(tx, reservation.ID, walkIn.Constraints); err != nil {
+			return err
+		}
+
+		record = models.Guest{
+			ReservationID:      reservation.ID,
+			Name:               reservation.Name,
+			AccompanyingGuests: reservation.AccompanyingGuests,
+			TableID:            reservation.TableID,
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return queryError(err, "error creating guest")
+		}
+		return recordMovement(tx, reservation.ID, record.TotalGuests(), record.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, notify.CheckIn, &reservation, 0)
+
+	record.Reservation = &reservation
+	return &record, nil
+}
+
+// freeTable returns the table with the required features and the fewest free seats fitting the persons, among the
+// ones selected by the scopes. The seats of the accepted reservations whose slot has not ended are taken, by the
+// persons booked or, once their guest arrived with more persons, by the ones present.
+func freeTable(tx *gorm.DB, persons int, requirements models.Features, scopes ...func(*gorm.DB) *gorm.DB) (int, error) {
+	var tables []struct {
+		ID   int
+		Free int
+	}
+
+	err := tx.Table("tables").
+		Scopes(append(scopes, withFeatures(requirements))...).
+		Select("tables.id, tables.capacity - COALESCE(SUM(GREATEST(1 + reservations.accompanying_guests, COALESCE(1 + guests.accompanying_guests, 0))), 0) AS free").
+		Joins("LEFT JOIN reservations ON reservations.table_id = tables.id AND reservations.status = ? AND (reservations.ends_at IS NULL OR reservations.ends_at > ?)", models.StatusAccepted, time.Now()).
+		Joins("LEFT JOIN guests ON guests.reservation_id = reservations.id").
+		Group("tables.id, tables.capacity").
+		Having("free >= ?", persons).
+		Order("free, tables.id").
+		Limit(1).
+		Scan(&tables).Error
+	if err != nil {
+		return 0, queryError(err, "error finding a free table")
+	}
+
+	if len(tables) == 0 {
+		if len(scopes) > 0 {
+			return 0, errorf(ErrCapacityExceeded, "no table following the seating constraints has %d free seats", persons)
+		}
+		if len(requirements) > 0 {
+			return 0, errorf(ErrCapacityExceeded, "no %s table has %d free seats", strings.Join(requirements, ", "), persons)
+		}
+		return 0, errorf(ErrCapacityExceeded, "no table has %d free seats", persons)
+	}
+
+	return tables[0].ID, nil
+}
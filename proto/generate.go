@@ -0,0 +1,4 @@
+// Package proto holds the protobuf definitions of the gRPC services and their generated code.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative party/v1/party.proto
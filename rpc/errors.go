@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/party"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fail logs the error of the call and returns it with its code.
+// The guest names mentioned by the service errors are redacted from the log.
+func fail(ctx context.Context, code codes.Code, format string, values ...interface{}) error {
+	msg := fmt.Sprintf(format, values...)
+
+	var names []string
+	for _, value := range values {
+		if err, ok := value.(error); ok {
+			names = append(names, party.Names(err)...)
+		}
+	}
+
+	logger := logging.FromContext(ctx)
+	logged := logging.Redact(msg, names...)
+	if code == codes.Internal {
+		logger.Error(logged, zap.Stringer("code", code))
+	} else {
+		logger.Warn(logged, zap.Stringer("code", code))
+	}
+
+	return status.Error(code, msg)
+}
+
+// failService returns the error of a service operation with the code of its kind,
+// the errors of no kind are reported as internal errors
+func failService(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, party.ErrInvalid):
+		return fail(ctx, codes.InvalidArgument, "%v", err)
+	case errors.Is(err, party.ErrNotFound):
+		return fail(ctx, codes.NotFound, "%v", err)
+	case errors.Is(err, party.ErrAmbiguous), errors.Is(err, party.ErrNotAccepted), errors.Is(err, party.ErrCapacityExceeded), errors.Is(err, party.ErrUnsuitableTable), errors.Is(err, party.ErrOversizeArrival), errors.Is(err, party.ErrArrivalConflict),
+		errors.Is(err, party.ErrOutsideSlot), errors.Is(err, party.ErrInvitationExpired), errors.Is(err, party.ErrConstraintViolated):
+		return fail(ctx, codes.FailedPrecondition, "%v", err)
+	case errors.Is(err, party.ErrWalkInsDisabled):
+		return fail(ctx, codes.PermissionDenied, "%v", err)
+	case errors.Is(err, party.ErrNotificationsDisabled):
+		return fail(ctx, codes.Unavailable, "%v", err)
+	case errors.Is(err, party.ErrTicketsDisabled):
+		return fail(ctx, codes.Unimplemented, "%v", err)
+	default:
+		return fail(ctx, codes.Internal, "%v", err)
+	}
+}
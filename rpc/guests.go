@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/amaury95/GetGround-Party/models"
+	partyv1 "github.com/amaury95/GetGround-Party/proto/party/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func guestMessage(g *models.Guest) *partyv1.Guest {
+	return &partyv1.Guest{
+		Id:                 g.ID,
+		ReservationId:      g.ReservationID,
+		Name:               g.Name,
+		TableId:            int32(g.TableID),
+		AccompanyingGuests: int32(g.AccompanyingGuests),
+		ArrivedAt:          timestamppb.New(g.CreatedAt),
+	}
+}
+
+// ListGuests returns the arrived guests paginated by name
+func (h *Handler) ListGuests(ctx context.Context, req *partyv1.ListGuestsRequest) (*partyv1.ListGuestsResponse, error) {
+	p, err := newPage(req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, fail(ctx, codes.InvalidArgument, "%v", err)
+	}
+
+	elements, token, err := h.service.Guests(ctx, p)
+	if err != nil {
+		return nil, failService(ctx, err)
+	}
+
+	resp := &partyv1.ListGuestsResponse{NextPageToken: token}
+	for i := range elements {
+		resp.Guests = append(resp.Guests, guestMessage(&elements[i]))
+	}
+	return resp, nil
+}
+
+// CheckIn registers the arrival of persons of an accepted reservation at its table
+func (h *Handler) CheckIn(ctx context.Context, req *partyv1.CheckInRequest) (*partyv1.Guest, error) {
+	persons := int(req.Arriving)
+	if persons == 0 {
+		persons = 1 + int(req.AccompanyingGuests)
+	}
+
+	record, err := h.service.CheckIn(ctx, reservationRef(req.ReservationId, req.Name), persons)
+	if err != nil {
+		return nil, failService(ctx, err)
+	}
+
+	return guestMessage(record), nil
+}
+
+// CheckOut registers the departure of persons of the reservation
+func (h *Handler) CheckOut(ctx context.Context, req *partyv1.CheckOutRequest) (*partyv1.CheckOutResponse, error) {
+	if err := h.service.CheckOut(ctx, reservationRef(req.ReservationId, req.Name), int(req.Leaving)); err != nil {
+		return nil, failService(ctx, err)
+	}
+
+	return &partyv1.CheckOutResponse{}, nil
+}
@@ -0,0 +1,124 @@
+/*
+Package rpc holds the gRPC handler of the services defined in proto/party/v1, the counterpart of the api handler.
+
+Both transports run the operations of the party service, so they apply the same rules in the same transactions.
+*/
+package rpc
+
+import (
+	"github.com/amaury95/GetGround-Party/auth"
+	"github.com/amaury95/GetGround-Party/notify"
+	"github.com/amaury95/GetGround-Party/occupancy"
+	"github.com/amaury95/GetGround-Party/party"
+	partyv1 "github.com/amaury95/GetGround-Party/proto/party/v1"
+	"github.com/amaury95/GetGround-Party/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gorm.io/gorm"
+)
+
+// Handler implements the gRPC services of the party
+type Handler struct {
+	partyv1.UnimplementedTableServiceServer
+	partyv1.UnimplementedReservationServiceServer
+	partyv1.UnimplementedGuestServiceServer
+	partyv1.UnimplementedOccupancyServiceServer
+
+	db        *gorm.DB
+	notifier  notify.Notifier
+	occupancy *occupancy.Broker
+	tracer    trace.TracerProvider
+	tokens    *auth.Tokens
+	service   *party.Service
+}
+
+// WithConnection sets the given connection as context of the handler and return it
+func (h *Handler) WithConnection(conn *gorm.DB) *Handler {
+	h.db = conn
+	return h
+}
+
+// WithNotifier sets the notifier used to message the reservation holders and return the handler
+func (h *Handler) WithNotifier(notifier notify.Notifier) *Handler {
+	h.notifier = notifier
+	return h
+}
+
+// WithService sets the service running the operations of the methods and return the handler, its commit hooks
+// refresh the occupancy broker. Without it the service is built from the connection, notifier and broker of the handler.
+func (h *Handler) WithService(service *party.Service) *Handler {
+	h.service = service
+	return h
+}
+
+// WithOccupancy sets the broker streaming the occupancy changes and return the handler
+func (h *Handler) WithOccupancy(broker *occupancy.Broker) *Handler {
+	h.occupancy = broker
+	return h
+}
+
+// WithTracing sets the provider tracing the handled calls and return the handler
+func (h *Handler) WithTracing(provider trace.TracerProvider) *Handler {
+	h.tracer = provider
+	return h
+}
+
+// WithAuth sets the tokens required to call the services and return the handler.
+// Without tokens the services are open, as the api they are meant to run behind a trusted network.
+func (h *Handler) WithAuth(tokens *auth.Tokens) *Handler {
+	h.tokens = tokens
+	return h
+}
+
+type ServerConfig struct {
+	Logger      *zap.Logger
+	Credentials credentials.TransportCredentials
+}
+
+// Server returns the gRPC server with the services of the handler registered
+func (h *Handler) Server(config *ServerConfig) *grpc.Server {
+	if h.service == nil {
+		h.service = party.New(h.db).WithNotifier(h.notifier)
+		if h.occupancy != nil {
+			h.service.WithCommitHook(h.occupancy.Refresh)
+		}
+	}
+
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
+	)
+
+	// trace the calls, continuing the trace of the W3C traceparent metadata
+	if h.tracer != nil {
+		opts := []otelgrpc.Option{otelgrpc.WithTracerProvider(h.tracer), otelgrpc.WithPropagators(tracing.Propagator())}
+		unary = append(unary, otelgrpc.UnaryServerInterceptor(opts...))
+		stream = append(stream, otelgrpc.StreamServerInterceptor(opts...))
+	}
+
+	if config.Logger != nil {
+		unary = append(unary, unaryLogger(config.Logger))
+		stream = append(stream, streamLogger(config.Logger))
+	}
+
+	unary = append(unary, h.authorizeUnary)
+	stream = append(stream, h.authorizeStream)
+
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...)}
+	if config.Credentials != nil {
+		opts = append(opts, grpc.Creds(config.Credentials))
+	}
+
+	s := grpc.NewServer(opts...)
+	partyv1.RegisterTableServiceServer(s, h)
+	partyv1.RegisterReservationServiceServer(s, h)
+	partyv1.RegisterGuestServiceServer(s, h)
+	if h.occupancy != nil {
+		partyv1.RegisterOccupancyServiceServer(s, h)
+	}
+
+	return s
+}
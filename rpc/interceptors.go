@@ -0,0 +1,141 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/auth"
+	"github.com/amaury95/GetGround-Party/logging"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// permissions are the permissions required by the methods, the same granted to the routes of the api
+var permissions = map[string]auth.Permission{
+	"/party.v1.TableService/ListTables":              auth.PermRead,
+	"/party.v1.TableService/CreateTable":             auth.PermManage,
+	"/party.v1.ReservationService/ListReservations":  auth.PermRead,
+	"/party.v1.ReservationService/GetReservation":    auth.PermRead,
+	"/party.v1.ReservationService/CreateReservation": auth.PermManage,
+	"/party.v1.ReservationService/CancelReservation": auth.PermManage,
+	"/party.v1.GuestService/ListGuests":              auth.PermRead,
+	"/party.v1.GuestService/CheckIn":                 auth.PermCheckIn,
+	"/party.v1.GuestService/CheckOut":                auth.PermCheckIn,
+	"/party.v1.OccupancyService/GetOccupancy":        auth.PermRead,
+	"/party.v1.OccupancyService/WatchOccupancy":      auth.PermRead,
+}
+
+// authorize checks the bearer token of the authorization metadata grants the permission of the method.
+// Every call is allowed when authentication is disabled.
+func (h *Handler) authorize(ctx context.Context, method string) error {
+	if h.tokens == nil {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	value := strings.TrimPrefix(values[0], "Bearer ")
+	if value == values[0] {
+		return status.Error(codes.Unauthenticated, "expected a bearer token")
+	}
+
+	token, ok := h.tokens.Lookup(value)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	permission, ok := permissions[method]
+	if !ok || !token.Role.Allows(permission) {
+		return status.Errorf(codes.PermissionDenied, `role "%s" is not allowed to %s`, token.Role, permission)
+	}
+
+	return nil
+}
+
+func (h *Handler) authorizeUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := h.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (h *Handler) authorizeStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := h.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// callLogger returns the logger of the call, carrying its request id and trace id
+func callLogger(ctx context.Context, base *zap.Logger, method string) *zap.Logger {
+	id := logging.NewRequestID()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(strings.ToLower(logging.RequestIDHeader)); len(values) > 0 && values[0] != "" && len(values[0]) <= 128 {
+			id = values[0]
+		}
+	}
+
+	logger := base.With(zap.String("request_id", id), zap.String("method", method))
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		logger = logger.With(zap.String("trace_id", span.TraceID().String()))
+	}
+	return logger
+}
+
+// logCall logs the outcome of the call, at error level for the internal errors and warn for the client errors
+func logCall(logger *zap.Logger, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := zapcore.InfoLevel
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = zapcore.ErrorLevel
+	default:
+		level = zapcore.WarnLevel
+	}
+
+	if ce := logger.Check(level, "call handled"); ce != nil {
+		ce.Write(zap.Stringer("code", code), zap.Duration("latency", time.Since(start)))
+	}
+}
+
+func unaryLogger(base *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		logger := callLogger(ctx, base, info.FullMethod)
+
+		resp, err := handler(logging.NewContext(ctx, logger), req)
+		logCall(logger, start, err)
+		return resp, err
+	}
+}
+
+func streamLogger(base *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		logger := callLogger(ss.Context(), base, info.FullMethod)
+
+		err := handler(srv, &loggedStream{ServerStream: ss, ctx: logging.NewContext(ss.Context(), logger)})
+		logCall(logger, start, err)
+		return err
+	}
+}
+
+// loggedStream carries the logger of the call in the context of the stream
+type loggedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *loggedStream) Context() context.Context { return s.ctx }
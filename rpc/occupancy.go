@@ -0,0 +1,45 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/amaury95/GetGround-Party/occupancy"
+	partyv1 "github.com/amaury95/GetGround-Party/proto/party/v1"
+)
+
+func occupancyMessage(o occupancy.Occupancy) *partyv1.Occupancy {
+	msg := &partyv1.Occupancy{
+		Capacity:   int32(o.Capacity),
+		Booked:     int32(o.Booked),
+		Present:    int32(o.Present),
+		SeatsEmpty: int32(o.SeatsEmpty),
+	}
+	for _, t := range o.Tables {
+		msg.Tables = append(msg.Tables, &partyv1.TableOccupancy{
+			TableId:    int32(t.ID),
+			Capacity:   int32(t.Capacity),
+			Present:    int32(t.Present),
+			SeatsEmpty: int32(t.SeatsEmpty),
+		})
+	}
+	return msg
+}
+
+// GetOccupancy returns the current occupancy of the party
+func (h *Handler) GetOccupancy(ctx context.Context, req *partyv1.GetOccupancyRequest) (*partyv1.Occupancy, error) {
+	o, err := h.service.Occupancy(ctx)
+	if err != nil {
+		return nil, failService(ctx, err)
+	}
+	return occupancyMessage(o), nil
+}
+
+// WatchOccupancy streams the current occupancy, then every change of it until the client cancels the call
+func (h *Handler) WatchOccupancy(req *partyv1.WatchOccupancyRequest, stream partyv1.OccupancyService_WatchOccupancyServer) error {
+	for o := range h.occupancy.Subscribe(stream.Context()) {
+		if err := stream.Send(occupancyMessage(o)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
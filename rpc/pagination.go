@@ -0,0 +1,22 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/amaury95/GetGround-Party/party"
+)
+
+// MaxPageSize is the largest page the list methods return, the same of the api
+const MaxPageSize = party.MaxPageSize
+
+// newPage reads the keyset pagination of a list method, the page token is the key of the last element of the
+// previous page. Lists are returned whole when no page size is given.
+func newPage(size int32, token string) (party.Page, error) {
+	if size == 0 && token == "" {
+		return party.Page{}, nil
+	}
+	if size <= 0 || size > MaxPageSize {
+		return party.Page{}, fmt.Errorf(`invalid "%d" page size, expected a value between 1 and %d`, size, MaxPageSize)
+	}
+	return party.Page{Limit: int(size), After: token}, nil
+}
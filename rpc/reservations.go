@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/party"
+	partyv1 "github.com/amaury95/GetGround-Party/proto/party/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// reservationRef references the reservation by id when it is set, by name otherwise
+func reservationRef(id, name string) party.Ref {
+	if id != "" {
+		return party.ByID(id)
+	}
+	return party.ByName(name)
+}
+
+func reservationMessage(r *models.Reservation) *partyv1.Reservation {
+	msg := &partyv1.Reservation{
+		Id:                 r.ID,
+		Name:               r.Name,
+		TableId:            int32(r.TableID),
+		AccompanyingGuests: int32(r.AccompanyingGuests),
+		Status:             r.Status,
+		Email:              r.Email,
+		Phone:              r.Phone,
+	}
+	if r.RespondBy != nil {
+		msg.RespondBy = timestamppb.New(*r.RespondBy)
+	}
+	return msg
+}
+
+// ListReservations returns the guest list paginated by name, optionally filtered by the invitation status
+func (h *Handler) ListReservations(ctx context.Context, req *partyv1.ListReservationsRequest) (*partyv1.ListReservationsResponse, error) {
+	p, err := newPage(req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, fail(ctx, codes.InvalidArgument, "%v", err)
+	}
+
+	elements, token, err := h.service.Reservations(ctx, req.Status, p)
+	if err != nil {
+		return nil, failService(ctx, err)
+	}
+
+	resp := &partyv1.ListReservationsResponse{NextPageToken: token}
+	for i := range elements {
+		resp.Reservations = append(resp.Reservations, reservationMessage(&elements[i]))
+	}
+	return resp, nil
+}
+
+// GetReservation returns the reservation with the given id, or held by the given name
+func (h *Handler) GetReservation(ctx context.Context, req *partyv1.GetReservationRequest) (*partyv1.Reservation, error) {
+	record, err := h.service.Reservation(ctx, reservationRef(req.Id, req.Name))
+	if err != nil {
+		return nil, failService(ctx, err)
+	}
+
+	return reservationMessage(record), nil
+}
+
+// CreateReservation invites the guest and notifies the reservation holder, returning the rsvp token of the invitation
+func (h *Handler) CreateReservation(ctx context.Context, req *partyv1.CreateReservationRequest) (*partyv1.CreateReservationResponse, error) {
+	booking := party.Booking{
+		Name:               req.Name,
+		Table:              int(req.TableId),
+		AccompanyingGuests: int(req.AccompanyingGuests),
+		Email:              req.Email,
+		Phone:              req.Phone,
+	}
+	if req.RespondBy != nil {
+		respondBy := req.RespondBy.AsTime()
+		booking.RespondBy = &respondBy
+	}
+
+	record, err := h.service.BookReservation(ctx, booking)
+	if err != nil {
+		return nil, failService(ctx, err)
+	}
+
+	return &partyv1.CreateReservationResponse{Reservation: reservationMessage(record), RsvpToken: record.Token}, nil
+}
+
+// CancelReservation removes the reservation from the guest list and notifies the holder
+func (h *Handler) CancelReservation(ctx context.Context, req *partyv1.CancelReservationRequest) (*partyv1.CancelReservationResponse, error) {
+	if _, err := h.service.CancelReservation(ctx, reservationRef(req.Id, req.Name)); err != nil {
+		return nil, failService(ctx, err)
+	}
+
+	return &partyv1.CancelReservationResponse{}, nil
+}
@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/party"
+	partyv1 "github.com/amaury95/GetGround-Party/proto/party/v1"
+	"google.golang.org/grpc/codes"
+)
+
+func tableMessage(t *models.Table) *partyv1.Table {
+	return &partyv1.Table{Id: int32(t.ID), Capacity: int32(t.Capacity), Label: t.Label, Zone: t.Zone, Features: t.Features}
+}
+
+// ListTables returns the tables paginated by id
+func (h *Handler) ListTables(ctx context.Context, req *partyv1.ListTablesRequest) (*partyv1.ListTablesResponse, error) {
+	p, err := newPage(req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, fail(ctx, codes.InvalidArgument, "%v", err)
+	}
+
+	filter := party.TableFilter{Zone: req.Zone, Features: req.Features, MinCapacity: int(req.MinCapacity)}
+	elements, token, err := h.service.Tables(ctx, filter, p)
+	if err != nil {
+		return nil, failService(ctx, err)
+	}
+
+	resp := &partyv1.ListTablesResponse{NextPageToken: token}
+	for i := range elements {
+		resp.Tables = append(resp.Tables, tableMessage(&elements[i]))
+	}
+	return resp, nil
+}
+
+// CreateTable adds a table with the given capacity
+func (h *Handler) CreateTable(ctx context.Context, req *partyv1.CreateTableRequest) (*partyv1.Table, error) {
+	record, err := h.service.AddTable(ctx, party.TableSpec{
+		Label:    req.Label,
+		Zone:     req.Zone,
+		Capacity: int(req.Capacity),
+		Features: req.Features,
+	})
+	if err != nil {
+		return nil, failService(ctx, err)
+	}
+
+	return tableMessage(record), nil
+}
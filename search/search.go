@@ -0,0 +1,209 @@
+/*
+Package search holds the fuzzy matching of the guest names: their normalization, the trigrams indexing them and the
+ranking of the names found through the index.
+
+Names are compared case and accent insensitive, "José Núñez" is normalized as "jose nunez". Each word is indexed by its
+trigrams padded with two leading spaces and a trailing one ("  j", " jo", "jos", "ose", "se "), so a query shares all its
+trigrams with the words it is a prefix of, and most of them with the names it misspells.
+*/
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MinScore is the similarity below which the names matching neither the query nor its prefix are discarded
+const MinScore = 0.3
+
+// Normalize lowercases the name, strips its accents and reduces it to its words separated by single spaces
+func Normalize(name string) string {
+	// transformers keep state, they can not be shared between goroutines
+	fold := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+	folded, _, err := transform.String(fold, name)
+	if err != nil {
+		folded = name
+	}
+
+	var b strings.Builder
+	separated := true
+	for _, r := range strings.ToLower(folded) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			separated = false
+			continue
+		}
+
+		if !separated {
+			b.WriteByte(' ')
+			separated = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), " ")
+}
+
+// Trigrams returns the distinct trigrams indexing the name
+func Trigrams(name string) []string {
+	return trigrams(Normalize(name), false)
+}
+
+// QueryTrigrams returns the distinct trigrams of the query, the last word is not closed so it matches
+// the words it is a prefix of
+func QueryTrigrams(query string) []string {
+	return trigrams(Normalize(query), true)
+}
+
+func trigrams(normalized string, open bool) []string {
+	words := strings.Fields(normalized)
+
+	seen := make(map[string]bool)
+	var out []string
+	for i, word := range words {
+		padded := []rune("  " + word + " ")
+		if open && i == len(words)-1 {
+			padded = padded[:len(padded)-1]
+		}
+
+		for j := 0; j+3 <= len(padded); j++ {
+			if t := string(padded[j : j+3]); !seen[t] {
+				seen[t] = true
+				out = append(out, t)
+			}
+		}
+	}
+
+	return out
+}
+
+// Distance returns the edit distance between the two strings, in runes
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// Candidate is a name found through the index with the amount of trigrams it shares with the query,
+// ID identifies the record holding the name as names may be shared
+type Candidate struct {
+	ID   string
+	Name string
+	Hits int
+}
+
+// Result is a name ranked by its similarity with the query, between 0 and 1
+type Result struct {
+	ID    string
+	Name  string
+	Score float64
+}
+
+// matches of a name with the query, from the best
+const (
+	matchExact = iota
+	matchPrefix
+	matchWordPrefix
+	matchFuzzy
+)
+
+// Rank orders the candidates by how well they match the query: the exact matches first, then the names and the
+// words starting by the query, then by similarity and edit distance. Fuzzy matches scoring below MinScore are discarded.
+func Rank(query string, candidates []Candidate) []Result {
+	q := Normalize(query)
+	total := len(QueryTrigrams(query))
+	if q == "" || total == 0 {
+		return nil
+	}
+
+	type ranked struct {
+		Result
+		match    int
+		distance int
+	}
+
+	var results []ranked
+	for _, c := range candidates {
+		name := Normalize(c.Name)
+
+		r := ranked{
+			Result:   Result{ID: c.ID, Name: c.Name, Score: float64(c.Hits) / float64(total)},
+			match:    matchFuzzy,
+			distance: Distance(q, name),
+		}
+
+		switch {
+		case name == q:
+			r.match, r.Score = matchExact, 1
+		case strings.HasPrefix(name, q):
+			r.match = matchPrefix
+		case strings.Contains(" "+name, " "+q):
+			r.match = matchWordPrefix
+		}
+
+		if r.Score > 1 {
+			r.Score = 1
+		}
+
+		if r.match == matchFuzzy && r.Score < MinScore {
+			continue
+		}
+
+		results = append(results, r)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch {
+		case a.match != b.match:
+			return a.match < b.match
+		case a.Score != b.Score:
+			return a.Score > b.Score
+		case a.distance != b.distance:
+			return a.distance < b.distance
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		default:
+			return a.ID < b.ID
+		}
+	})
+
+	out := make([]Result, len(results))
+	for i, r := range results {
+		out[i] = r.Result
+	}
+	return out
+}
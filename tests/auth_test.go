@@ -0,0 +1,172 @@
+package tests_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/auth"
+	"github.com/amaury95/GetGround-Party/client"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Auth", func() {
+	const (
+		adminToken  = "admin-secret"
+		viewerToken = "viewer-secret"
+	)
+
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		expect *httpexpect.Expect
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		tokens, err := auth.NewTokens(
+			auth.Token{Name: "door", SHA256: auth.Hash(adminToken), Role: auth.RoleAdmin},
+			auth.Token{Name: "lobby", SHA256: auth.Hash(viewerToken), Role: auth.RoleViewer},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection and the tokens
+		handler := new(api.Handler).WithConnection(gdb).WithAuth(tokens)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		expect = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("rejects the requests without token", func() {
+		expect.GET(`/tables`).
+			Expect().Status(http.StatusUnauthorized).
+			Header(api.ErrorCodeHeader).Equal(api.CodeUnauthorized)
+	})
+
+	It("rejects the unknown tokens", func() {
+		expect.GET(`/tables`).WithHeader("Authorization", "Bearer unknown").
+			Expect().Status(http.StatusUnauthorized).
+			Header(api.ErrorCodeHeader).Equal(api.CodeUnauthorized)
+	})
+
+	It("allows the reads of the viewer tokens", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 5))
+		mock.ExpectCommit()
+
+		expect.GET(`/tables`).WithHeader("Authorization", "Bearer "+viewerToken).
+			Expect().Status(http.StatusOK).
+			JSON().Array().Length().Equal(1)
+	})
+
+	It("forbids the writes of the viewer tokens", func() {
+		expect.POST(`/tables`).WithHeader("Authorization", "Bearer "+viewerToken).
+			WithJSON(api.CreateTableRequest{Capacity: 4}).
+			Expect().Status(http.StatusForbidden).
+			Header(api.ErrorCodeHeader).Equal(api.CodeForbidden)
+	})
+
+	It("forbids the walk-ins of the viewer tokens", func() {
+		expect.POST(`/walk_ins`).WithHeader("Authorization", "Bearer "+viewerToken).
+			WithJSON(api.CreateWalkInRequest{Persons: 2}).
+			Expect().Status(http.StatusForbidden).
+			Header(api.ErrorCodeHeader).Equal(api.CodeForbidden)
+	})
+
+	It("keeps the invitation answers public", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("secret").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		expect.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: "accept"}).
+			Expect().Status(http.StatusNotFound)
+	})
+
+	It("authenticates the clients of a profile", func() {
+		dir, err := ioutil.TempDir("", "profiles")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "profiles.yaml")
+		Expect(ioutil.WriteFile(path, []byte(`
+default: door
+profiles:
+  door:
+    server: `+server.URL+`
+    token: `+adminToken+`
+  lobby:
+    server: `+server.URL+`
+    token: `+viewerToken+`
+`), 0600)).To(Succeed())
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 4, "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		profile, err := client.LoadProfile(path, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profile.Name).To(Equal("door"))
+
+		door, err := profile.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = door.CreateTable(context.Background(), 4)
+		Expect(err).NotTo(HaveOccurred())
+
+		profile, err = client.LoadProfile(path, "lobby")
+		Expect(err).NotTo(HaveOccurred())
+
+		lobby, err := profile.Client()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = lobby.CreateTable(context.Background(), 4)
+		Expect(errors.Is(err, client.ErrForbidden)).To(BeTrue())
+	})
+})
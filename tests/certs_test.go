@@ -0,0 +1,128 @@
+package tests_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/amaury95/GetGround-Party/certs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLS certificates", func() {
+	var (
+		dir    string
+		server *httptest.Server
+	)
+
+	// client returns an http client trusting the development ca and presenting the given certificates
+	client := func(certificates ...tls.Certificate) *http.Client {
+		ca, err := ioutil.ReadFile(filepath.Join(dir, certs.CAFile))
+		Expect(err).NotTo(HaveOccurred())
+
+		pool := x509.NewCertPool()
+		Expect(pool.AppendCertsFromPEM(ca)).To(BeTrue())
+
+		return &http.Client{Transport: &http.Transport{
+			ForceAttemptHTTP2: true,
+			TLSClientConfig: &tls.Config{
+				RootCAs:      pool,
+				Certificates: certificates,
+			},
+		}}
+	}
+
+	serve := func(clientCA string) *certs.Reloader {
+		reloader, err := certs.NewReloader(filepath.Join(dir, certs.ServerFile), filepath.Join(dir, certs.ServerKeyFile), clientCA)
+		Expect(err).NotTo(HaveOccurred())
+
+		server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		server.EnableHTTP2 = true
+		server.TLS = reloader.TLSConfig()
+		server.StartTLS()
+
+		return reloader
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "party-certs")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(certs.GenerateDev(dir, []string{"127.0.0.1"})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			server.Close()
+		}
+		os.RemoveAll(dir)
+	})
+
+	It("serves the generated certificate", func() {
+		serve("")
+
+		resp, err := client().Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+	})
+
+	It("requires a client certificate signed by the ca on mutual tls", func() {
+		serve(filepath.Join(dir, certs.CAFile))
+
+		_, err := client().Get(server.URL)
+		Expect(err).To(HaveOccurred())
+
+		cert, err := tls.LoadX509KeyPair(filepath.Join(dir, certs.ClientFile), filepath.Join(dir, certs.ClientKeyFile))
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := client(cert).Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+	})
+
+	It("negotiates http/2 with the certificates resolved on the handshake", func() {
+		serve(filepath.Join(dir, certs.CAFile))
+
+		cert, err := tls.LoadX509KeyPair(filepath.Join(dir, certs.ClientFile), filepath.Join(dir, certs.ClientKeyFile))
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := client(cert).Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ProtoMajor).To(Equal(2))
+	})
+
+	It("serves the new certificates after a reload", func() {
+		reloader := serve("")
+
+		// rotate every certificate, including the ca
+		Expect(certs.GenerateDev(dir, []string{"127.0.0.1"})).To(Succeed())
+
+		_, err := client().Get(server.URL)
+		Expect(err).To(HaveOccurred())
+
+		Expect(reloader.Reload()).To(Succeed())
+
+		resp, err := client().Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+	})
+
+	It("keeps the current certificates when the reload fails", func() {
+		reloader := serve("")
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, certs.ServerFile), []byte("broken"), 0644)).To(Succeed())
+		Expect(reloader.Reload()).NotTo(Succeed())
+
+		resp, err := client().Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+	})
+})
@@ -0,0 +1,271 @@
+package tests_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/client"
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		raw    *httpexpect.Expect
+		party  *client.Client
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup the typed client and a raw client for the protocol details
+		party, err = client.New(server.URL, client.WithRetries(2, time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+
+		raw = httpexpect.New(GinkgoT(), server.URL)
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("creates a table", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 4, "").
+			WillReturnResult(sqlmock.NewResult(7, 1))
+
+		mock.ExpectCommit()
+
+		table, err := party.CreateTable(ctx, 4)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(table.ID).To(Equal(7))
+		Expect(table.Capacity).To(Equal(4))
+	})
+
+	It("iterates over the pages of the tables", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` ORDER BY id LIMIT 3")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4).AddRow(2, 6).AddRow(3, 8))
+		mock.ExpectCommit()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE id > ? ORDER BY id LIMIT 3")).WithArgs("2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(3, 8))
+		mock.ExpectCommit()
+
+		var tables []models.Table
+		it := party.Tables(client.TableListOptions{ListOptions: client.ListOptions{PageSize: 2}})
+		for it.Next(ctx) {
+			tables = append(tables, it.Table())
+		}
+
+		Expect(it.Err()).NotTo(HaveOccurred())
+		Expect(tables).To(HaveLen(3))
+		Expect(tables[2].ID).To(Equal(3))
+	})
+
+	It("filters the guest list while iterating", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE status = ? ORDER BY name,id LIMIT 101")).WithArgs("declined").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status"}).AddRow("username", 1, 1, "declined"))
+		mock.ExpectCommit()
+
+		it := party.Reservations(client.ReservationListOptions{Status: models.StatusDeclined})
+		Expect(it.Next(ctx)).To(BeTrue())
+		Expect(it.Reservation().Name).To(Equal("username"))
+		Expect(it.Next(ctx)).To(BeFalse())
+		Expect(it.Err()).NotTo(HaveOccurred())
+	})
+
+	It("returns typed errors matching the server codes", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		_, err := party.CheckIn(ctx, "username", 2)
+		Expect(errors.Is(err, client.ErrNotFound)).To(BeTrue())
+
+		var apiErr *client.Error
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+		Expect(apiErr.Status).To(Equal(http.StatusNotFound))
+
+		_, err = party.CreateTable(ctx, 0)
+		Expect(errors.Is(err, client.ErrInvalidRequest)).To(BeTrue())
+	})
+
+	It("returns the candidates of the shared names", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("Maria Garcia").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).
+				AddRow("reservation-1", "Maria Garcia", 1, 1, "accepted").
+				AddRow("reservation-2", "Maria Garcia", 0, 3, "accepted"))
+		mock.ExpectRollback()
+
+		_, err := party.CheckIn(ctx, "Maria Garcia", 1)
+		Expect(errors.Is(err, client.ErrAmbiguousName)).To(BeTrue())
+
+		var apiErr *client.Error
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+		Expect(apiErr.Candidates).To(HaveLen(2))
+		Expect(apiErr.Message).To(ContainSubstring("reservation-1, reservation-2"))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-2", "Maria Garcia", 0, 3, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `members` WHERE reservation_id = ? ORDER BY position")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "dietary"}).AddRow("member-1", "reservation-2", "Maria Garcia", "vegan"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-2", 0, 3))
+		mock.ExpectCommit()
+
+		reservation, err := party.Reservation(ctx, apiErr.Candidates[1].ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reservation.TableID).To(Equal(3))
+		Expect(reservation.Members).To(HaveLen(1))
+		Expect(reservation.Members[0].Dietary).To(Equal("vegan"))
+		Expect(reservation.Arrivals).To(Equal(&models.Arrivals{Arrived: 1, Expected: 1}))
+	})
+
+	It("retries the writes failed by the server", func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 4, "").
+			WillReturnError(errors.New("deadlock found"))
+		mock.ExpectRollback()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 4, "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		table, err := party.CreateTable(ctx, 4)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(table.ID).To(Equal(1))
+	})
+
+	It("does not retry the rejected requests", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status"}).AddRow("username", 1, 1, "invited"))
+		mock.ExpectRollback()
+
+		_, err := party.CheckIn(ctx, "username", 1)
+		Expect(errors.Is(err, client.ErrNotAccepted)).To(BeTrue())
+	})
+
+	It("does not retry the requests of a disabled feature", func() {
+		// count the requests reaching the api
+		hits := 0
+		counted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			server.Config.Handler.ServeHTTP(w, r)
+		}))
+		defer counted.Close()
+
+		party, err := client.New(counted.URL, client.WithRetries(2, time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+
+		err = party.RemindReservation(ctx, "username")
+		Expect(errors.Is(err, client.ErrNotificationsDisabled)).To(BeTrue())
+		Expect(hits).To(Equal(1))
+	})
+
+	It("replays the writes sent again with the same idempotency key", func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 4, "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		raw.POST(`/tables`).WithHeader(api.IdempotencyKeyHeader, "create-table-1").WithJSON(api.CreateTableRequest{Capacity: 4}).
+			Expect().Status(http.StatusCreated).
+			JSON().Object().ValueEqual("id", 1)
+
+		raw.POST(`/tables`).WithHeader(api.IdempotencyKeyHeader, "create-table-1").WithJSON(api.CreateTableRequest{Capacity: 4}).
+			Expect().Status(http.StatusCreated).
+			Header(api.IdempotentReplayHeader).Equal("true")
+
+		raw.POST(`/tables`).WithHeader(api.IdempotencyKeyHeader, "create-table-1").WithJSON(api.CreateTableRequest{Capacity: 8}).
+			Expect().Status(http.StatusUnprocessableEntity).
+			Header(api.ErrorCodeHeader).Equal(api.CodeIdempotencyConflict)
+
+		// the query string is part of the request
+		raw.POST(`/tables`).WithQuery("dry_run", "true").WithHeader(api.IdempotencyKeyHeader, "create-table-1").WithJSON(api.CreateTableRequest{Capacity: 4}).
+			Expect().Status(http.StatusUnprocessableEntity).
+			Header(api.ErrorCodeHeader).Equal(api.CodeIdempotencyConflict)
+	})
+
+	It("releases the idempotency key of a write whose handler panicked", func() {
+		// a handler without connection panics on the writes
+		broken := httptest.NewServer(new(api.Handler).Router(&api.RouterConfig{ReleaseMode: true}))
+		defer broken.Close()
+
+		failing := httpexpect.New(GinkgoT(), broken.URL)
+		for i := 0; i < 2; i++ {
+			failing.POST(`/tables`).WithHeader(api.IdempotencyKeyHeader, "create-table-1").WithJSON(api.CreateTableRequest{Capacity: 4}).
+				Expect().Status(http.StatusInternalServerError)
+		}
+	})
+
+	It("rejects the writes with an idempotency key and a body too large to fingerprint", func() {
+		raw.POST(`/tables`).WithHeader(api.IdempotencyKeyHeader, "create-table-1").
+			WithBytes(bytes.Repeat([]byte(" "), api.IdempotencyMaxBody+1)).
+			Expect().Status(http.StatusRequestEntityTooLarge).
+			Header(api.ErrorCodeHeader).Equal(api.CodeInvalidBody)
+	})
+
+	It("stops on cancelled contexts", func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := party.SeatsEmpty(cancelled)
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+	})
+})
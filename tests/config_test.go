@@ -0,0 +1,211 @@
+package tests_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/akamensky/argparse"
+	"github.com/amaury95/GetGround-Party/config"
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/go-sql-driver/mysql"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Configuration", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "party-config")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	// parse registers the settings flags and parses the given arguments
+	parse := func(args ...string) *config.Flags {
+		parser := argparse.NewParser("party", "")
+		flags := config.RegisterFlags(&parser.Command)
+		Expect(parser.Parse(append([]string{"party"}, args...))).To(Succeed())
+		return flags
+	}
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	It("uses the defaults without sources", func() {
+		cfg, err := config.Load(parse(), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.Server.Port).To(Equal(3033))
+		Expect(cfg.Database.Password).To(BeEmpty())
+		Expect(cfg.Report()).To(ContainElement(`server.port = 3033 (default)`))
+	})
+
+	It("layers the file, the environment and the flags in order", func() {
+		path := write("party.yaml", `
+server:
+  port: 4000
+  read_timeout: 5s
+database:
+  username: file
+  password: file-secret
+`)
+
+		cfg, err := config.Load(parse("--config", path, "--username", "flag"), []string{
+			"PARTY_DATABASE_USERNAME=env",
+			"PARTY_DATABASE_PASSWORD=env-secret",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.Server.Port).To(Equal(4000))
+		Expect(cfg.Server.ReadTimeout).To(Equal(5 * time.Second))
+		Expect(cfg.Database.Password).To(Equal("env-secret"))
+		Expect(cfg.Database.Username).To(Equal("flag"))
+
+		Expect(cfg.Report()).To(ContainElements(
+			`server.port = 4000 (file `+path+`)`,
+			`database.password = ******** (env PARTY_DATABASE_PASSWORD)`,
+			`database.username = "flag" (flag --username)`,
+		))
+	})
+
+	It("reads the config file path from the environment", func() {
+		path := write("party.toml", `
+[database]
+max_open_conns = 10
+
+[notifications]
+transport = "log"
+`)
+
+		cfg, err := config.Load(parse(), []string{"PARTY_CONFIG=" + path})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.Database.MaxOpenConns).To(Equal(10))
+		Expect(cfg.Notifications.Transport).To(Equal("log"))
+	})
+
+	It("keeps the defaults of the settings left empty in the config file", func() {
+		path := write("party.yaml", `
+server:
+  port:
+database:
+  name: ~
+  password: null
+`)
+
+		cfg, err := config.Load(parse("--config", path), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.Server.Port).To(Equal(3033))
+		Expect(cfg.Database.Name).To(Equal("party"))
+		Expect(cfg.Database.Password).To(BeEmpty())
+		Expect(cfg.Report()).To(ContainElement(`server.port = 3033 (default)`))
+	})
+
+	It("escapes the database credentials in the data source name", func() {
+		cfg, err := config.Load(parse("--username", "party", "--password", "p@ss/w?rd"), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		dsn, err := mysql.ParseDSN(cfg.Database.DSN())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dsn.User).To(Equal("party"))
+		Expect(dsn.Passwd).To(Equal("p@ss/w?rd"))
+		Expect(dsn.DBName).To(Equal("party"))
+		Expect(dsn.ParseTime).To(BeTrue())
+	})
+
+	It("fails on values of the wrong type", func() {
+		_, err := config.Load(parse("--port", "abc"), nil)
+		Expect(err).To(MatchError(ContainSubstring("expects an integer")))
+
+		_, err = config.Load(parse(), []string{"PARTY_SERVER_READ_TIMEOUT=soon"})
+		Expect(err).To(MatchError(ContainSubstring("expects a duration")))
+	})
+
+	It("fails on unknown settings in the config file", func() {
+		path := write("party.yaml", "server:\n  prot: 4000\n")
+
+		_, err := config.Load(parse("--config", path), nil)
+		Expect(err).To(MatchError(ContainSubstring(`unknown setting "server.prot"`)))
+	})
+
+	It("fails on invalid values", func() {
+		_, err := config.Load(parse("--notifier", "pigeon"), nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("feeds the router configuration", func() {
+		cfg, err := config.Load(parse("--health-checks", "false"), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.RouterConfig(nil).HealthChecks).To(BeFalse())
+		Expect(cfg.RouterConfig(nil).ReleaseMode).To(BeTrue())
+	})
+
+	It("loads the tracing exporter", func() {
+		cfg, err := config.Load(parse("--tracing-exporter", "otlp", "--tracing-sample-ratio", "0.25"), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.TracingConfig().Enabled()).To(BeTrue())
+		Expect(cfg.Tracing.SampleRatio).To(Equal(0.25))
+
+		_, err = config.Load(parse("--tracing-sample-ratio", "2"), nil)
+		Expect(err).To(MatchError(ContainSubstring("sample ratio")))
+	})
+
+	It("loads the adjacent zones of the seating plan", func() {
+		cfg, err := config.Load(parse(), []string{"PARTY_SEATING_ADJACENT_ZONES=garden:terrace, terrace:hall"})
+		Expect(err).NotTo(HaveOccurred())
+
+		zones, err := party.ParseAdjacentZones(cfg.Seating.AdjacentZones)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(zones.Adjacent("hall", "terrace")).To(BeTrue())
+		Expect(zones.Adjacent("garden", "hall")).To(BeFalse())
+
+		_, err = config.Load(parse("--adjacent-zones", "garden"), nil)
+		Expect(err).To(MatchError(ContainSubstring("adjacent zones")))
+	})
+
+	It("loads the grace periods of the arrivals", func() {
+		cfg, err := config.Load(parse("--late-grace", "30m"), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.Arrivals.EarlyGrace).To(Equal(15 * time.Minute))
+		Expect(cfg.Arrivals.LateGrace).To(Equal(30 * time.Minute))
+
+		_, err = config.Load(parse(), []string{"PARTY_ARRIVALS_EARLY_GRACE=-5m"})
+		Expect(err).To(MatchError(ContainSubstring("grace periods")))
+	})
+
+	It("loads the validity of the tickets", func() {
+		cfg, err := config.Load(parse("--ticket-ttl", "48h"), []string{"PARTY_TICKETS_CLOCK_SKEW=1m"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.Tickets.TTL).To(Equal(48 * time.Hour))
+		Expect(cfg.Tickets.ClockSkew).To(Equal(time.Minute))
+
+		_, err = config.Load(parse("--ticket-ttl", "0s"), nil)
+		Expect(err).To(MatchError(ContainSubstring("validity of the tickets")))
+	})
+
+	It("loads the log level and format", func() {
+		cfg, err := config.Load(parse("--log-level", "debug", "--log-redact-names", "true"), []string{"PARTY_LOG_FORMAT=console"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cfg.Logging()).To(Equal(logging.Config{Level: "debug", Format: "console", RedactNames: true}))
+
+		_, err = config.Load(parse("--log-level", "loud"), nil)
+		Expect(err).To(MatchError(ContainSubstring(`invalid "loud" log level`)))
+	})
+})
@@ -0,0 +1,270 @@
+package tests_test
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"regexp"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/client"
+	"github.com/amaury95/GetGround-Party/door"
+	"github.com/amaury95/GetGround-Party/models"
+	tea "github.com/charmbracelet/bubbletea"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Door", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		screen tea.Model
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		party, err := client.New(server.URL, client.WithRetries(0, time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+
+		screen = door.New(party, door.Config{})
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	// expectSync mocks the fetch of the party state with the given guests, each list read in its own transaction
+	expectSync := func(guests *sqlmock.Rows) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE status = ? ORDER BY name,id LIMIT 101")).WithArgs("accepted").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).
+				AddRow("reservation-1", "Jane Cooper", 1, 1, "accepted").
+				AddRow("reservation-2", "John Smith", 2, 2, "accepted").
+				AddRow("reservation-3", "Mary Johnson", 0, 2, "accepted"))
+		mock.ExpectCommit()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` ORDER BY name,id LIMIT 101")).
+			WillReturnRows(guests)
+		mock.ExpectCommit()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` ORDER BY id LIMIT 101")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4).AddRow(2, 6))
+		mock.ExpectCommit()
+	}
+
+	// update feeds the message to the screen and returns the command it asked to run
+	update := func(msg tea.Msg) tea.Cmd {
+		var cmd tea.Cmd
+		screen, cmd = screen.Update(msg)
+		return cmd
+	}
+
+	typing := func(text string) tea.Msg {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)}
+	}
+
+	It("shows the guest list and the seats empty", func() {
+		expectSync(sqlmock.NewRows([]string{"reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("reservation-1", "Jane Cooper", 1, 1))
+
+		update(screen.Init()())
+
+		Expect(screen.View()).To(ContainSubstring("online"))
+		Expect(screen.View()).To(ContainSubstring("seats empty: 8"))
+		Expect(screen.View()).To(MatchRegexp(`✓ Jane Cooper`))
+		Expect(screen.View()).To(MatchRegexp(`· John Smith`))
+	})
+
+	It("searches the guest list incrementally", func() {
+		expectSync(sqlmock.NewRows(nil))
+
+		update(screen.Init()())
+
+		update(typing("jo"))
+		Expect(screen.View()).To(ContainSubstring("John Smith"))
+		Expect(screen.View()).To(ContainSubstring("Mary Johnson"))
+		Expect(screen.View()).NotTo(ContainSubstring("Jane Cooper"))
+
+		// names starting by the query come first
+		Expect(screen.View()).To(MatchRegexp(`> · John Smith`))
+
+		update(typing("hn"))
+		update(tea.KeyMsg{Type: tea.KeySpace})
+		update(typing("s"))
+		Expect(screen.View()).To(ContainSubstring("John Smith"))
+		Expect(screen.View()).NotTo(ContainSubstring("Mary Johnson"))
+
+		update(tea.KeyMsg{Type: tea.KeyEsc})
+		Expect(screen.View()).To(ContainSubstring("Jane Cooper"))
+	})
+
+	It("searches the guest list offline ignoring the accents", func() {
+		snapshot := door.Snapshot{Reservations: []models.Reservation{
+			{ID: "reservation-1", Name: "José Núñez"},
+			{ID: "reservation-2", Name: "Jane Cooper"},
+		}}
+
+		Expect(snapshot.Search("Jose")).To(ConsistOf(snapshot.Reservations[0]))
+		Expect(snapshot.Search("NUNEZ")).To(ConsistOf(snapshot.Reservations[0]))
+	})
+
+	It("checks in the selected guest with one key", func() {
+		expectSync(sqlmock.NewRows(nil))
+
+		update(screen.Init()())
+		update(typing("john"))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-2", "John Smith", 2, 2, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(2, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "reservation-2", "John Smith", 2, false, 2, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "reservation-2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-2", 3, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(sqlmock.AnyArg(), "reservation-2").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		sync := update(update(tea.KeyMsg{Type: tea.KeyEnter})())
+		Expect(screen.View()).To(ContainSubstring("John Smith checked in"))
+
+		// the state is fetched again after the check in
+		expectSync(sqlmock.NewRows([]string{"reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("reservation-2", "John Smith", 2, 2))
+		update(sync())
+
+		Expect(screen.View()).To(ContainSubstring("seats empty: 7"))
+		Expect(screen.View()).To(MatchRegexp(`✓ John Smith`))
+	})
+
+	It("checks in the party arriving in stages", func() {
+		expectSync(sqlmock.NewRows(nil))
+
+		update(screen.Init()())
+		update(typing("john"))
+
+		// one of the three persons of the party arrives first
+		update(tea.KeyMsg{Type: tea.KeyLeft})
+		update(tea.KeyMsg{Type: tea.KeyLeft})
+		Expect(screen.View()).To(ContainSubstring("arriving: 1"))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-2", "John Smith", 2, 2, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(2, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "reservation-2", "John Smith", 0, false, 2, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "reservation-2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-2", 1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		sync := update(update(tea.KeyMsg{Type: tea.KeyEnter})())
+		Expect(screen.View()).To(ContainSubstring("John Smith checked in"))
+
+		expectSync(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-2", "John Smith", 0, 2))
+		update(sync())
+		Expect(screen.View()).To(ContainSubstring("1/3 arrived"))
+
+		// the rest of the party joins
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-2", "John Smith", 2, 2, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-2", "John Smith", 0, 2))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(2, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "accompanying_guests", "table_id"}).AddRow("guest-1", 0, 2))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `guests` SET `reservation_id`=?,`name`=?,`accompanying_guests`=?,`oversize`=?,`table_id`=?,`created_at`=? WHERE `id` = ?")).
+			WithArgs("reservation-2", "John Smith", 2, false, 2, sqlmock.AnyArg(), "guest-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-2", 2, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(sqlmock.AnyArg(), "reservation-2").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		update(update(tea.KeyMsg{Type: tea.KeyEnter})())
+		Expect(screen.View()).To(ContainSubstring("John Smith checked in"))
+	})
+
+	It("shows the fill of the tables", func() {
+		expectSync(sqlmock.NewRows([]string{"reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("reservation-2", "John Smith", 2, 2))
+
+		update(screen.Init()())
+		update(tea.KeyMsg{Type: tea.KeyTab})
+
+		Expect(screen.View()).To(MatchRegexp(`1\s+\[-+\]\s+0/4\s+2`))
+		Expect(screen.View()).To(MatchRegexp(`2\s+\[#{10}-{10}\]\s+3/6\s+4`))
+	})
+
+	It("keeps the last state while the server is not reachable", func() {
+		expectSync(sqlmock.NewRows(nil))
+
+		update(screen.Init()())
+		update(typing("mary"))
+
+		server.Close()
+
+		// the next refresh fails and the screen keeps working on the last state
+		update(update(tea.KeyMsg{Type: tea.KeyCtrlR})())
+		Expect(screen.View()).To(ContainSubstring("OFFLINE"))
+		Expect(screen.View()).To(ContainSubstring("Mary Johnson"))
+
+		update(update(tea.KeyMsg{Type: tea.KeyEnter})())
+		Expect(screen.View()).To(ContainSubstring("Mary Johnson could not be checked in: the server is not reachable"))
+	})
+})
@@ -19,6 +19,12 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 )
 
+// freeSeatsQuery is the query of the seats of a table not booked by the other reservations, checked for the parties
+// arriving with more persons than booked
+var freeSeatsQuery = regexp.QuoteMeta("SELECT tables.capacity - COALESCE(SUM(GREATEST(1 + reservations.accompanying_guests, COALESCE(1 + guests.accompanying_guests, 0))), 0) AS free FROM `tables` " +
+	"LEFT JOIN reservations ON reservations.table_id = tables.id AND reservations.status = ? AND reservations.id <> ? AND (reservations.ends_at IS NULL OR reservations.ends_at > ?) " +
+	"LEFT JOIN guests ON guests.reservation_id = reservations.id WHERE tables.id = ? GROUP BY tables.id, tables.capacity")
+
 var _ = Describe("Guest controller", func() {
 	var (
 		mock   sqlmock.Sqlmock
@@ -67,34 +73,48 @@ var _ = Describe("Guest controller", func() {
 	})
 
 	It("registers a guest in an empty table", func() {
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY `reservations`.`name` LIMIT 1")).WithArgs("username").
-			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}).AddRow("username", 5, 1))
-
 		mock.ExpectBegin()
 
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 5, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
 
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
 			WillReturnRows(sqlmock.NewRows(nil))
 
-		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`name`,`accompanying_guests`,`table_id`,`created_at`) VALUES (?,?,?,?)")).
-			WithArgs("username", 5, 1, sqlmock.AnyArg()).
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(sqlmock.AnyArg(), "reservation-1", "username", 5, false, 1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 6, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
 		mock.ExpectCommit()
 
 		client.PUT(`/guests/username`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: 5}).
 			Expect().Status(http.StatusCreated).
-			JSON().Equal(api.CreateGuestResponse{Name: "username"})
+			JSON().Object().
+			ValueEqual("reservation", "reservation-1").
+			ValueEqual("name", "username").
+			Value("id").String().Length().Equal(36)
 	})
 
 	It("registers a guest in a not empty table", func() {
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY `reservations`.`name` LIMIT 1")).WithArgs("username").
-			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}).AddRow("username", 5, 1))
-
 		mock.ExpectBegin()
 
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 5, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 9))
 
@@ -102,39 +122,97 @@ var _ = Describe("Guest controller", func() {
 			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}).
 				AddRow("lastname", 2, 1))
 
-		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`name`,`accompanying_guests`,`table_id`,`created_at`) VALUES (?,?,?,?)")).
-			WithArgs("username", 5, 1, sqlmock.AnyArg()).
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(sqlmock.AnyArg(), "reservation-1", "username", 5, false, 1, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 6, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
 
 		mock.ExpectCommit()
 
 		client.PUT(`/guests/username`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: 5}).
 			Expect().Status(http.StatusCreated).
-			JSON().Equal(api.CreateGuestResponse{Name: "username"})
+			JSON().Object().
+			ValueEqual("reservation", "reservation-1").
+			ValueEqual("name", "username").
+			Value("id").String().Length().Equal(36)
 	})
 
-	It("fails registering a guest with a name shorter than 6 characters", func() {
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY `reservations`.`name` LIMIT 1")).WithArgs("user").
-			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}).AddRow("user", 5, 1))
+	It("flags a guest arriving with more accompanying guests than booked", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 2, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		// no other reservation books the table
+		mock.ExpectQuery(freeSeatsQuery).WithArgs("accepted", "reservation-1", sqlmock.AnyArg(), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"free"}).AddRow(6))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(sqlmock.AnyArg(), "reservation-1", "username", 4, true, 1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 5, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectCommit()
+
+		client.PUT(`/guests/username`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: 4}).
+			Expect().Status(http.StatusCreated)
+	})
 
+	It("fails flagging a guest taking the seats booked by another reservation", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 2, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		// another party not arrived yet books 3 of the 6 seats
+		mock.ExpectQuery(freeSeatsQuery).WithArgs("accepted", "reservation-1", sqlmock.AnyArg(), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"free"}).AddRow(3))
+		mock.ExpectRollback()
+
+		client.PUT(`/guests/username`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: 4}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeCapacityExceeded)
+	})
+
+	It("fails registering a guest with a name shorter than 6 characters", func() {
 		client.PUT(`/guests/user`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: 5}).
 			Expect().Status(http.StatusBadRequest)
 	})
 
 	It("fails registering a guest with negative accompanying", func() {
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY `reservations`.`name` LIMIT 1")).WithArgs("username").
-			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}).AddRow("username", 5, 1))
-
 		client.PUT(`/guests/username`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: -5}).
 			Expect().Status(http.StatusBadRequest)
 	})
 
 	It("fails registering a guest for an accompanying bigger than total capacity", func() {
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY `reservations`.`name` LIMIT 1")).WithArgs("username").
-			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}).AddRow("username", 5, 1))
-
 		mock.ExpectBegin()
 
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 5, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 5))
 
@@ -144,15 +222,18 @@ var _ = Describe("Guest controller", func() {
 		mock.ExpectRollback()
 
 		client.PUT(`/guests/username`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: 5}).
-			Expect().Status(http.StatusInternalServerError)
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeCapacityExceeded)
 	})
 
 	It("fails registering a guest for an accompanying bigger than available capacity", func() {
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY `reservations`.`name` LIMIT 1")).WithArgs("username").
-			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}).AddRow("username", 5, 1))
-
 		mock.ExpectBegin()
 
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 5, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 8))
 
@@ -163,15 +244,27 @@ var _ = Describe("Guest controller", func() {
 		mock.ExpectRollback()
 
 		client.PUT(`/guests/username`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: 5}).
-			Expect().Status(http.StatusInternalServerError)
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeCapacityExceeded)
 	})
 
 	It("deletes a guest from the registry", func() {
 		mock.ExpectBegin()
 
-		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `guests` WHERE name = ?")).WithArgs("username").
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 5, 1, "accepted"))
+
+		// the persons present leave
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-1", "username", 5, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `guests` WHERE `guests`.`id` = ?")).WithArgs("guest-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", -6, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ?")).WithArgs(nil, "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
 		mock.ExpectCommit()
 
 		client.DELETE(`/guests/username`).Expect().Status(http.StatusAccepted)
@@ -185,8 +278,10 @@ var _ = Describe("Guest controller", func() {
 			AddRow("user01", 3, 1, date).
 			AddRow("user02", 4, 2, date)
 
+		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests`")).
 			WillReturnRows(rows)
+		mock.ExpectCommit()
 
 		resp := api.GetGuestsResponse{
 			Guests: []models.Guest{
@@ -201,8 +296,10 @@ var _ = Describe("Guest controller", func() {
 	})
 
 	It("retrieves an empty guests list", func() {
+		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests`")).
 			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectCommit()
 
 		resp := api.GetReservationsResponse{
 			Guests: []models.Reservation{},
@@ -212,4 +309,243 @@ var _ = Describe("Guest controller", func() {
 			Expect().Status(http.StatusOK).
 			JSON().Equal(resp)
 	})
+
+	It("retrieves the accepted reservations whose party never arrived", func() {
+		// the parties arrived and gone keep their arrival
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (status = ? AND arrived_at IS NULL) AND (starts_at IS NULL OR starts_at <= ?)")).
+			WithArgs("accepted", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-2", "lastname", 1, 2, "accepted"))
+		mock.ExpectCommit()
+
+		client.GET(`/guest_list/no_shows`).
+			Expect().Status(http.StatusOK).
+			JSON().Path("$.guests[*].id").Array().Elements("reservation-2")
+	})
+
+	It("checks in the members of a party one by one", func() {
+		member := func(id string, arrivedAt interface{}) *sqlmock.Rows {
+			return sqlmock.NewRows([]string{"id", "reservation_id", "name", "arrived_at"}).AddRow(id, "reservation-1", "Lucia", arrivedAt)
+		}
+
+		// the first member arriving is the guest of the reservation
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `members` WHERE id = ? ORDER BY `members`.`id` LIMIT 1")).WithArgs("member-1").
+			WillReturnRows(member("member-1", nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 1, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 2))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "reservation-1", "username", 0, false, 1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "member-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		client.PUT(`/members/member-1`).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Value("arrived_at").String().NotEmpty()
+
+		// the following ones accompany it, taking a seat each
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `members` WHERE id = ? ORDER BY `members`.`id` LIMIT 1")).WithArgs("member-2").
+			WillReturnRows(member("member-2", nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 1, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-1", "username", 0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `members` WHERE reservation_id = ? AND arrived_at IS NOT NULL")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 2))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "accompanying_guests", "table_id"}).AddRow("guest-1", 0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `guests` SET `reservation_id`=?,`name`=?,`accompanying_guests`=?,`oversize`=?,`table_id`=?,`created_at`=? WHERE `id` = ?")).
+			WithArgs("reservation-1", "username", 1, false, 1, sqlmock.AnyArg(), "guest-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "member-2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		client.PUT(`/members/member-2`).Expect().Status(http.StatusOK)
+
+		// members arrive once
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `members` WHERE id = ? ORDER BY `members`.`id` LIMIT 1")).WithArgs("member-2").
+			WillReturnRows(member("member-2", time.Now()))
+		mock.ExpectRollback()
+
+		client.PUT(`/members/member-2`).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeArrivalConflict)
+	})
+
+	It("counts the members among the persons of their party checked in without naming them", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `members` WHERE id = ? ORDER BY `members`.`id` LIMIT 1")).WithArgs("member-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "arrived_at"}).AddRow("member-2", "reservation-1", "Lucia", nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 2, 1, "accepted"))
+
+		// two persons of the party are present, one of them a member checked in by name
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-1", "username", 1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `members` WHERE reservation_id = ? AND arrived_at IS NOT NULL")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		// so the member arriving is the other one, already seated
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "member-2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		client.PUT(`/members/member-2`).Expect().Status(http.StatusOK)
+	})
+
+	It("checks out the guest of a party with its last member", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `members` WHERE id = ? ORDER BY `members`.`id` LIMIT 1")).WithArgs("member-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "arrived_at"}).AddRow("member-1", "reservation-1", "Lucia", time.Now()))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-1", "username", 0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `guests` WHERE `guests`.`id` = ?")).WithArgs("guest-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", -1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(nil, "member-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		client.DELETE(`/members/member-1`).
+			Expect().Status(http.StatusOK).
+			JSON().Object().NotContainsKey("arrived_at")
+	})
+
+	It("adds the staggered arrivals of a party up to its size", func() {
+		reservation := func() *sqlmock.Rows {
+			return sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 5, 1, "accepted")
+		}
+
+		// two persons arrive first
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(reservation())
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "reservation-1", "username", 1, false, 1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 2, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		client.PUT(`/reservations/reservation-1/guest`).WithJSON(api.CreateGuestRequest{Arriving: 2}).
+			Expect().Status(http.StatusCreated).
+			JSON().Object().
+			ValueEqual("arrived", 2).
+			ValueEqual("expected", 6)
+
+		// the other four join them, completing the party, the members arrived before keep their arrival
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(reservation())
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-1", "username", 1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "accompanying_guests", "table_id"}).AddRow("guest-1", 1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `guests` SET `reservation_id`=?,`name`=?,`accompanying_guests`=?,`oversize`=?,`table_id`=?,`created_at`=? WHERE `id` = ?")).
+			WithArgs("reservation-1", "username", 5, false, 1, sqlmock.AnyArg(), "guest-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 4, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		client.PUT(`/reservations/reservation-1/guest`).WithJSON(api.CreateGuestRequest{Arriving: 4}).
+			Expect().Status(http.StatusCreated).
+			JSON().Object().
+			ValueEqual("arrived", 6).
+			ValueEqual("expected", 6)
+	})
+
+	It("removes the persons leaving from the guest", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 5, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-1", "username", 3, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `members` WHERE reservation_id = ? AND arrived_at IS NOT NULL")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "accompanying_guests", "table_id"}).AddRow("guest-1", 3, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `guests` SET `reservation_id`=?,`name`=?,`accompanying_guests`=?,`oversize`=?,`table_id`=?,`created_at`=? WHERE `id` = ?")).
+			WithArgs("reservation-1", "username", 1, false, 1, sqlmock.AnyArg(), "guest-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", -2, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		client.DELETE(`/reservations/reservation-1/guest`).WithQuery("leaving", 2).
+			Expect().Status(http.StatusAccepted)
+
+		// more persons than the present can not leave
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 5, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-1", "username", 1, 1))
+		mock.ExpectRollback()
+
+		client.DELETE(`/reservations/reservation-1/guest`).WithQuery("leaving", 3).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeArrivalConflict)
+	})
+
+	It("fails removing by count the members present", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 2, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-1", "username", 2, 1))
+
+		// two of the three persons present are members checked in by name
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `members` WHERE reservation_id = ? AND arrived_at IS NOT NULL")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectRollback()
+
+		client.DELETE(`/reservations/reservation-1/guest`).WithQuery("leaving", 2).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeArrivalConflict)
+	})
+
+	It("does not seat walk-ins unless they are enabled", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(eventQuery).WithArgs(1).WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		client.POST(`/walk_ins`).WithJSON(api.CreateWalkInRequest{Persons: 2}).
+			Expect().Status(http.StatusForbidden).
+			Header(api.ErrorCodeHeader).Equal(api.CodeWalkInsDisabled)
+	})
 })
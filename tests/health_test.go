@@ -0,0 +1,142 @@
+package tests_test
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Health controller", func() {
+	var (
+		gdb    *gorm.DB
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+	)
+
+	// schemaRows returns the columns of the migrated models, skipping the given ones
+	schemaRows := func(skip ...string) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"table_name", "column_name"})
+
+		for _, model := range models.Models {
+			stmt := &gorm.Statement{DB: gdb}
+			Expect(stmt.Parse(model)).To(Succeed())
+
+		columns:
+			for _, column := range stmt.Schema.DBNames {
+				for _, s := range skip {
+					if s == stmt.Schema.Table+"."+column {
+						continue columns
+					}
+				}
+				rows.AddRow(stmt.Schema.Table, column)
+			}
+		}
+
+		return rows
+	}
+
+	expectSchema := func(rows *sqlmock.Rows) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT table_name AS table_name, column_name AS column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name IN (?,?,?,?,?,?,?,?,?)")).
+			WithArgs("tables", "reservations", "members", "guests", "seating_constraints", "reservation_trigrams", "movements", "synced_check_ins", "events").
+			WillReturnRows(rows)
+	}
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New(sqlmock.MonitorPingsOption(true))
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection, pinged when opened
+		mock.ExpectPing()
+		gdb, err = gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode:  true,
+			HealthChecks: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("reports the process is alive without touching the database", func() {
+		client.GET(`/healthz`).
+			Expect().Status(http.StatusOK).
+			JSON().Equal(api.HealthResponse{Status: api.HealthOK})
+	})
+
+	It("reports ready when the database is reachable and migrated", func() {
+		mock.ExpectPing()
+		expectSchema(schemaRows())
+
+		resp := client.GET(`/readyz`).
+			Expect().Status(http.StatusOK).
+			JSON().Object()
+
+		resp.ValueEqual("status", api.HealthOK)
+		resp.Path("$.checks.database.status").Equal(api.HealthOK)
+		resp.Path("$.checks.migrations.status").Equal(api.HealthOK)
+	})
+
+	It("reports not ready when the database is unreachable", func() {
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		expectSchema(schemaRows())
+
+		resp := client.GET(`/readyz`).
+			Expect().Status(http.StatusServiceUnavailable).
+			JSON().Object()
+
+		resp.ValueEqual("status", api.HealthUnavailable)
+		resp.Path("$.checks.database.error").Equal("connection refused")
+	})
+
+	It("reports not ready when migrations are pending", func() {
+		mock.ExpectPing()
+		expectSchema(schemaRows("reservations.status"))
+
+		resp := client.GET(`/readyz`).
+			Expect().Status(http.StatusServiceUnavailable).
+			JSON().Object()
+
+		resp.Path("$.checks.database.status").Equal(api.HealthOK)
+		resp.Path("$.checks.migrations.pending").Array().Elements("reservations.status")
+	})
+})
@@ -0,0 +1,202 @@
+package tests_test
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/logging"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Logging", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+		logs   *observer.ObservedLogs
+	)
+
+	BeforeEach(func() {
+		var (
+			db   *sql.DB
+			core zapcore.Core
+			err  error
+		)
+
+		// record the logs in memory
+		core, logs = observer.New(zapcore.DebugLevel)
+		log := zap.New(core)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection logging the queries
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logging.NewGorm(log, time.Second),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			Logger:      log,
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		logging.RedactNames(false)
+
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("generates a request id and returns it", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests`")).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}))
+		mock.ExpectCommit()
+
+		id := client.GET(`/guests`).
+			Expect().Status(http.StatusOK).
+			Header(logging.RequestIDHeader).NotEmpty().Raw()
+
+		requests := logs.FilterMessage("request handled").All()
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].ContextMap()).To(HaveKeyWithValue("request_id", id))
+		Expect(requests[0].ContextMap()).To(HaveKeyWithValue("route", "/guests"))
+		Expect(requests[0].ContextMap()).To(HaveKeyWithValue("status", int64(http.StatusOK)))
+	})
+
+	It("propagates the request id to the response and the database queries", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests`")).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}))
+		mock.ExpectCommit()
+
+		client.GET(`/guests`).WithHeader(logging.RequestIDHeader, "door-42").
+			Expect().Status(http.StatusOK).
+			Header(logging.RequestIDHeader).Equal("door-42")
+
+		queries := logs.FilterMessage("query").All()
+		Expect(queries).To(HaveLen(1))
+		Expect(queries[0].ContextMap()).To(HaveKeyWithValue("request_id", "door-42"))
+		Expect(queries[0].ContextMap()["sql"]).To(ContainSubstring("SELECT * FROM `guests`"))
+	})
+
+	It("logs the controller errors", func() {
+		client.POST(`/guest_list/user`).WithJSON(api.CreateReservationRequest{Table: 1}).
+			Expect().Status(http.StatusBadRequest)
+
+		errors := logs.FilterMessageSnippet("error validating reservation").All()
+		Expect(errors).To(HaveLen(1))
+		Expect(errors[0].Level).To(Equal(zapcore.WarnLevel))
+		Expect(errors[0].ContextMap()).To(HaveKey("request_id"))
+	})
+
+	It("redacts the guest names", func() {
+		logging.RedactNames(true)
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4))
+
+		mock.ExpectRollback()
+
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeCapacityExceeded)
+
+		rejections := logs.FilterMessage("reservation exceeds table capacity").All()
+		Expect(rejections).To(HaveLen(1))
+		Expect(rejections[0].ContextMap()["guest"]).To(HavePrefix("redacted:"))
+
+		for _, entry := range logs.All() {
+			for _, field := range entry.Context {
+				Expect(field.String).NotTo(ContainSubstring("username"))
+			}
+		}
+	})
+
+	It("redacts the guest names of the errors and the failed queries", func() {
+		logging.RedactNames(true)
+
+		// the name is shared
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).
+				AddRow("reservation-1", "username", 1, 1, "accepted").
+				AddRow("reservation-2", "username", 2, 2, "accepted"))
+		mock.ExpectRollback()
+
+		client.PUT(`/guests/username`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: 1}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeAmbiguousName)
+
+		// the lookup fails
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		client.PUT(`/guests/username`).WithJSON(api.CreateGuestRequest{AccompanyingGuests: 1}).
+			Expect().Status(http.StatusInternalServerError)
+
+		failures := logs.FilterMessage("query failed").All()
+		Expect(failures).To(HaveLen(1))
+		Expect(failures[0].ContextMap()).NotTo(HaveKey("sql"))
+
+		for _, entry := range logs.All() {
+			Expect(entry.Message).NotTo(ContainSubstring("username"))
+			for _, field := range entry.Context {
+				Expect(field.String).NotTo(ContainSubstring("username"))
+			}
+		}
+	})
+
+	It("redacts the guest names with a hash keyed by the configured secret", func() {
+		logging.RedactNames(true)
+		defer logging.RedactKey("")
+
+		logging.RedactKey("first-secret")
+		first := logging.Name("guest", "username").String
+		Expect(logging.Name("guest", "username").String).To(Equal(first))
+
+		// the unkeyed hash of the name does not give it away
+		sum := sha256.Sum256([]byte("username"))
+		Expect(first).NotTo(ContainSubstring(hex.EncodeToString(sum[:4])))
+
+		logging.RedactKey("second-secret")
+		Expect(logging.Name("guest", "username").String).NotTo(Equal(first))
+		Expect(logging.Redact(`reservation "username" not found`, "username")).NotTo(ContainSubstring("username"))
+	})
+})
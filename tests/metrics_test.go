@@ -0,0 +1,134 @@
+package tests_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/metrics"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+	)
+
+	expectRefresh := func(booked int, tables *sqlmock.Rows) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(1 + accompanying_guests), 0) FROM `reservations` WHERE status = ?")).WithArgs("accepted").
+			WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(booked))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tables.id, tables.capacity, COALESCE(SUM(1 + guests.accompanying_guests), 0) AS present FROM `tables` LEFT JOIN guests ON guests.table_id = tables.id GROUP BY tables.id, tables.capacity ORDER BY tables.id")).
+			WillReturnRows(tables)
+	}
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// instrument the connection with the initial occupancy
+		expectRefresh(4, sqlmock.NewRows([]string{"id", "capacity", "present"}).AddRow(1, 6, 2))
+
+		collectors := metrics.New()
+		Expect(collectors.Instrument(gdb)).To(Succeed())
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb).WithMetrics(collectors)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("exposes the initial occupancy", func() {
+		body := client.GET(`/metrics`).Expect().Status(http.StatusOK).Body()
+
+		body.Contains("party_seats_capacity 6")
+		body.Contains("party_seats_booked 4")
+		body.Contains("party_guests_present 2")
+		body.Contains(`party_seats_empty{table="1"} 4`)
+	})
+
+	It("refreshes the occupancy after a write", func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 4, "").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		// the gauges are refreshed once the transaction of the service commits
+		expectRefresh(4, sqlmock.NewRows([]string{"id", "capacity", "present"}).AddRow(1, 6, 2).AddRow(2, 4, 0))
+
+		client.POST(`/tables`).WithJSON(api.CreateTableRequest{Capacity: 4}).
+			Expect().Status(http.StatusCreated)
+
+		body := client.GET(`/metrics`).Expect().Status(http.StatusOK).Body()
+
+		body.Contains("party_seats_capacity 10")
+		body.Contains(`party_seats_empty{table="2"} 4`)
+		body.Contains(`party_db_query_duration_seconds_count{operation="create",table="tables"} 1`)
+	})
+
+	It("does not refresh the occupancy with the writes rolled back", func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 4, "").
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		client.POST(`/tables`).WithJSON(api.CreateTableRequest{Capacity: 4}).
+			Expect().Status(http.StatusInternalServerError)
+
+		client.GET(`/metrics`).Expect().Status(http.StatusOK).Body().
+			Contains("party_seats_capacity 6")
+	})
+
+	It("does not refresh the occupancy on reads", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables`")).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectCommit()
+
+		client.GET(`/tables`).Expect().Status(http.StatusOK)
+
+		client.GET(`/metrics`).Expect().Status(http.StatusOK).Body().
+			Contains(`party_http_requests_total{method="GET",route="/tables",status="200"} 1`)
+	})
+})
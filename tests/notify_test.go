@@ -0,0 +1,238 @@
+package tests_test
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/notify"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// recorder is a notifier keeping the messages in memory, it fails the first failures sends
+type recorder struct {
+	mu       sync.Mutex
+	failures int
+	messages []notify.Message
+}
+
+func (r *recorder) Notify(msg notify.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("transport unavailable")
+	}
+
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func (r *recorder) Messages() []notify.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]notify.Message(nil), r.messages...)
+}
+
+var _ = Describe("Notifications", func() {
+	var (
+		mock     sqlmock.Sqlmock
+		server   *httptest.Server
+		client   *httpexpect.Expect
+		messages *recorder
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection and recording notifier
+		messages = new(recorder)
+		handler := new(api.Handler).WithConnection(gdb).WithNotifier(messages)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	reservation := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status", "token", "email"}).
+			AddRow("reservation-1", "username", 1, 1, "invited", "secret", "user@example.com")
+	}
+
+	It("sends a confirmation when a reservation is created", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 8))
+
+		mock.ExpectCommit()
+
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 1, Email: "user@example.com"}).
+			Expect().Status(http.StatusCreated)
+
+		Expect(messages.Messages()).To(HaveLen(1))
+		Expect(messages.Messages()[0].Kind).To(Equal(notify.Confirmation))
+		Expect(messages.Messages()[0].To.Email).To(Equal("user@example.com"))
+		Expect(messages.Messages()[0].Body).To(ContainSubstring("for 2 people at table 1"))
+	})
+
+	It("fails creating a reservation with an invalid email", func() {
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, Email: "username"}).
+			Expect().Status(http.StatusBadRequest)
+
+		Expect(messages.Messages()).To(BeEmpty())
+	})
+
+	It("sends a table change when the reservation moves", func() {
+		table := 2
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(reservation())
+
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `name`=?,`accompanying_guests`=?,`table_id`=?,`requirements`=?,`starts_at`=?,`ends_at`=?,`status`=?,`token`=?,`respond_by`=?,`responded_at`=?,`email`=?,`phone`=?,`source`=?,`arrived_at`=? WHERE `id` = ?")).
+			WithArgs("username", 1, 2, "", nil, nil, "invited", "secret", nil, nil, "user@example.com", "", "", nil, "reservation-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		// the new table follows the seating constraints of the reservation
+		mock.ExpectQuery(regexp.QuoteMeta(placementsQuery)).
+			WillReturnRows(placementRows())
+
+		// and takes its guest along, not arrived yet
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		mock.ExpectCommit()
+
+		client.PUT(`/guest_list/username`).WithJSON(api.UpdateReservationRequest{Table: &table}).
+			Expect().Status(http.StatusOK).
+			JSON().Object().ValueEqual("table", 2)
+
+		Expect(messages.Messages()).To(HaveLen(1))
+		Expect(messages.Messages()[0].Kind).To(Equal(notify.TableChange))
+		Expect(messages.Messages()[0].Body).To(ContainSubstring("from table 1 to table 2"))
+	})
+
+	It("sends a cancellation when the reservation is cancelled", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(reservation())
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `reservations` WHERE `reservations`.`id` = ?")).WithArgs("reservation-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `reservation_trigrams` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 8))
+
+		mock.ExpectCommit()
+
+		client.DELETE(`/guest_list/username`).Expect().Status(http.StatusAccepted)
+
+		Expect(messages.Messages()).To(HaveLen(1))
+		Expect(messages.Messages()[0].Kind).To(Equal(notify.Cancellation))
+	})
+
+	It("sends a reminder with the rsvp code of a pending invitation", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(reservation())
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `members` WHERE reservation_id = ? ORDER BY position")).WithArgs(sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs(sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectCommit()
+
+		client.POST(`/guest_list/username/reminder`).Expect().Status(http.StatusAccepted)
+
+		Expect(messages.Messages()).To(HaveLen(1))
+		Expect(messages.Messages()[0].Kind).To(Equal(notify.Reminder))
+		Expect(messages.Messages()[0].Body).To(ContainSubstring("secret"))
+	})
+
+	It("retries the failed sends in the background", func() {
+		transport := &recorder{failures: 2}
+
+		queue := notify.NewQueue(transport, 3, time.Millisecond, nil)
+		defer queue.Close()
+
+		Expect(queue.Notify(notify.Message{Kind: notify.Reminder})).To(Succeed())
+
+		Eventually(transport.Messages).Should(HaveLen(1))
+		Expect(queue.DeadLetters()).To(BeEmpty())
+	})
+
+	It("retries the failed sends of a multi transport on the failed transports only", func() {
+		delivering, failing := &recorder{}, &recorder{failures: 2}
+
+		queue := notify.NewQueue(notify.Multi{delivering, failing}, 3, time.Millisecond, nil)
+		defer queue.Close()
+
+		Expect(queue.Notify(notify.Message{Kind: notify.Reminder})).To(Succeed())
+
+		Eventually(failing.Messages).Should(HaveLen(1))
+		Expect(delivering.Messages()).To(HaveLen(1))
+		Expect(queue.DeadLetters()).To(BeEmpty())
+	})
+
+	It("keeps the messages failing after the last attempt as dead letters", func() {
+		transport := &recorder{failures: 5}
+
+		queue := notify.NewQueue(transport, 2, time.Millisecond, nil)
+		defer queue.Close()
+
+		Expect(queue.Notify(notify.Message{Kind: notify.Reminder})).To(Succeed())
+
+		Eventually(queue.DeadLetters).Should(HaveLen(1))
+		Expect(transport.Messages()).To(BeEmpty())
+	})
+})
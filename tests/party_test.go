@@ -0,0 +1,161 @@
+package tests_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/party"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Party service", func() {
+	var (
+		mock     sqlmock.Sqlmock
+		gdb      *gorm.DB
+		service  *party.Service
+		messages *recorder
+		ctx      context.Context
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err = gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create service with mock connection and recording notifier
+		messages = new(recorder)
+		service = party.New(gdb).WithNotifier(messages)
+
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("validates the input before opening a transaction", func() {
+		_, err := service.BookReservation(ctx, party.Booking{Name: "user", Table: 1})
+		Expect(errors.Is(err, party.ErrInvalid)).To(BeTrue())
+
+		_, err = service.AddTable(ctx, party.TableSpec{Capacity: 0})
+		Expect(errors.Is(err, party.ErrInvalid)).To(BeTrue())
+
+		_, _, err = service.Reservations(ctx, "pending", party.Page{})
+		Expect(errors.Is(err, party.ErrInvalid)).To(BeTrue())
+
+		_, _, err = service.Guests(ctx, party.Page{Limit: party.MaxPageSize + 1})
+		Expect(errors.Is(err, party.ErrInvalid)).To(BeTrue())
+	})
+
+	It("rolls back the booking exceeding the capacity of the table without notifying", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 2))
+		mock.ExpectRollback()
+
+		_, err := service.BookReservation(ctx, party.Booking{Name: "username", Table: 1, AccompanyingGuests: 4})
+		Expect(errors.Is(err, party.ErrCapacityExceeded)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("error creating reservation"))
+
+		Expect(messages.Messages()).To(BeEmpty())
+	})
+
+	It("notifies the check in once it is committed", func() {
+		expectCheckIn := func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status", "email"}).AddRow("reservation-1", "username", 1, 1, "accepted", "user@example.com"))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+				WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+				WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests`")).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+
+		// the failed commits are internal errors, the holder is not notified
+		expectCheckIn()
+		mock.ExpectCommit().WillReturnError(errors.New("connection lost"))
+
+		_, err := service.CheckIn(ctx, party.ByName("username"), 2)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, party.ErrNotFound) || errors.Is(err, party.ErrInvalid)).To(BeFalse())
+		Expect(messages.Messages()).To(BeEmpty())
+
+		expectCheckIn()
+		mock.ExpectCommit()
+
+		guest, err := service.CheckIn(ctx, party.ByName("username"), 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(guest.TableID).To(Equal(1))
+		Expect(messages.Messages()).To(HaveLen(1))
+	})
+
+	It("rejects the guests arriving with more accompanying guests than booked by policy", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 1, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-1", "reservation-1", "username", 0, 1))
+		mock.ExpectRollback()
+
+		_, err := party.New(gdb).WithArrivalPolicy(party.ArrivalsReject).CheckIn(ctx, party.ByID("reservation-1"), 2)
+		Expect(errors.Is(err, party.ErrOversizeArrival)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("3 persons arrived, 2 were booked"))
+	})
+
+	It("commits the expiration of an invitation before reporting it", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("secret").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status", "token", "respond_by"}).
+				AddRow("reservation-1", "username", 1, 1, "invited", "secret", time.Now().Add(-time.Hour)))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `status`=? WHERE `id` = ?")).
+			WithArgs("expired", "reservation-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		_, err := service.RespondInvitation(ctx, "secret", true)
+		Expect(errors.Is(err, party.ErrInvitationExpired)).To(BeTrue())
+	})
+
+	It("reports the operations disabled by the configuration", func() {
+		err := party.New(gdb).RemindReservation(ctx, party.ByName("username"))
+		Expect(errors.Is(err, party.ErrNotificationsDisabled)).To(BeTrue())
+
+		_, err = party.New(gdb).Ticket(ctx, party.ByName("username"))
+		Expect(errors.Is(err, party.ErrTicketsDisabled)).To(BeTrue())
+	})
+})
@@ -2,9 +2,11 @@ package tests_test
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"time"
 
 	"github.com/amaury95/GetGround-Party/api"
 	"github.com/amaury95/GetGround-Party/models"
@@ -18,6 +20,17 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 )
 
+// uuidPattern matches the ids generated for the reservations and guests
+const uuidPattern = `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`
+
+// anyID matches the generated ids given as arguments of the queries
+type anyID struct{}
+
+func (anyID) Match(v driver.Value) bool {
+	id, ok := v.(string)
+	return ok && regexp.MustCompile(uuidPattern).MatchString(id)
+}
+
 var _ = Describe("Reservation controller", func() {
 	var (
 		mock   sqlmock.Sqlmock
@@ -65,86 +78,139 @@ var _ = Describe("Reservation controller", func() {
 		Expect(err).ShouldNot(HaveOccurred())
 	})
 
-	It("creates a reservation in an empty table", func() {
+	It("creates an invitation in an empty table", func() {
 		mock.ExpectBegin()
 
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
 
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE `reservations`.`table_id` = ?")).WithArgs(1).
-			WillReturnRows(sqlmock.NewRows(nil))
-
-		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations` (`name`,`accompanying_guests`,`table_id`) VALUES (?,?,?)")).
-			WithArgs("username", 5, 1).
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations` (`id`,`name`,`accompanying_guests`,`table_id`,`requirements`,`starts_at`,`ends_at`,`status`,`token`,`respond_by`,`responded_at`,`email`,`phone`,`source`,`arrived_at`) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "username", 5, 1, "", nil, nil, "invited", sqlmock.AnyArg(), nil, nil, "", "", "guest_list", nil).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 8))
 
 		mock.ExpectCommit()
 
-		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5}).
+		resp := client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5}).
 			Expect().Status(http.StatusCreated).
-			JSON().Equal(api.CreateReservationResponse{Name: "username"})
+			JSON().Object()
+
+		resp.Value("id").String().Match(uuidPattern)
+		resp.ValueEqual("name", "username")
+		resp.ValueEqual("status", "invited")
+		resp.Value("rsvp_token").String().NotEmpty()
 	})
 
-	It("creates a reservation in a not empty table", func() {
+	It("creates an invitation with a response deadline", func() {
+		deadline := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+
 		mock.ExpectBegin()
 
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 8))
 
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE `reservations`.`table_id` = ?")).WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}).
-				AddRow("lastname", 1, 1))
-
-		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations` (`name`,`accompanying_guests`,`table_id`) VALUES (?,?,?)")).
-			WithArgs("username", 5, 1).
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations` (`id`,`name`,`accompanying_guests`,`table_id`,`requirements`,`starts_at`,`ends_at`,`status`,`token`,`respond_by`,`responded_at`,`email`,`phone`,`source`,`arrived_at`) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "username", 5, 1, "", nil, nil, "invited", sqlmock.AnyArg(), deadline, nil, "", "", "guest_list", nil).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 8))
 
 		mock.ExpectCommit()
 
-		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5}).
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5, RespondBy: &deadline}).
 			Expect().Status(http.StatusCreated).
-			JSON().Equal(api.CreateReservationResponse{Name: "username"})
+			JSON().Object().ValueEqual("status", "invited")
 	})
 
-	It("fails creating a reservation with a name shorter than 6 characters", func() {
-		client.POST(`/guest_list/user`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5}).
-			Expect().Status(http.StatusBadRequest)
+	It("creates an invitation for the named members of the party", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations` (`id`,`name`,`accompanying_guests`,`table_id`,`requirements`,`starts_at`,`ends_at`,`status`,`token`,`respond_by`,`responded_at`,`email`,`phone`,`source`,`arrived_at`) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "username", 1, 1, "", nil, nil, "invited", sqlmock.AnyArg(), nil, nil, "", "", "guest_list", nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `members` (`id`,`reservation_id`,`position`,`name`,`email`,`dietary`,`age_group`,`arrived_at`) VALUES (?,?,?,?,?,?,?,?),(?,?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, anyID{}, 0, "username", "", "", "adult", nil, anyID{}, anyID{}, 1, "Lucia", "", "vegetarian", "child", nil).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 8))
+
+		mock.ExpectCommit()
+
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, Members: []api.Member{
+			{Name: "username", AgeGroup: "adult"},
+			{Name: "Lucia", Dietary: "vegetarian", AgeGroup: "child"},
+		}}).
+			Expect().Status(http.StatusCreated)
 	})
 
-	It("fails creating a reservation with negative accompanying", func() {
-		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: -5}).
-			Expect().Status(http.StatusBadRequest)
+	It("fails creating a reservation at a table lacking the features it requires", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity", "features"}).AddRow(1, 6, "near-stage"))
+		mock.ExpectRollback()
+
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, Requirements: []string{"wheelchair-accessible"}}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeUnsuitableTable)
 	})
 
-	It("fails creating a reservation for an accompanying bigger than total capacity", func() {
+	It("creates a reservation naming part of its party", func() {
 		mock.ExpectBegin()
 
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
 
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE `reservations`.`table_id` = ?")).WithArgs(1).
-			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations` (`id`,`name`,`accompanying_guests`,`table_id`,`requirements`,`starts_at`,`ends_at`,`status`,`token`,`respond_by`,`responded_at`,`email`,`phone`,`source`,`arrived_at`) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "username", 3, 1, "", nil, nil, "invited", sqlmock.AnyArg(), nil, nil, "", "", "guest_list", nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `members` (`id`,`reservation_id`,`position`,`name`,`email`,`dietary`,`age_group`,`arrived_at`) VALUES (?,?,?,?,?,?,?,?),(?,?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, anyID{}, 0, "username", "", "", "adult", nil, anyID{}, anyID{}, 1, "Lucia", "", "", "child", nil).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 8))
 
-		mock.ExpectRollback()
+		mock.ExpectCommit()
 
-		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5}).
-			Expect().Status(http.StatusInternalServerError)
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 3, Members: []api.Member{
+			{Name: "username", AgeGroup: "adult"},
+			{Name: "Lucia", AgeGroup: "child"},
+		}}).
+			Expect().Status(http.StatusCreated)
+	})
+
+	It("fails creating a reservation whose members do not fit the party", func() {
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 1, Members: []api.Member{{Name: "username"}, {Name: "Lucia"}, {Name: "Marco"}}}).
+			Expect().Status(http.StatusBadRequest)
+
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, Members: []api.Member{{Name: "username", AgeGroup: "senior"}}}).
+			Expect().Status(http.StatusBadRequest)
 	})
 
-	It("fails creating a reservation for an accompanying bigger than available capacity", func() {
+	It("fails creating a reservation with a name shorter than 6 characters", func() {
+		client.POST(`/guest_list/user`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5}).
+			Expect().Status(http.StatusBadRequest)
+	})
+
+	It("fails creating a reservation with negative accompanying", func() {
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: -5}).
+			Expect().Status(http.StatusBadRequest)
+	})
+
+	It("fails creating a reservation for an accompanying bigger than total capacity", func() {
 		mock.ExpectBegin()
 
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 8))
-
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE `reservations`.`table_id` = ?")).WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}).
-				AddRow("lastname", 2, 1))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4))
 
 		mock.ExpectRollback()
 
 		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5}).
-			Expect().Status(http.StatusInternalServerError)
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeCapacityExceeded)
 	})
 
 	It("retrieves the populated reservations list", func() {
@@ -153,8 +219,10 @@ var _ = Describe("Reservation controller", func() {
 			AddRow("user01", 3, 1).
 			AddRow("user02", 4, 2)
 
+		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations`")).
 			WillReturnRows(rows)
+		mock.ExpectCommit()
 
 		resp := api.GetReservationsResponse{
 			Guests: []models.Reservation{
@@ -168,9 +236,24 @@ var _ = Describe("Reservation controller", func() {
 			JSON().Equal(resp)
 	})
 
+	It("keeps the rsvp token of the reservations read secret", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations`")).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status", "token"}).AddRow("user01", 3, 1, "invited", "secret"))
+		mock.ExpectCommit()
+
+		client.GET(`/guest_list`).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Value("guests").Array().Element(0).Object().
+			ValueEqual("status", "invited").
+			NotContainsKey("rsvp_token")
+	})
+
 	It("retrieves the empty reservations list", func() {
+		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations`")).
 			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectCommit()
 
 		resp := api.GetReservationsResponse{
 			Guests: []models.Reservation{},
@@ -180,4 +263,166 @@ var _ = Describe("Reservation controller", func() {
 			Expect().Status(http.StatusOK).
 			JSON().Equal(resp)
 	})
+
+	It("filters the reservations list by status", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE status = ?")).WithArgs("accepted").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status"}).
+				AddRow("user01", 3, 1, "accepted"))
+		mock.ExpectCommit()
+
+		resp := api.GetReservationsResponse{
+			Guests: []models.Reservation{
+				{Name: "user01", AccompanyingGuests: 3, TableID: 1, Status: "accepted"},
+			},
+		}
+
+		client.GET(`/guest_list`).WithQuery("status", "accepted").
+			Expect().Status(http.StatusOK).
+			JSON().Equal(resp)
+	})
+
+	It("fails filtering the reservations list by an unknown status", func() {
+		client.GET(`/guest_list`).WithQuery("status", "maybe").
+			Expect().Status(http.StatusBadRequest)
+	})
+
+	It("summarizes the invitation responses", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT CASE WHEN status = ? AND respond_by < ? THEN ? ELSE status END AS response, COUNT(*) AS total FROM `reservations` GROUP BY `response`")).
+			WithArgs("invited", sqlmock.AnyArg(), "expired").
+			WillReturnRows(sqlmock.NewRows([]string{"response", "total"}).
+				AddRow("invited", 2).
+				AddRow("accepted", 3).
+				AddRow("declined", 1).
+				AddRow("expired", 2))
+		mock.ExpectCommit()
+
+		client.GET(`/guest_list/summary`).
+			Expect().Status(http.StatusOK).
+			JSON().Equal(api.GetReservationsSummaryResponse{
+			Total:          8,
+			Invited:        2,
+			Accepted:       3,
+			Declined:       1,
+			Expired:        2,
+			ResponseRate:   0.5,
+			AcceptanceRate: 0.75,
+		})
+	})
+	It("fails referencing a shared name, listing the reservations holding it", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("Maria Garcia").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).
+				AddRow("reservation-1", "Maria Garcia", 1, 1, "accepted").
+				AddRow("reservation-2", "Maria Garcia", 0, 3, "invited"))
+		mock.ExpectRollback()
+
+		resp := client.DELETE(`/guest_list/Maria Garcia`).
+			Expect().Status(http.StatusConflict)
+
+		resp.Header(api.ErrorCodeHeader).Equal(api.CodeAmbiguousName)
+		resp.JSON().Object().Value("candidates").Equal([]api.Candidate{
+			{ID: "reservation-1", Name: "Maria Garcia", Table: 1, PartySize: 2, Status: "accepted"},
+			{ID: "reservation-2", Name: "Maria Garcia", Table: 3, PartySize: 1, Status: "invited"},
+		})
+	})
+
+	It("renames the reservation with the given id", func() {
+		name := "Maria Garcia Lopez"
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status", "token"}).
+				AddRow("reservation-2", "Maria Garcia", 0, 3, "invited", "secret"))
+
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `name`=?,`accompanying_guests`=?,`table_id`=?,`requirements`=?,`starts_at`=?,`ends_at`=?,`status`=?,`token`=?,`respond_by`=?,`responded_at`=?,`email`=?,`phone`=?,`source`=?,`arrived_at`=? WHERE `id` = ?")).
+			WithArgs(name, 0, 3, "", nil, nil, "invited", "secret", nil, nil, "", "", "", nil, "reservation-2").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		// the name is indexed again for the searches
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `reservation_trigrams` WHERE reservation_id = ?")).WithArgs("reservation-2").
+			WillReturnResult(sqlmock.NewResult(0, 11))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 16))
+
+		mock.ExpectCommit()
+
+		client.PUT(`/reservations/reservation-2`).WithJSON(api.UpdateReservationRequest{Name: &name}).
+			Expect().Status(http.StatusOK).
+			JSON().Object().
+			ValueEqual("id", "reservation-2").
+			ValueEqual("name", name)
+	})
+
+	It("fails shrinking a party below its named members", func() {
+		accompanying := 0
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status", "token"}).
+				AddRow("reservation-1", "username", 1, 1, "invited", "secret"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `members` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectRollback()
+
+		client.PUT(`/reservations/reservation-1`).WithJSON(api.UpdateReservationRequest{AccompanyingGuests: &accompanying}).
+			Expect().Status(http.StatusBadRequest).
+			Header(api.ErrorCodeHeader).Equal(api.CodeInvalidRequest)
+	})
+
+	It("moves the guest of an arrived party along with its reservation", func() {
+		table := 2
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status", "token"}).
+				AddRow("reservation-1", "username", 1, 1, "accepted", "secret"))
+
+		// the reservation takes the seats booked at the new table
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(2, 4))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (status = ? AND id <> ?) AND (ends_at IS NULL OR ends_at > ?) AND `reservations`.`table_id` = ?")).
+			WithArgs("accepted", "reservation-1", sqlmock.AnyArg(), 2).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations`")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(placementsQuery)).
+			WillReturnRows(placementRows())
+
+		// and its guest the seats present
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).
+				AddRow("guest-1", "reservation-1", "username", 1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(2, 4))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `guests` SET `reservation_id`=?,`name`=?,`accompanying_guests`=?,`oversize`=?,`table_id`=?,`created_at`=? WHERE `id` = ?")).
+			WithArgs("reservation-1", "username", 1, false, 2, sqlmock.AnyArg(), "guest-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectCommit()
+
+		client.PUT(`/reservations/reservation-1`).WithJSON(api.UpdateReservationRequest{Table: &table}).
+			Expect().Status(http.StatusOK).
+			JSON().Object().ValueEqual("table", 2)
+	})
+
+	It("pages the guest list by name and id, as names may be shared", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (name, id) > (?, ?) ORDER BY name,id LIMIT 2")).WithArgs("Maria Garcia", "reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id"}).
+				AddRow("reservation-2", "Maria Garcia", 0, 3).
+				AddRow("reservation-3", "Mary Johnson", 0, 2))
+		mock.ExpectCommit()
+
+		resp := client.GET(`/guest_list`).WithQuery("limit", 1).WithQuery("after", "Maria Garcia/reservation-1").
+			Expect().Status(http.StatusOK)
+
+		resp.Header(api.NextCursorHeader).Equal("Maria Garcia/reservation-2")
+		resp.JSON().Object().Value("guests").Array().Length().Equal(1)
+	})
 })
@@ -0,0 +1,250 @@
+package tests_test
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"regexp"
+
+	"github.com/amaury95/GetGround-Party/auth"
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/occupancy"
+	partyv1 "github.com/amaury95/GetGround-Party/proto/party/v1"
+	"github.com/amaury95/GetGround-Party/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("RPC", func() {
+	var (
+		mock    sqlmock.Sqlmock
+		gdb     *gorm.DB
+		handler *rpc.Handler
+		server  *grpc.Server
+		conn    *grpc.ClientConn
+		ctx     context.Context
+	)
+
+	// serve starts the gRPC server of the handler on an in-memory listener and connects to it
+	serve := func() {
+		lis := bufconn.Listen(1 << 20)
+
+		server = handler.Server(&rpc.ServerConfig{})
+		go server.Serve(lis)
+
+		var err error
+		conn, err = grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err = gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection
+		handler = new(rpc.Handler).WithConnection(gdb)
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		conn.Close()
+		server.Stop()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("creates and lists the tables", func() {
+		serve()
+		tables := partyv1.NewTableServiceClient(conn)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 4, "").
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectCommit()
+
+		table, err := tables.CreateTable(ctx, &partyv1.CreateTableRequest{Capacity: 4})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(table.Id).To(BeEquivalentTo(3))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` ORDER BY id LIMIT 2")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4).AddRow(3, 4))
+		mock.ExpectCommit()
+
+		resp, err := tables.ListTables(ctx, &partyv1.ListTablesRequest{PageSize: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Tables).To(HaveLen(1))
+		Expect(resp.NextPageToken).To(Equal("1"))
+
+		_, err = tables.CreateTable(ctx, &partyv1.CreateTableRequest{Capacity: -1})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("returns the rsvp token of an invitation only on its creation", func() {
+		serve()
+		reservations := partyv1.NewReservationServiceClient(conn)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 8))
+		mock.ExpectCommit()
+
+		created, err := reservations.CreateReservation(ctx, &partyv1.CreateReservationRequest{Name: "username", TableId: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created.RsvpToken).NotTo(BeEmpty())
+		Expect(created.Reservation.Status).To(Equal("invited"))
+	})
+
+	It("enforces the capacity rules of the models on check in", func() {
+		serve()
+		guests := partyv1.NewGuestServiceClient(conn)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status"}).AddRow("username", 5, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs(sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 5))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		_, err := guests.CheckIn(ctx, &partyv1.CheckInRequest{Name: "username", AccompanyingGuests: 5})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(status.Convert(err).Message()).To(ContainSubstring("table capacity is exceded"))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status"}).AddRow("username", 1, 1, "invited"))
+		mock.ExpectRollback()
+
+		_, err = guests.CheckIn(ctx, &partyv1.CheckInRequest{Name: "username", AccompanyingGuests: 1})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(status.Convert(err).Message()).To(ContainSubstring("only accepted reservations can check in"))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("unknown").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		_, err = guests.CheckIn(ctx, &partyv1.CheckInRequest{Name: "unknown"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("requires the permission of the method", func() {
+		tokens, err := auth.NewTokens(auth.Token{Name: "lobby", SHA256: auth.Hash("viewer-secret"), Role: auth.RoleViewer})
+		Expect(err).NotTo(HaveOccurred())
+		handler.WithAuth(tokens)
+
+		serve()
+		tables := partyv1.NewTableServiceClient(conn)
+
+		_, err = tables.ListTables(ctx, &partyv1.ListTablesRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+
+		viewer := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer viewer-secret")
+
+		_, err = tables.CreateTable(viewer, &partyv1.CreateTableRequest{Capacity: 4})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables`")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4))
+		mock.ExpectCommit()
+
+		resp, err := tables.ListTables(viewer, &partyv1.ListTablesRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Tables).To(HaveLen(1))
+	})
+
+	It("streams the occupancy changes made by the writes", func() {
+		expectLoad := func(tables *sqlmock.Rows) {
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(1 + accompanying_guests), 0) FROM `reservations` WHERE status = ?")).WithArgs("accepted").
+				WillReturnRows(sqlmock.NewRows([]string{"booked"}).AddRow(2))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT tables.id, tables.capacity, COALESCE(SUM(1 + guests.accompanying_guests), 0) AS present FROM `tables` LEFT JOIN guests ON guests.table_id = tables.id GROUP BY tables.id, tables.capacity ORDER BY tables.id")).
+				WillReturnRows(tables)
+		}
+
+		broker := occupancy.NewBroker()
+		expectLoad(sqlmock.NewRows([]string{"id", "capacity", "present"}).AddRow(1, 4, 3))
+		Expect(broker.Instrument(gdb)).To(Succeed())
+
+		handler.WithOccupancy(broker)
+		serve()
+
+		watch, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := partyv1.NewOccupancyServiceClient(conn).WatchOccupancy(watch, &partyv1.WatchOccupancyRequest{})
+		Expect(err).NotTo(HaveOccurred())
+
+		current, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(current.SeatsEmpty).To(BeEquivalentTo(1))
+		Expect(current.Booked).To(BeEquivalentTo(2))
+
+		// writes of any transport are published once committed
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 6, "").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		expectLoad(sqlmock.NewRows([]string{"id", "capacity", "present"}).AddRow(1, 4, 3).AddRow(2, 6, 0))
+
+		Expect(gdb.Create(&models.Table{Capacity: 6}).Error).To(Succeed())
+
+		changed, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed.Capacity).To(BeEquivalentTo(10))
+		Expect(changed.SeatsEmpty).To(BeEquivalentTo(7))
+		Expect(changed.Tables).To(HaveLen(2))
+
+		// the ones of the service once its transaction commits
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 2, "").
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectCommit()
+		expectLoad(sqlmock.NewRows([]string{"id", "capacity", "present"}).AddRow(1, 4, 3).AddRow(2, 6, 0).AddRow(3, 2, 0))
+
+		_, err = partyv1.NewTableServiceClient(conn).CreateTable(ctx, &partyv1.CreateTableRequest{Capacity: 2})
+		Expect(err).NotTo(HaveOccurred())
+
+		changed, err = stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed.Capacity).To(BeEquivalentTo(12))
+		Expect(changed.Tables).To(HaveLen(3))
+	})
+})
@@ -0,0 +1,170 @@
+package tests_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("RSVP controller", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	invitation := func(respondBy interface{}) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status", "token", "respond_by"}).
+			AddRow("reservation-1", "username", 5, 1, "invited", "secret", respondBy)
+	}
+
+	It("accepts an invitation in a table with available capacity", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("secret").
+			WillReturnRows(invitation(nil))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 8))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (status = ? AND id <> ?) AND (ends_at IS NULL OR ends_at > ?) AND `reservations`.`table_id` = ?")).
+			WithArgs("accepted", "reservation-1", sqlmock.AnyArg(), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status"}).
+				AddRow("lastname", 1, 1, "accepted"))
+
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `name`=?,`accompanying_guests`=?,`table_id`=?,`requirements`=?,`starts_at`=?,`ends_at`=?,`status`=?,`token`=?,`respond_by`=?,`responded_at`=?,`email`=?,`phone`=?,`source`=?,`arrived_at`=? WHERE `id` = ?")).
+			WithArgs("username", 5, 1, "", nil, nil, "accepted", "secret", nil, sqlmock.AnyArg(), "", "", "", nil, "reservation-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		// the reservation accepted is bound by its seating constraints
+		mock.ExpectQuery(regexp.QuoteMeta(placementsQuery)).
+			WillReturnRows(placementRows())
+
+		mock.ExpectCommit()
+
+		client.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: api.ResponseAccept}).
+			Expect().Status(http.StatusOK).
+			JSON().Equal(api.RespondInvitationResponse{ID: "reservation-1", Name: "username", Status: "accepted"})
+	})
+
+	It("declines an invitation without checking capacity", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("secret").
+			WillReturnRows(invitation(nil))
+
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `name`=?,`accompanying_guests`=?,`table_id`=?,`requirements`=?,`starts_at`=?,`ends_at`=?,`status`=?,`token`=?,`respond_by`=?,`responded_at`=?,`email`=?,`phone`=?,`source`=?,`arrived_at`=? WHERE `id` = ?")).
+			WithArgs("username", 5, 1, "", nil, nil, "declined", "secret", nil, sqlmock.AnyArg(), "", "", "", nil, "reservation-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectCommit()
+
+		client.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: api.ResponseDecline}).
+			Expect().Status(http.StatusOK).
+			JSON().Equal(api.RespondInvitationResponse{ID: "reservation-1", Name: "username", Status: "declined"})
+	})
+
+	It("fails accepting an invitation for an accompanying bigger than available capacity", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("secret").
+			WillReturnRows(invitation(nil))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 8))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (status = ? AND id <> ?) AND (ends_at IS NULL OR ends_at > ?) AND `reservations`.`table_id` = ?")).
+			WithArgs("accepted", "reservation-1", sqlmock.AnyArg(), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status"}).
+				AddRow("lastname", 2, 1, "accepted"))
+
+		mock.ExpectRollback()
+
+		client.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: api.ResponseAccept}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeCapacityExceeded)
+	})
+
+	It("expires an invitation answered after its deadline", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("secret").
+			WillReturnRows(invitation(time.Now().Add(-time.Hour)))
+
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `status`=? WHERE `id` = ?")).
+			WithArgs("expired", "reservation-1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectCommit()
+
+		client.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: api.ResponseAccept}).
+			Expect().Status(http.StatusGone)
+	})
+
+	It("fails answering an unknown invitation", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("unknown").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		client.POST(`/rsvp/unknown`).WithJSON(api.RespondInvitationRequest{Response: api.ResponseAccept}).
+			Expect().Status(http.StatusNotFound)
+	})
+
+	It("fails answering with an unknown response", func() {
+		client.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: "maybe"}).
+			Expect().Status(http.StatusBadRequest)
+	})
+})
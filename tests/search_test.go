@@ -0,0 +1,160 @@
+package tests_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/search"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Search", func() {
+	names := func(results []search.Result) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+
+	It("normalizes the names case and accent insensitive", func() {
+		Expect(search.Normalize("  José NÚÑEZ-o'Brien ")).To(Equal("jose nunez o brien"))
+		Expect(search.Trigrams("Ana Li")).To(Equal([]string{"  a", " an", "ana", "na ", "  l", " li", "li "}))
+
+		// the last word of a query matches the words it is a prefix of
+		Expect(search.QueryTrigrams("Jo")).To(Equal([]string{"  j", " jo"}))
+	})
+
+	It("ranks the exact and prefix matches before the misspelled names", func() {
+		candidates := []search.Candidate{
+			{Name: "Mary Johnson", Hits: 2},
+			{Name: "John Smith", Hits: 2},
+			{Name: "Joanna Stone", Hits: 2},
+		}
+		// the shorter names are closer to the query
+		Expect(names(search.Rank("jo", candidates))).To(Equal([]string{"John Smith", "Joanna Stone", "Mary Johnson"}))
+
+		candidates = []search.Candidate{
+			{Name: "John Smith", Hits: 7},
+			{Name: "Jon Smythe", Hits: 6},
+			{Name: "Joan Sims", Hits: 4},
+			{Name: "Bob Smart", Hits: 2},
+		}
+		results := search.Rank("Jon Smith", candidates)
+		Expect(names(results)).To(Equal([]string{"John Smith", "Jon Smythe", "Joan Sims"}))
+		Expect(results[0].Score).To(BeNumerically("~", 7.0/9, 0.001))
+
+		Expect(search.Rank("jöhn SMITH", candidates)[0]).To(Equal(search.Result{Name: "John Smith", Score: 1}))
+	})
+})
+
+var _ = Describe("Search controller", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("indexes the trigrams of the created reservations", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams` (`trigram`,`reservation_id`) VALUES (?,?),(?,?),(?,?),(?,?),(?,?),(?,?),(?,?)")).
+			WithArgs("  a", anyID{}, " an", anyID{}, "ana", anyID{}, "na ", anyID{}, "  l", anyID{}, " li", anyID{}, "li ", anyID{}).
+			WillReturnResult(sqlmock.NewResult(0, 7))
+
+		mock.ExpectCommit()
+
+		client.POST(`/guest_list/Ana Li`).WithJSON(api.CreateReservationRequest{Table: 1}).
+			Expect().Status(http.StatusCreated)
+	})
+
+	It("finds the reservations through the trigram index", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT reservation_id AS id, COUNT(*) AS hits FROM `reservation_trigrams` WHERE trigram IN (?,?,?,?,?,?,?,?,?) GROUP BY `reservation_id` ORDER BY hits DESC, reservation_id LIMIT 10")).
+			WithArgs("  j", " jo", "jon", "on ", "  s", " sm", "smi", "mit", "ith").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "hits"}).AddRow("reservation-1", 7).AddRow("reservation-2", 2))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id IN (?,?)")).WithArgs("reservation-1", "reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).
+				AddRow("reservation-2", "Mary Johnson", 0, 2, "accepted").
+				AddRow("reservation-1", "John Smith", 2, 3, "accepted"))
+
+		mock.ExpectCommit()
+
+		results := client.GET(`/guest_list/search`).WithQuery("q", "jon smith").WithQuery("limit", 2).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Value("results").Array()
+
+		// too few trigrams of the query are in the other name
+		results.Length().Equal(1)
+		results.Element(0).Object().
+			ValueEqual("id", "reservation-1").
+			ValueEqual("name", "John Smith").
+			ValueEqual("table", 3).
+			ValueEqual("party_size", 3).
+			ValueEqual("status", "accepted")
+	})
+
+	It("fails searching without a query", func() {
+		client.GET(`/guest_list/search`).WithQuery("q", " - ").
+			Expect().Status(http.StatusBadRequest).
+			Header(api.ErrorCodeHeader).Equal(api.CodeInvalidRequest)
+	})
+})
@@ -0,0 +1,223 @@
+package tests_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/models"
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// placementsQuery loads the seating constraints along the tables and zones of their reservations
+const placementsQuery = "SELECT seating_constraints.id, seating_constraints.kind, seating_constraints.reservation_id, seating_constraints.other_id, seating_constraints.note, " +
+	"holder.table_id, holder_table.zone, other.table_id AS other_table_id, other_table.zone AS other_zone FROM `seating_constraints` " +
+	"JOIN reservations holder ON holder.id = seating_constraints.reservation_id JOIN reservations other ON other.id = seating_constraints.other_id " +
+	"JOIN tables holder_table ON holder_table.id = holder.table_id JOIN tables other_table ON other_table.id = other.table_id WHERE "
+
+// pendingCondition skips the placements of the declined and expired invitations, the ones whose deadline passed included
+const pendingCondition = "holder.status NOT IN (?,?) AND (holder.status <> ? OR holder.respond_by IS NULL OR holder.respond_by >= ?) AND " +
+	"other.status NOT IN (?,?) AND (other.status <> ? OR other.respond_by IS NULL OR other.respond_by >= ?)"
+
+// placementRows returns the rows of the placements query
+func placementRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "kind", "reservation_id", "other_id", "note", "table_id", "zone", "other_table_id", "other_zone"})
+}
+
+var _ = Describe("Seating constraints", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with a service seating the walk-ins, the garden next to the terrace
+		zones, err := party.ParseAdjacentZones("garden:terrace")
+		Expect(err).NotTo(HaveOccurred())
+		handler := new(api.Handler).WithService(party.New(gdb).WithWalkIns(true).WithAdjacentZones(zones))
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	expectReservation := func(id string, table int) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "table_id", "status"}).AddRow(id, "lastname", table, "invited"))
+	}
+
+	It("binds two reservations by a constraint", func() {
+		mock.ExpectBegin()
+		expectReservation("reservation-1", 1)
+		expectReservation("reservation-2", 2)
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `seating_constraints` (`kind`,`reservation_id`,`other_id`,`note`) VALUES (?,?,?,?)")).
+			WithArgs("different_table", "reservation-1", "reservation-2", "exes").
+			WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectCommit()
+
+		client.POST(`/seating/constraints`).
+			WithJSON(api.CreateConstraintRequest{Kind: models.ConstraintDifferentTable, Reservation: "reservation-1", Other: "reservation-2", Note: "exes"}).
+			Expect().Status(http.StatusCreated).
+			JSON().Equal(models.SeatingConstraint{ID: 4, Kind: "different_table", ReservationID: "reservation-1", OtherID: "reservation-2", Note: "exes"})
+	})
+
+	It("validates the constraints", func() {
+		client.POST(`/seating/constraints`).
+			WithJSON(api.CreateConstraintRequest{Kind: "next_to", Reservation: "reservation-1", Other: "reservation-2"}).
+			Expect().Status(http.StatusBadRequest).
+			Header(api.ErrorCodeHeader).Equal(api.CodeInvalidRequest)
+
+		client.POST(`/seating/constraints`).
+			WithJSON(api.CreateConstraintRequest{Kind: models.ConstraintSameTable, Reservation: "reservation-1", Other: "reservation-1"}).
+			Expect().Status(http.StatusBadRequest).
+			Header(api.ErrorCodeHeader).Equal(api.CodeInvalidRequest)
+	})
+
+	It("reports the constraints broken by the plan", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(placementsQuery+pendingCondition+" ORDER BY seating_constraints.id")).
+			WithArgs("declined", "expired", "invited", sqlmock.AnyArg(), "declined", "expired", "invited", sqlmock.AnyArg()).
+			WillReturnRows(placementRows().
+				AddRow(1, "same_table", "reservation-1", "reservation-2", "", 1, "garden", 2, "garden").
+				AddRow(2, "different_table", "reservation-3", "reservation-4", "exes", 3, "hall", 3, "hall").
+				AddRow(3, "adjacent_zone", "reservation-1", "reservation-5", "", 1, "garden", 5, "terrace").
+				AddRow(4, "adjacent_zone", "reservation-1", "reservation-3", "", 1, "garden", 3, "hall"))
+		mock.ExpectCommit()
+
+		client.GET(`/seating/violations`).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Value("violations").Array().Equal([]api.Violation{
+			{Constraint: 1, Kind: "same_table", Reservation: "reservation-1", Table: 1, Zone: "garden", Other: "reservation-2", OtherTable: 2, OtherZone: "garden", Reason: "seated at the tables 1 and 2"},
+			{Constraint: 2, Kind: "different_table", Reservation: "reservation-3", Table: 3, Zone: "hall", Other: "reservation-4", OtherTable: 3, OtherZone: "hall", Note: "exes", Reason: "both seated at the table 3"},
+			{Constraint: 4, Kind: "adjacent_zone", Reservation: "reservation-1", Table: 1, Zone: "garden", Other: "reservation-3", OtherTable: 3, OtherZone: "hall", Reason: `seated in the zones "garden" and "hall", which are not adjacent`},
+		})
+	})
+
+	It("rejects moving a reservation away from the one it sits with", func() {
+		table := 2
+
+		mock.ExpectBegin()
+		expectReservation("reservation-1", 1)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(placementsQuery+"("+pendingCondition+") AND (seating_constraints.reservation_id = ? OR seating_constraints.other_id = ?) ORDER BY seating_constraints.id")).
+			WithArgs("declined", "expired", "invited", sqlmock.AnyArg(), "declined", "expired", "invited", sqlmock.AnyArg(), "reservation-1", "reservation-1").
+			WillReturnRows(placementRows().AddRow(1, "same_table", "reservation-2", "reservation-1", "", 1, "garden", 2, "garden"))
+		mock.ExpectRollback()
+
+		client.PUT(`/reservations/reservation-1`).WithJSON(api.UpdateReservationRequest{Table: &table}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeConstraintViolated)
+	})
+
+	It("rejects accepting again a declined invitation away from the one it sits with", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("secret").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "table_id", "status", "token"}).AddRow("reservation-1", "lastname", 1, "declined", "secret"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (status = ? AND id <> ?) AND (ends_at IS NULL OR ends_at > ?) AND `reservations`.`table_id` = ?")).
+			WithArgs("accepted", "reservation-1", sqlmock.AnyArg(), 1).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		// the constraints skipped while declined bind it again
+		mock.ExpectQuery(regexp.QuoteMeta(placementsQuery)).
+			WillReturnRows(placementRows().AddRow(1, "same_table", "reservation-2", "reservation-1", "", 2, "garden", 1, "garden"))
+		mock.ExpectRollback()
+
+		client.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: api.ResponseAccept}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeConstraintViolated)
+	})
+
+	It("seats a walk-in at the table of the reservation it sits with", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(eventQuery).WithArgs(1).WillReturnRows(eventRows(true))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT reservations.table_id, tables.zone FROM `reservations` JOIN tables ON tables.id = reservations.table_id WHERE reservations.id = ?")).
+			WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"table_id", "zone"}).AddRow(3, "garden"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tables.id, tables.capacity - COALESCE(SUM(GREATEST(1 + reservations.accompanying_guests, COALESCE(1 + guests.accompanying_guests, 0))), 0) AS free FROM `tables` "+
+			"LEFT JOIN reservations ON reservations.table_id = tables.id AND reservations.status = ? AND (reservations.ends_at IS NULL OR reservations.ends_at > ?) LEFT JOIN guests ON guests.reservation_id = reservations.id "+
+			"WHERE tables.id = ? GROUP BY tables.id, tables.capacity HAVING free >= ? ORDER BY free, tables.id LIMIT 1")).
+			WithArgs("accepted", sqlmock.AnyArg(), 3, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "free"}).AddRow(3, 2))
+
+		// the reservation takes the seats booked
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(3, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (status = ? AND id <> ?) AND (ends_at IS NULL OR ends_at > ?) AND `reservations`.`table_id` = ?")).
+			WithArgs("accepted", anyID{}, sqlmock.AnyArg(), 3).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status"}).AddRow("lastname", 3, 3, "accepted"))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// bound to the reservation it sits with
+		expectReservation("reservation-2", 3)
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `seating_constraints` (`kind`,`reservation_id`,`other_id`,`note`) VALUES (?,?,?,?)")).
+			WithArgs("same_table", anyID{}, "reservation-2", "").
+			WillReturnResult(sqlmock.NewResult(5, 1))
+
+		// and its guest the seats present
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(3, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectCommit()
+
+		client.POST(`/walk_ins`).WithJSON(api.CreateWalkInRequest{Name: "Ana Torres", Persons: 2, Constraints: []api.Rule{{Kind: models.ConstraintSameTable, With: "reservation-2"}}}).
+			Expect().Status(http.StatusCreated).
+			JSON().Object().ValueEqual("table", 3)
+	})
+})
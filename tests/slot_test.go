@@ -0,0 +1,208 @@
+package tests_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Sittings", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with a service checking in the parties up to 15 minutes before their slot
+		handler := new(api.Handler).WithService(party.New(gdb).WithGracePeriods(15*time.Minute, 0))
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	// sitting returns the reservation of the sitting starting at the given moment and lasting two hours
+	sitting := func(status string, startsAt time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "starts_at", "ends_at", "status", "token"}).
+			AddRow("reservation-1", "username", 3, 1, startsAt, startsAt.Add(2*time.Hour), status, "secret")
+	}
+
+	It("accepts an invitation at a table only full in the other sitting", func() {
+		second := time.Date(2021, 6, 20, 20, 30, 0, 0, time.UTC)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("secret").
+			WillReturnRows(sitting("invited", second))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4))
+
+		// only the reservations overlapping the sitting take its seats
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (status = ? AND id <> ?) AND (starts_at IS NULL OR (starts_at < ? AND ends_at > ?)) AND `reservations`.`table_id` = ?")).
+			WithArgs("accepted", "reservation-1", second.Add(2*time.Hour), second, 1).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(placementsQuery)).
+			WillReturnRows(placementRows())
+		mock.ExpectCommit()
+
+		client.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: api.ResponseAccept}).
+			Expect().Status(http.StatusOK).
+			JSON().Object().ValueEqual("status", "accepted")
+	})
+
+	It("books the seats of a sitting at its busiest moment", func() {
+		second := time.Date(2021, 6, 20, 19, 0, 0, 0, time.UTC)
+		booked := func(rows *sqlmock.Rows) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE token = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("secret").
+				WillReturnRows(sitting("invited", second))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (status = ? AND id <> ?) AND (starts_at IS NULL OR (starts_at < ? AND ends_at > ?)) AND `reservations`.`table_id` = ?")).
+				WithArgs("accepted", "reservation-1", second.Add(2*time.Hour), second, 1).
+				WillReturnRows(rows)
+		}
+		slot := func(rows *sqlmock.Rows, id string, startsAt, endsAt time.Time) *sqlmock.Rows {
+			return rows.AddRow(id, "lastname", 1, 1, startsAt, endsAt, "accepted", id)
+		}
+		rows := func() *sqlmock.Rows {
+			return sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "starts_at", "ends_at", "status", "token"})
+		}
+
+		// the sittings before and after it each overlap the party of 4, never both at once
+		booked(slot(slot(rows(), "reservation-2", second.Add(-90*time.Minute), second.Add(30*time.Minute)),
+			"reservation-3", second.Add(time.Hour), second.Add(3*time.Hour)))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(placementsQuery)).
+			WillReturnRows(placementRows())
+		mock.ExpectCommit()
+
+		client.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: api.ResponseAccept}).
+			Expect().Status(http.StatusOK)
+
+		// a party joining the later sitting while the party of 4 is seated fills the table
+		booked(slot(slot(rows(), "reservation-3", second.Add(time.Hour), second.Add(3*time.Hour)),
+			"reservation-4", second.Add(75*time.Minute), second.Add(2*time.Hour)))
+		mock.ExpectRollback()
+
+		client.POST(`/rsvp/secret`).WithJSON(api.RespondInvitationRequest{Response: api.ResponseAccept}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeCapacityExceeded)
+	})
+
+	It("validates the slot of the reservations", func() {
+		startsAt := time.Date(2021, 6, 20, 18, 0, 0, 0, time.UTC)
+		endsAt := startsAt.Add(-time.Hour)
+
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, StartsAt: &startsAt}).
+			Expect().Status(http.StatusBadRequest)
+
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, StartsAt: &startsAt, EndsAt: &endsAt}).
+			Expect().Status(http.StatusBadRequest)
+	})
+
+	It("checks in the parties arriving within the grace period before their slot", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sitting("accepted", time.Now().Add(10*time.Minute)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 4))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		client.PUT(`/reservations/reservation-1/guest`).WithJSON(api.CreateGuestRequest{Arriving: 2}).
+			Expect().Status(http.StatusCreated).
+			JSON().Object().ValueEqual("arrived", 2)
+	})
+
+	It("rejects the parties arriving out of their slot", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sitting("accepted", time.Now().Add(time.Hour)))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		client.PUT(`/reservations/reservation-1/guest`).WithJSON(api.CreateGuestRequest{Arriving: 4}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeOutsideSlot)
+	})
+
+	It("retrieves the seats not booked at a moment", func() {
+		at := time.Date(2021, 6, 20, 21, 0, 0, 0, time.UTC)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tables.zone, SUM(tables.capacity) AS capacity, SUM(tables.capacity - COALESCE(booked.persons, 0)) AS seats_empty FROM `tables` "+
+			"LEFT JOIN (SELECT table_id, SUM(1 + accompanying_guests) AS persons FROM `reservations` WHERE status = ? AND (starts_at IS NULL OR (starts_at <= ? AND ends_at > ?)) GROUP BY `table_id`) booked "+
+			"ON booked.table_id = tables.id GROUP BY `tables`.`zone` ORDER BY tables.zone")).
+			WithArgs("accepted", at, at).
+			WillReturnRows(sqlmock.NewRows([]string{"zone", "capacity", "seats_empty"}).AddRow("garden", 4, 0).AddRow("hall", 6, 2))
+		mock.ExpectCommit()
+
+		client.GET(`/seats_empty`).WithQuery("at", at.Format(time.RFC3339)).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Equal(api.GetSeatsEmptyRespose{SeatsEmpty: 2, Zones: []api.ZoneSeats{
+			{Zone: "garden", Capacity: 4, SeatsEmpty: 0},
+			{Zone: "hall", Capacity: 6, SeatsEmpty: 2},
+		}})
+
+		client.GET(`/seats_empty`).WithQuery("at", "tonight").
+			Expect().Status(http.StatusBadRequest)
+	})
+})
@@ -0,0 +1,156 @@
+package tests_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var _ = Describe("Stats", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	first := time.Date(2021, 6, 26, 19, 0, 0, 0, time.UTC)
+	last := time.Date(2021, 6, 26, 19, 40, 12, 0, time.UTC)
+
+	// expectStats expects the queries of the stats of an event of two tables and five accepted reservations, one of
+	// them not arrived, another one whose slot has not started and another one gone after the peak, the arrivals
+	// counted per buckets of the given seconds
+	expectStats := func(bucket int64) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) AS reservations, "+
+			"COALESCE(SUM(CASE WHEN reservations.starts_at IS NULL OR reservations.starts_at <= ? THEN 1 ELSE 0 END), 0) AS expected, "+
+			"COALESCE(SUM(CASE WHEN reservations.arrived_at IS NULL AND (reservations.starts_at IS NULL OR reservations.starts_at <= ?) THEN 1 ELSE 0 END), 0) AS no_shows, "+
+			"COALESCE(AVG(1 + reservations.accompanying_guests), 0) AS party_size FROM `reservations` WHERE reservations.status = ?")).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "accepted").
+			WillReturnRows(sqlmock.NewRows([]string{"reservations", "expected", "no_shows", "party_size"}).AddRow(5, 4, 1, 2.6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT FLOOR(TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', created_at) / ?) * ? AS bucket, COUNT(DISTINCT reservation_id) AS parties, SUM(persons) AS persons FROM `movements` WHERE persons > 0 GROUP BY `bucket` ORDER BY bucket")).
+			WithArgs(bucket, bucket).
+			WillReturnRows(sqlmock.NewRows([]string{"bucket", "parties", "persons"}).
+				AddRow(first.Unix(), 2, 5).
+				AddRow(first.Add(30*time.Minute).Unix(), 1, 4))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT (SELECT SUM(earlier.persons) FROM movements earlier " +
+			"WHERE earlier.created_at < movements.created_at OR (earlier.created_at = movements.created_at AND earlier.id <= movements.id)) AS persons, " +
+			"movements.created_at AS at FROM `movements` ORDER BY persons DESC, movements.created_at, movements.id LIMIT 1")).
+			WillReturnRows(sqlmock.NewRows([]string{"persons", "at"}).AddRow(9, last))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tables.id, tables.label, tables.zone, tables.capacity, COALESCE(booked.persons, 0) AS booked, COALESCE(present.persons, 0) AS present FROM `tables` "+
+			"LEFT JOIN (SELECT table_id, MAX(persons) AS persons FROM (SELECT sittings.table_id, SUM(1 + reservations.accompanying_guests) AS persons FROM (SELECT DISTINCT table_id, starts_at FROM `reservations` WHERE status = ?) sittings "+
+			"JOIN reservations ON reservations.table_id = sittings.table_id AND reservations.status = ? AND (reservations.starts_at IS NULL OR (reservations.starts_at <= sittings.starts_at AND reservations.ends_at > sittings.starts_at)) "+
+			"GROUP BY sittings.table_id, sittings.starts_at) seated GROUP BY `table_id`) booked ON booked.table_id = tables.id "+
+			"LEFT JOIN (SELECT table_id, SUM(1 + accompanying_guests) AS persons FROM `guests` GROUP BY `table_id`) present ON present.table_id = tables.id ORDER BY tables.id")).
+			WithArgs("accepted", "accepted").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "label", "zone", "capacity", "booked", "present"}).
+				AddRow(1, "VIP-A", "garden", 8, 8, 6).
+				AddRow(2, "", "", 5, 5, 0))
+		mock.ExpectCommit()
+	}
+
+	It("reports the arrivals, no-shows and use of the tables", func() {
+		expectStats(1800)
+
+		client.GET(`/stats`).WithQuery("bucket", "30m").
+			Expect().Status(http.StatusOK).
+			JSON().Equal(api.GetStatsResponse{
+			Reservations:     5,
+			Expected:         4,
+			NoShows:          1,
+			NoShowRate:       0.25,
+			AveragePartySize: 2.6,
+			Bucket:           "30m0s",
+			Arrivals: []api.ArrivalBucket{
+				{Start: first, Parties: 2, Persons: 5},
+				{Start: first.Add(30 * time.Minute), Parties: 1, Persons: 4},
+			},
+			Peak: api.Peak{Persons: 9, At: &last},
+			Tables: []api.TableUse{
+				{Table: 1, Label: "VIP-A", Zone: "garden", Capacity: 8, Booked: 8, Present: 6, Utilization: 0.75},
+				{Table: 2, Capacity: 5, Booked: 5},
+			},
+		})
+	})
+
+	It("reports the stats as CSV", func() {
+		expectStats(900)
+
+		client.GET(`/stats`).WithQuery("format", "csv").
+			Expect().Status(http.StatusOK).
+			ContentType("text/csv").
+			Body().Equal("metric,value\n" +
+			"reservations,5\nexpected,4\nno_shows,1\nno_show_rate,0.2500\naverage_party_size,2.6000\npeak_persons,9\npeak_at,2021-06-26T19:40:12Z\n" +
+			"\n" +
+			"bucket_start,parties,persons\n" +
+			"2021-06-26T19:00:00Z,2,5\n2021-06-26T19:30:00Z,1,4\n" +
+			"\n" +
+			"table,label,zone,capacity,booked,present,utilization\n" +
+			"1,VIP-A,garden,8,8,6,0.7500\n2,,,5,5,0,0.0000\n")
+	})
+
+	It("validates the bucket and the format", func() {
+		client.GET(`/stats`).WithQuery("bucket", "soon").
+			Expect().Status(http.StatusBadRequest).
+			Header(api.ErrorCodeHeader).Equal(api.CodeInvalidRequest)
+
+		client.GET(`/stats`).WithQuery("bucket", "1500ms").
+			Expect().Status(http.StatusBadRequest).
+			Header(api.ErrorCodeHeader).Equal(api.CodeInvalidRequest)
+
+		client.GET(`/stats`).WithQuery("format", "xlsx").
+			Expect().Status(http.StatusBadRequest).
+			Header(api.ErrorCodeHeader).Equal(api.CodeInvalidRequest)
+	})
+})
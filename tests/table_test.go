@@ -67,7 +67,7 @@ var _ = Describe("Table controller", func() {
 
 	It("succeed creating a valid table", func() {
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`capacity`) VALUES (?)")).WithArgs(4).
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).WithArgs("", "", 4, "").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
@@ -76,6 +76,37 @@ var _ = Describe("Table controller", func() {
 			JSON().Equal(models.Table{ID: 1, Capacity: 4})
 	})
 
+	It("creates a table with its label, zone and features", func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `tables` (`label`,`zone`,`capacity`,`features`) VALUES (?,?,?,?)")).
+			WithArgs("VIP-A", "garden", 4, "wheelchair-accessible,near-stage").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		client.POST(`/tables`).WithJSON(api.CreateTableRequest{Label: "VIP-A", Zone: "garden", Capacity: 4, Features: []string{"wheelchair-accessible", "near-stage"}}).
+			Expect().Status(http.StatusCreated).
+			JSON().Object().
+			ValueEqual("label", "VIP-A").
+			ValueEqual("zone", "garden").
+			ValueEqual("features", []string{"wheelchair-accessible", "near-stage"})
+
+		client.POST(`/tables`).WithJSON(api.CreateTableRequest{Capacity: 4, Features: []string{"Near Stage"}}).
+			Expect().Status(http.StatusBadRequest)
+	})
+
+	It("filters the tables by zone, features and capacity", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE zone = ? AND capacity >= ? AND FIND_IN_SET(?, tables.features)")).
+			WithArgs("garden", 4, "wheelchair-accessible").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "label", "zone", "capacity", "features"}).AddRow(3, "Table 3", "garden", 6, "wheelchair-accessible,high-chair"))
+		mock.ExpectCommit()
+
+		client.GET(`/tables`).WithQuery("zone", "garden").WithQuery("feature", "wheelchair-accessible").WithQuery("min_capacity", 4).
+			Expect().Status(http.StatusOK).
+			JSON().Array().
+			Elements(models.Table{ID: 3, Label: "Table 3", Zone: "garden", Capacity: 6, Features: models.Features{"wheelchair-accessible", "high-chair"}})
+	})
+
 	It("fails creating a table with 0 capacity", func() {
 		client.POST(`/tables`).WithJSON(api.CreateTableRequest{Capacity: 0}).
 			Expect().Status(http.StatusBadRequest)
@@ -91,8 +122,10 @@ var _ = Describe("Table controller", func() {
 			AddRow(1, 5).
 			AddRow(2, 4)
 
+		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables`")).
 			WillReturnRows(rows)
+		mock.ExpectCommit()
 
 		client.GET(`/tables`).
 			Expect().Status(http.StatusOK).
@@ -104,8 +137,10 @@ var _ = Describe("Table controller", func() {
 	})
 
 	It("retrieves the empty tables list", func() {
+		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables`")).
 			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectCommit()
 
 		client.GET(`/tables`).
 			Expect().Status(http.StatusOK).
@@ -113,14 +148,44 @@ var _ = Describe("Table controller", func() {
 	})
 
 	It("retrieves the empty seats", func() {
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT SUM(capacity) FROM `tables`")).
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(capacity), 0) FROM `tables`")).
 			WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(6))
 
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) + SUM(accompanying_guests) FROM `guests`")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) + COALESCE(SUM(accompanying_guests), 0) FROM `guests`")).
 			WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(2))
 
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tables.zone, SUM(tables.capacity) AS capacity, SUM(tables.capacity - COALESCE(present.persons, 0)) AS seats_empty FROM `tables` " +
+			"LEFT JOIN (SELECT table_id, SUM(1 + accompanying_guests) AS persons FROM `guests` GROUP BY `table_id`) present ON present.table_id = tables.id GROUP BY `tables`.`zone` ORDER BY tables.zone")).
+			WillReturnRows(sqlmock.NewRows([]string{"zone", "capacity", "seats_empty"}).AddRow("garden", 4, 2).AddRow("hall", 2, 2))
+		mock.ExpectCommit()
+
+		client.GET(`/seats_empty`).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Equal(api.GetSeatsEmptyRespose{SeatsEmpty: 4, Zones: []api.ZoneSeats{
+			{Zone: "garden", Capacity: 4, SeatsEmpty: 2},
+			{Zone: "hall", Capacity: 2, SeatsEmpty: 2},
+		}})
+	})
+
+	It("retrieves the empty seats before the guests arrive", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(capacity), 0) FROM `tables`")).
+			WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(6))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) + COALESCE(SUM(accompanying_guests), 0) FROM `guests`")).
+			WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(0))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tables.zone, SUM(tables.capacity) AS capacity, SUM(tables.capacity - COALESCE(present.persons, 0)) AS seats_empty FROM `tables` " +
+			"LEFT JOIN (SELECT table_id, SUM(1 + accompanying_guests) AS persons FROM `guests` GROUP BY `table_id`) present ON present.table_id = tables.id GROUP BY `tables`.`zone` ORDER BY tables.zone")).
+			WillReturnRows(sqlmock.NewRows([]string{"zone", "capacity", "seats_empty"}).AddRow("garden", 4, 4).AddRow("hall", 2, 2))
+		mock.ExpectCommit()
+
 		client.GET(`/seats_empty`).
 			Expect().Status(http.StatusOK).
-			JSON().Object().Equal(api.GetSeatsEmptyRespose{SeatsEmpty: 4})
+			JSON().Object().Equal(api.GetSeatsEmptyRespose{SeatsEmpty: 6, Zones: []api.ZoneSeats{
+			{Zone: "garden", Capacity: 4, SeatsEmpty: 4},
+			{Zone: "hall", Capacity: 2, SeatsEmpty: 2},
+		}})
 	})
 })
@@ -0,0 +1,408 @@
+package tests_test
+
+import (
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/amaury95/GetGround-Party/tickets"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// syncedQuery and syncedInsert are the lookup and the record of the offline check-ins applied by a sync
+var (
+	syncedQuery  = regexp.QuoteMeta("SELECT count(*) FROM `synced_check_ins` WHERE device = ? AND reservation_id = ? AND checked_in_at = ?")
+	syncedInsert = regexp.QuoteMeta("INSERT INTO `synced_check_ins` (`device`,`reservation_id`,`checked_in_at`,`created_at`) VALUES (?,?,?,?)")
+)
+
+var _ = Describe("Ticket controller", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+		issuer *tickets.Issuer
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create a deterministic signing key
+		issuer = tickets.NewIssuer(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb).WithTicketIssuer(issuer)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("exports the public key", func() {
+		client.GET(`/tickets/public_key`).
+			Expect().Status(http.StatusOK).
+			JSON().Object().
+			ValueEqual("algorithm", "Ed25519").
+			ValueEqual("key", base64.StdEncoding.EncodeToString(issuer.PublicKey()))
+	})
+
+	It("fails the ticket requests of a service without issuer", func() {
+		handler := new(api.Handler).WithTicketIssuer(issuer).WithService(party.New(nil))
+		disabled := httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+		defer disabled.Close()
+
+		httpexpect.New(GinkgoT(), disabled.URL).GET(`/tickets/public_key`).
+			Expect().Status(http.StatusNotImplemented).
+			Header(api.ErrorCodeHeader).Equal(api.CodeTicketsDisabled)
+	})
+
+	It("issues a verifiable ticket for a reservation", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("username").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 5, 1, "accepted"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `members` WHERE reservation_id = ? ORDER BY position")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectCommit()
+
+		token := client.GET(`/guest_list/username/ticket`).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Value("ticket").String().Raw()
+
+		ticket, err := tickets.Verify(issuer.PublicKey(), token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ticket.Reservation).To(Equal("reservation-1"))
+		Expect(ticket.Name).To(Equal("username"))
+		Expect(ticket.Table).To(Equal(1))
+		Expect(ticket.PartySize).To(Equal(6))
+		Expect(ticket.ExpiresAt).To(BeTemporally("~", ticket.IssuedAt.Add(party.DefaultTicketTTL), time.Second))
+	})
+
+	It("rejects an expired ticket", func() {
+		date := time.Date(2021, 6, 20, 20, 0, 0, 0, time.UTC)
+
+		token, err := issuer.Issue(tickets.Ticket{Name: "username", Table: 1, PartySize: 2, IssuedAt: date, ExpiresAt: date.Add(time.Hour)})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tickets.VerifyAt(issuer.PublicKey(), token, date.Add(30*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tickets.Verify(issuer.PublicKey(), token)
+		Expect(err).To(Equal(tickets.ErrExpiredTicket))
+
+		// the tickets without expiry never verify
+		token, err = issuer.Issue(tickets.Ticket{Name: "username", Table: 1, PartySize: 2})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tickets.Verify(issuer.PublicKey(), token)
+		Expect(err).To(Equal(tickets.ErrExpiredTicket))
+	})
+
+	It("rejects a tampered ticket", func() {
+		token, err := issuer.Issue(tickets.Ticket{Name: "username", Table: 1, PartySize: 2})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tickets.Verify(issuer.PublicKey(), "x"+token)
+		Expect(err).To(Equal(tickets.ErrInvalidTicket))
+	})
+
+	It("syncs offline check-ins reporting conflicts", func() {
+		date := time.Date(2021, 6, 20, 20, 0, 0, 0, time.UTC)
+		issued, expires := date.Add(-24*time.Hour), date.Add(24*time.Hour)
+
+		valid, err := issuer.Issue(tickets.Ticket{Reservation: "reservation-1", Name: "username", Table: 1, PartySize: 3, IssuedAt: issued, ExpiresAt: expires})
+		Expect(err).NotTo(HaveOccurred())
+
+		// tickets issued before the reservations had ids reference them by name
+		repeated, err := issuer.Issue(tickets.Ticket{Name: "lastname", Table: 1, PartySize: 2, IssuedAt: issued, ExpiresAt: expires})
+		Expect(err).NotTo(HaveOccurred())
+
+		// first check-in by device time, each check-in is applied in its own transaction
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 2, 1, "accepted"))
+
+		mock.ExpectQuery(syncedQuery).WithArgs("door-1", "reservation-1", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(nil))
+
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "reservation-1", "username", 2, false, 1, date).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(date, "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 3, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(syncedInsert).WithArgs("door-1", "reservation-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(sqlmock.AnyArg(), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectCommit()
+
+		// second check-in brings more persons than booked
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE name = ? ORDER BY id")).WithArgs("lastname").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-2", "lastname", 1, 1, "accepted"))
+
+		mock.ExpectQuery(syncedQuery).WithArgs("door-1", "reservation-2", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id"}).AddRow("guest-2", "reservation-2", "lastname", 1, 1))
+
+		mock.ExpectQuery(freeSeatsQuery).WithArgs("accepted", "reservation-2", sqlmock.AnyArg(), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"free"}).AddRow(3))
+
+		mock.ExpectRollback()
+
+		resp := client.POST(`/checkins/sync`).WithJSON(api.SyncCheckInsRequest{
+			Device: "door-1",
+			CheckIns: []api.SyncCheckIn{
+				{Ticket: repeated, AccompanyingGuests: 1, CheckedInAt: date.Add(time.Minute)},
+				{Ticket: "invalid", AccompanyingGuests: 1, CheckedInAt: date.Add(time.Hour)},
+				{Ticket: valid, AccompanyingGuests: 2, CheckedInAt: date},
+			},
+		}).Expect().Status(http.StatusOK).JSON().Object()
+
+		resp.Value("accepted").Array().Elements("username")
+		resp.Value("conflicts").Array().Length().Equal(2)
+		resp.Value("conflicts").Array().Element(0).Object().ValueEqual("name", "lastname")
+		resp.Value("conflicts").Array().Element(1).Object().ValueEqual("reason", tickets.ErrInvalidTicket.Error())
+	})
+
+	It("does not mark the members of a party synced in part", func() {
+		date := time.Date(2021, 6, 20, 20, 0, 0, 0, time.UTC)
+		issued, expires := date.Add(-24*time.Hour), date.Add(24*time.Hour)
+
+		ticket, err := issuer.Issue(tickets.Ticket{Reservation: "reservation-1", Name: "username", Table: 1, PartySize: 4, IssuedAt: issued, ExpiresAt: expires})
+		Expect(err).NotTo(HaveOccurred())
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 3, 1, "accepted"))
+		mock.ExpectQuery(syncedQuery).WithArgs("door-1", "reservation-1", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "reservation-1", "username", 1, false, 1, date).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(date, "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 2, date).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(syncedInsert).WithArgs("door-1", "reservation-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		client.POST(`/checkins/sync`).WithJSON(api.SyncCheckInsRequest{
+			Device:   "door-1",
+			CheckIns: []api.SyncCheckIn{{Ticket: ticket, AccompanyingGuests: 1, CheckedInAt: date}},
+		}).Expect().Status(http.StatusOK).JSON().Object().
+			Value("accepted").Array().Elements("username")
+	})
+
+	It("adds the persons of a party synced in stages", func() {
+		date := time.Date(2021, 6, 20, 20, 0, 0, 0, time.UTC)
+		issued, expires := date.Add(-24*time.Hour), date.Add(24*time.Hour)
+
+		ticket, err := issuer.Issue(tickets.Ticket{Reservation: "reservation-1", Name: "username", Table: 1, PartySize: 3, IssuedAt: issued, ExpiresAt: expires})
+		Expect(err).NotTo(HaveOccurred())
+
+		// one person arrived first, the other two join
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status", "arrived_at"}).AddRow("reservation-1", "username", 2, 1, "accepted", date))
+		mock.ExpectQuery(syncedQuery).WithArgs("door-1", "reservation-1", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reservation_id", "name", "accompanying_guests", "table_id", "created_at"}).AddRow("guest-1", "reservation-1", "username", 0, 1, date))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "accompanying_guests", "table_id"}).AddRow("guest-1", 0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `guests` SET `reservation_id`=?,`name`=?,`accompanying_guests`=?,`oversize`=?,`table_id`=?,`created_at`=? WHERE `id` = ?")).
+			WithArgs("reservation-1", "username", 2, false, 1, date, "guest-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 2, date.Add(10*time.Minute)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(syncedInsert).WithArgs("door-1", "reservation-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(date.Add(10*time.Minute), "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		client.POST(`/checkins/sync`).WithJSON(api.SyncCheckInsRequest{
+			Device:   "door-1",
+			CheckIns: []api.SyncCheckIn{{Ticket: ticket, Arriving: 2, CheckedInAt: date.Add(10 * time.Minute)}},
+		}).Expect().Status(http.StatusOK).JSON().Object().
+			Value("accepted").Array().Elements("username")
+	})
+
+	It("does not count again the offline check-ins of a batch sent twice", func() {
+		date := time.Date(2021, 6, 20, 20, 0, 0, 0, time.UTC)
+		issued, expires := date.Add(-24*time.Hour), date.Add(24*time.Hour)
+
+		ticket, err := issuer.Issue(tickets.Ticket{Reservation: "reservation-1", Name: "username", Table: 1, PartySize: 2, IssuedAt: issued, ExpiresAt: expires})
+		Expect(err).NotTo(HaveOccurred())
+
+		batch := api.SyncCheckInsRequest{
+			Device:   "door-1",
+			CheckIns: []api.SyncCheckIn{{Ticket: ticket, AccompanyingGuests: 1, CheckedInAt: date}},
+		}
+
+		// first sync registers the guest
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status"}).AddRow("reservation-1", "username", 1, 1, "accepted"))
+		mock.ExpectQuery(syncedQuery).WithArgs("door-1", "reservation-1", date).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "reservation-1", "username", 1, false, 1, date).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `reservations` SET `arrived_at`=? WHERE `id` = ?")).WithArgs(date, "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs("reservation-1", 2, date).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(syncedInsert).WithArgs("door-1", "reservation-1", date, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `members` SET `arrived_at`=? WHERE reservation_id = ? AND arrived_at IS NULL")).WithArgs(date, "reservation-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		client.POST(`/checkins/sync`).WithJSON(batch).Expect().Status(http.StatusOK).JSON().Object().
+			Value("accepted").Array().Elements("username")
+
+		// the batch sent again is accepted without adding the persons to the guest nor recording their movement
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "status", "arrived_at"}).AddRow("reservation-1", "username", 1, 1, "accepted", date))
+		mock.ExpectQuery(syncedQuery).WithArgs("door-1", "reservation-1", date).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectRollback()
+
+		resp := client.POST(`/checkins/sync`).WithJSON(batch).Expect().Status(http.StatusOK).JSON().Object()
+
+		resp.Value("accepted").Array().Elements("username")
+		resp.Value("conflicts").Array().Empty()
+	})
+
+	It("reports the offline check-ins out of the slot of the reservation as conflicts", func() {
+		date := time.Date(2021, 6, 20, 20, 0, 0, 0, time.UTC)
+		issued, expires := date.Add(-24*time.Hour), date.Add(24*time.Hour)
+
+		ticket, err := issuer.Issue(tickets.Ticket{Reservation: "reservation-1", Name: "username", Table: 1, PartySize: 2, IssuedAt: issued, ExpiresAt: expires})
+		Expect(err).NotTo(HaveOccurred())
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE id = ? ORDER BY `reservations`.`id` LIMIT 1")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "accompanying_guests", "table_id", "starts_at", "ends_at", "status"}).
+				AddRow("reservation-1", "username", 1, 1, date, date.Add(2*time.Hour), "accepted"))
+		mock.ExpectQuery(syncedQuery).WithArgs("door-1", "reservation-1", date.Add(-time.Hour)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE reservation_id = ?")).WithArgs("reservation-1").
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		resp := client.POST(`/checkins/sync`).WithJSON(api.SyncCheckInsRequest{
+			Device:   "door-1",
+			CheckIns: []api.SyncCheckIn{{Ticket: ticket, AccompanyingGuests: 1, CheckedInAt: date.Add(-time.Hour)}},
+		}).Expect().Status(http.StatusOK).JSON().Object()
+
+		resp.Value("accepted").Array().Empty()
+		resp.Value("conflicts").Array().Length().Equal(1)
+		resp.Value("conflicts").Array().Element(0).Object().Value("reason").String().Contains("slot")
+	})
+
+	It("reports the offline check-ins with device timestamps out of the validity of their ticket as conflicts", func() {
+		date := time.Date(2021, 6, 20, 20, 0, 0, 0, time.UTC)
+		issued, expires := date.Add(-24*time.Hour), date.Add(24*time.Hour)
+
+		ticket, err := issuer.Issue(tickets.Ticket{Reservation: "reservation-1", Name: "username", Table: 1, PartySize: 2, IssuedAt: issued, ExpiresAt: expires})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp := client.POST(`/checkins/sync`).WithJSON(api.SyncCheckInsRequest{
+			Device: "door-1",
+			CheckIns: []api.SyncCheckIn{
+				{Ticket: ticket, AccompanyingGuests: 1},
+				{Ticket: ticket, AccompanyingGuests: 1, CheckedInAt: issued.Add(-time.Hour)},
+				{Ticket: ticket, AccompanyingGuests: 1, CheckedInAt: expires.Add(time.Hour)},
+				{Ticket: ticket, AccompanyingGuests: 1, CheckedInAt: time.Now().Add(time.Hour)},
+			},
+		}).Expect().Status(http.StatusOK).JSON().Object()
+
+		resp.Value("accepted").Array().Empty()
+		conflicts := resp.Value("conflicts").Array()
+		conflicts.Length().Equal(4)
+		conflicts.Element(0).Object().ValueEqual("reason", "missing check-in time")
+		conflicts.Element(1).Object().ValueEqual("name", "username").Value("reason").String().Contains("before the ticket was issued")
+		conflicts.Element(2).Object().ValueEqual("reason", tickets.ErrExpiredTicket.Error())
+		conflicts.Element(3).Object().Value("reason").String().Contains("ahead of the server clock")
+	})
+})
@@ -0,0 +1,164 @@
+package tests_test
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/tracing"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// spanNamed returns the recorded span with the given name
+func spanNamed(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	Fail("span " + name + " was not recorded")
+	return nil
+}
+
+// spanAttribute returns the value of the span attribute with the given key
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) string {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value.Emit()
+		}
+	}
+	return ""
+}
+
+var _ = Describe("Tracing", func() {
+	var (
+		mock     sqlmock.Sqlmock
+		server   *httptest.Server
+		client   *httpexpect.Expect
+		recorder *tracetest.SpanRecorder
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// record the spans in memory
+		recorder = tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		tracing.Register(provider)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(tracing.Instrument(gdb, provider)).To(Succeed())
+
+		// create handler with mock connection
+		handler := new(api.Handler).WithConnection(gdb).WithTracing(provider)
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		tracing.Register(trace.NewNoopTracerProvider())
+
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("nests the statements and the hook queries in the route span", func() {
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(1, 6))
+
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations`")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 8))
+
+		mock.ExpectCommit()
+
+		client.POST(`/guest_list/username`).WithJSON(api.CreateReservationRequest{Table: 1, AccompanyingGuests: 5}).
+			Expect().Status(http.StatusCreated)
+
+		spans := recorder.Ended()
+
+		route := spanNamed(spans, "/guest_list/:name")
+		create := spanNamed(spans, "gorm.create reservations")
+		hook := spanNamed(spans, "Reservation.BeforeCreate")
+		query := spanNamed(spans, "gorm.query tables")
+
+		Expect(route.SpanKind()).To(Equal(trace.SpanKindServer))
+		Expect(create.Parent().SpanID()).To(Equal(route.SpanContext().SpanID()))
+		Expect(hook.Parent().SpanID()).To(Equal(create.SpanContext().SpanID()))
+		Expect(query.Parent().SpanID()).To(Equal(hook.SpanContext().SpanID()))
+
+		Expect(spanAttribute(create, "db.statement")).To(HavePrefix("INSERT INTO `reservations`"))
+		Expect(spanAttribute(query, "db.statement")).To(HavePrefix("SELECT * FROM `tables`"))
+	})
+
+	It("continues the trace of the traceparent header", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests`")).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id"}))
+		mock.ExpectCommit()
+
+		client.GET(`/guests`).WithHeader("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01").
+			Expect().Status(http.StatusOK)
+
+		route := spanNamed(recorder.Ended(), "/guests")
+		Expect(route.SpanContext().TraceID().String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+		Expect(route.Parent().SpanID().String()).To(Equal("00f067aa0ba902b7"))
+		Expect(route.Parent().IsRemote()).To(BeTrue())
+	})
+
+	It("records the failed statements", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests`")).
+			WillReturnError(errors.New("connection lost"))
+		mock.ExpectRollback()
+
+		client.GET(`/guests`).
+			Expect().Status(http.StatusInternalServerError)
+
+		query := spanNamed(recorder.Ended(), "gorm.query guests")
+		Expect(query.Status().Code).To(Equal(codes.Error))
+		Expect(query.Status().Description).To(Equal("connection lost"))
+	})
+})
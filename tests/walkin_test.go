@@ -0,0 +1,169 @@
+package tests_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+
+	"github.com/amaury95/GetGround-Party/api"
+	"github.com/amaury95/GetGround-Party/party"
+	"github.com/gavv/httpexpect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// eventQuery loads the settings of the event changed while it runs
+var eventQuery = regexp.QuoteMeta("SELECT * FROM `events` WHERE id = ? LIMIT 1")
+
+// eventRows returns the rows of the event settings, the walk-ins left to the server default when nil
+func eventRows(walkIns interface{}) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "walk_ins", "updated_at"}).AddRow(1, walkIns, time.Now())
+}
+
+var _ = Describe("Walk-ins", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		server *httptest.Server
+		client *httpexpect.Expect
+	)
+
+	BeforeEach(func() {
+		var (
+			db  *sql.DB
+			err error
+		)
+
+		// get database mock
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		// mock database connection
+		gdb, err := gorm.Open(mysql.New(mysql.Config{
+			Conn:                      db,
+			SkipInitializeWithVersion: true,
+		}), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// create handler with a service seating the walk-ins
+		handler := new(api.Handler).WithService(party.New(gdb).WithWalkIns(true))
+
+		// setup test server
+		server = httptest.NewServer(handler.Router(&api.RouterConfig{
+			ReleaseMode: true,
+		}))
+
+		// setup http expect
+		client = httpexpect.New(GinkgoT(), server.URL)
+	})
+
+	AfterEach(func() {
+		// close server
+		server.Close()
+
+		// make sure all expectations were met
+		err := mock.ExpectationsWereMet()
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	freeTable := regexp.QuoteMeta("SELECT tables.id, tables.capacity - COALESCE(SUM(GREATEST(1 + reservations.accompanying_guests, COALESCE(1 + guests.accompanying_guests, 0))), 0) AS free FROM `tables` " +
+		"LEFT JOIN reservations ON reservations.table_id = tables.id AND reservations.status = ? AND (reservations.ends_at IS NULL OR reservations.ends_at > ?) LEFT JOIN guests ON guests.reservation_id = reservations.id " +
+		"GROUP BY tables.id, tables.capacity HAVING free >= ? ORDER BY free, tables.id LIMIT 1")
+
+	It("seats the walk-in at the table with the fewest free seats fitting the party", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(eventQuery).WithArgs(1).WillReturnRows(sqlmock.NewRows(nil))
+
+		mock.ExpectQuery(freeTable).WithArgs("accepted", sqlmock.AnyArg(), 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "free"}).AddRow(2, 4))
+
+		// the reservation takes the seats booked
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(2, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE (status = ? AND id <> ?) AND (ends_at IS NULL OR ends_at > ?) AND `reservations`.`table_id` = ?")).
+			WithArgs("accepted", anyID{}, sqlmock.AnyArg(), 2).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "accompanying_guests", "table_id", "status"}).AddRow("lastname", 1, 2, "accepted"))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservations` (`id`,`name`,`accompanying_guests`,`table_id`,`requirements`,`starts_at`,`ends_at`,`status`,`token`,`respond_by`,`responded_at`,`email`,`phone`,`source`,`arrived_at`) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, "Walk-in guest", 2, 2, "", nil, nil, "accepted", sqlmock.AnyArg(), nil, sqlmock.AnyArg(), "", "", "walk_in", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `reservation_trigrams`")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// and its guest the seats present
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tables` WHERE `tables`.`id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "capacity"}).AddRow(2, 6))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `guests` WHERE `guests`.`table_id` = ?")).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `guests` (`id`,`reservation_id`,`name`,`accompanying_guests`,`oversize`,`table_id`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+			WithArgs(anyID{}, anyID{}, "Walk-in guest", 2, false, 2, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movements` (`reservation_id`,`persons`,`created_at`) VALUES (?,?,?)")).WithArgs(anyID{}, 3, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectCommit()
+
+		client.POST(`/walk_ins`).WithJSON(api.CreateWalkInRequest{Persons: 3}).
+			Expect().Status(http.StatusCreated).
+			JSON().Object().
+			ValueEqual("name", "Walk-in guest").
+			ValueEqual("table", 2).
+			ValueEqual("arrived", 3)
+	})
+
+	It("fails when no table has enough free seats", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(eventQuery).WithArgs(1).WillReturnRows(eventRows(nil))
+		mock.ExpectQuery(freeTable).WithArgs("accepted", sqlmock.AnyArg(), 8).
+			WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectRollback()
+
+		client.POST(`/walk_ins`).WithJSON(api.CreateWalkInRequest{Name: "Ana Torres", Persons: 8}).
+			Expect().Status(http.StatusConflict).
+			Header(api.ErrorCodeHeader).Equal(api.CodeCapacityExceeded)
+	})
+
+	It("validates the walk-in party", func() {
+		client.POST(`/walk_ins`).WithJSON(api.CreateWalkInRequest{Persons: 0}).
+			Expect().Status(http.StatusBadRequest).
+			Header(api.ErrorCodeHeader).Equal(api.CodeInvalidRequest)
+	})
+
+	It("does not seat walk-ins once they are disabled for the event", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(eventQuery).WithArgs(1).WillReturnRows(eventRows(false))
+		mock.ExpectRollback()
+
+		client.POST(`/walk_ins`).WithJSON(api.CreateWalkInRequest{Persons: 2}).
+			Expect().Status(http.StatusForbidden).
+			Header(api.ErrorCodeHeader).Equal(api.CodeWalkInsDisabled)
+	})
+
+	It("toggles the walk-ins of the event at runtime", func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `events` (`walk_ins`,`updated_at`,`id`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `walk_ins`=VALUES(`walk_ins`),`updated_at`=VALUES(`updated_at`)")).
+			WithArgs(false, sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(eventQuery).WithArgs(1).WillReturnRows(eventRows(false))
+		mock.ExpectCommit()
+
+		client.PUT(`/event`).WithJSON(map[string]interface{}{"walk_ins": false}).
+			Expect().Status(http.StatusOK).
+			JSON().Object().ValueEqual("walk_ins", false)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(eventQuery).WithArgs(1).WillReturnRows(eventRows(false))
+		mock.ExpectCommit()
+
+		client.GET(`/event`).
+			Expect().Status(http.StatusOK).
+			JSON().Object().ValueEqual("walk_ins", false)
+	})
+})
@@ -0,0 +1,167 @@
+/*
+Package tickets holds the issuing and verification of the Ed25519 signed tickets handed to the guests.
+
+A ticket is self contained: door devices holding the exported public key can verify it without reaching the server.
+The encoded form of a ticket is composed by two base64 (raw url encoding) segments separated by a dot:
+
+	<payload>.<signature>
+
+where payload is the JSON representation of the Ticket and signature is the Ed25519 signature of the payload segment.
+Tickets are valid until the expiry signed in their payload, the ones without expiry are rejected.
+*/
+package tickets
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidTicket is returned when a ticket is malformed or its signature does not match the public key
+	ErrInvalidTicket = errors.New("invalid ticket")
+
+	// ErrExpiredTicket is returned when a ticket is verified past its expiry, or has none
+	ErrExpiredTicket = errors.New("expired ticket")
+)
+
+// Ticket is the information encoded into a signed ticket, Reservation is the id of the reservation of the guest
+type Ticket struct {
+	Reservation string    `json:"reservation,omitempty"`
+	Name        string    `json:"name"`
+	Table       int       `json:"table"`
+	PartySize   int       `json:"party_size"`
+	IssuedAt    time.Time `json:"issued_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Issuer signs tickets with an Ed25519 private key
+type Issuer struct {
+	key ed25519.PrivateKey
+}
+
+// NewIssuer returns an issuer signing with the given key
+func NewIssuer(key ed25519.PrivateKey) *Issuer {
+	return &Issuer{key: key}
+}
+
+// PublicKey returns the public key used by the door devices to verify tickets
+func (i *Issuer) PublicKey() ed25519.PublicKey {
+	return i.key.Public().(ed25519.PublicKey)
+}
+
+// Issue signs the ticket and returns its encoded form
+func (i *Issuer) Issue(t Ticket) (string, error) {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("error encoding ticket: %v", err)
+	}
+
+	segment := base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(i.key, []byte(segment))
+
+	return segment + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature of the encoded ticket against the public key and its expiry against the current time, and
+// returns its content
+func Verify(key ed25519.PublicKey, token string) (*Ticket, error) {
+	return VerifyAt(key, token, time.Now())
+}
+
+// VerifyAt checks the signature of the encoded ticket against the public key and that it had not expired at the given
+// moment, such as the arrival recorded by a door device, and returns its content
+func VerifyAt(key ed25519.PublicKey, token string, at time.Time) (*Ticket, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidTicket
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !ed25519.Verify(key, []byte(parts[0]), signature) {
+		return nil, ErrInvalidTicket
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidTicket
+	}
+
+	var t Ticket
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return nil, ErrInvalidTicket
+	}
+
+	if t.ExpiresAt.IsZero() || !at.Before(t.ExpiresAt) {
+		return nil, ErrExpiredTicket
+	}
+
+	return &t, nil
+}
+
+// EncodePublicKey returns the PEM (PKIX) representation of the public key
+func EncodePublicKey(key ed25519.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// LoadOrCreateKey reads the PEM (PKCS8) encoded private key at path.
+// If the file does not exist a new key is generated and stored on it.
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return createKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf(`no PEM data found in "%s"`, path)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %v", err)
+	}
+
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf(`key in "%s" is not an Ed25519 private key`, path)
+	}
+
+	return private, nil
+}
+
+func createKey(path string) (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding key: %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("error writing key file: %v", err)
+	}
+
+	return key, nil
+}
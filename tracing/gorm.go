@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	spanKey   = "tracing:span"
+	parentKey = "tracing:parent"
+)
+
+// Instrument registers the gorm callbacks tracing every statement as a child of the span carried by its context.
+// Statements run by the model hooks are children of the statement span that triggered them.
+func Instrument(db *gorm.DB, provider trace.TracerProvider) error {
+	tracer := provider.Tracer(instrumentationName + "/gorm")
+	cb := db.Callback()
+
+	hooks := []struct {
+		operation     string
+		before, after registerer
+	}{
+		{"create", cb.Create().Before("*"), cb.Create().After("*")},
+		{"query", cb.Query().Before("*"), cb.Query().After("*")},
+		{"update", cb.Update().Before("*"), cb.Update().After("*")},
+		{"delete", cb.Delete().Before("*"), cb.Delete().After("*")},
+		{"row", cb.Row().Before("*"), cb.Row().After("*")},
+		{"raw", cb.Raw().Before("*"), cb.Raw().After("*")},
+	}
+
+	for _, h := range hooks {
+		if err := h.before.Register("tracing:before_"+h.operation, before(tracer, h.operation)); err != nil {
+			return fmt.Errorf("error registering %s callback: %v", h.operation, err)
+		}
+
+		if err := h.after.Register("tracing:after_"+h.operation, after); err != nil {
+			return fmt.Errorf("error registering %s callback: %v", h.operation, err)
+		}
+	}
+
+	return nil
+}
+
+type registerer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+func before(tracer trace.Tracer, operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		name := "gorm." + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+
+		ctx, span := tracer.Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemMySQL,
+				semconv.DBOperationKey.String(operation),
+			),
+		)
+
+		// the hooks of the statement run with its context, so their queries are nested in its span
+		db.InstanceSet(parentKey, db.Statement.Context)
+		db.InstanceSet(spanKey, span)
+		db.Statement.Context = ctx
+	}
+}
+
+func after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	// statements chained on the same session are siblings, not children
+	if parent, ok := db.InstanceGet(parentKey); ok {
+		if ctx, ok := parent.(context.Context); ok {
+			db.Statement.Context = ctx
+		}
+	}
+
+	// the statement is attached with its placeholders, the values may hold personal data
+	span.SetAttributes(
+		semconv.DBStatementKey.String(db.Statement.SQL.String()),
+		semconv.DBSQLTableKey.String(db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Package tracing holds the OpenTelemetry tracing of the application.
+
+Every request handled by the api router is traced as a server span whose context is propagated from the W3C
+traceparent header, and every gorm statement executed within the request, including the ones of the model hooks,
+is traced as a child span with its SQL attached.
+*/
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the name the spans of the server are reported under
+const ServiceName = "party"
+
+// Span exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Config holds the tracing settings
+type Config struct {
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+}
+
+// Validate checks the exporter and the sample ratio are supported
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case ExporterNone, ExporterStdout, ExporterOTLP, "":
+	default:
+		return fmt.Errorf(`invalid "%s" tracing exporter, expected none, stdout or otlp`, c.Exporter)
+	}
+
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf(`invalid "%g" tracing sample ratio, expected a value between 0 and 1`, c.SampleRatio)
+	}
+
+	return nil
+}
+
+// Enabled reports whether the spans are exported
+func (c Config) Enabled() bool {
+	return c.Exporter != "" && c.Exporter != ExporterNone
+}
+
+// New returns the tracer provider exporting the spans with the configured exporter.
+// The stdout exporter writes to out. The provider and the W3C trace context propagator are registered as globals.
+func New(ctx context.Context, config Config, out io.Writer) (*sdktrace.TracerProvider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch config.Exporter {
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+		if config.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("tracing is disabled")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s exporter: %v", config.Exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(ServiceName))),
+	)
+
+	Register(provider)
+
+	return provider, nil
+}
+
+// Register sets the provider and the W3C trace context propagator as globals
+func Register(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(Propagator())
+}
+
+// Propagator returns the W3C trace context and baggage propagator
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// Start starts a span of the application named name as child of the span carried by ctx
+func Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name)
+}
+
+const instrumentationName = "github.com/amaury95/GetGround-Party"